@@ -0,0 +1,573 @@
+package jiesql
+
+import (
+	"fmt"
+)
+
+// clone 复制表的结构和所有行, ALTER TABLE 在副本上修改, 出错时原来的表保持不变
+func (t *table) clone() *table {
+	c := *t
+	c.columns = append([]string{}, t.columns...)
+	c.columnTypes = append([]ColumnType{}, t.columnTypes...)
+	c.columnModifiers = append([]typeModifier{}, t.columnModifiers...)
+	c.columnDefaults = append([]*expression{}, t.columnDefaults...)
+	c.columnGenerated = append([]*expression{}, t.columnGenerated...)
+	c.columnIdentity = append([]bool{}, t.columnIdentity...)
+	c.columnSequences = append([]string{}, t.columnSequences...)
+	c.checks = append([]checkConstraint{}, t.checks...)
+	c.foreignKeys = append([]foreignKeyConstraint{}, t.foreignKeys...)
+
+	c.rows = make([][]MemoryCell, len(t.rows))
+	for r, row := range t.rows {
+		c.rows[r] = append([]MemoryCell{}, row...)
+	}
+
+	return &c
+}
+
+// mapExpression 复制表达式, 先复制所有子表达式, 再把每个节点换成 f 的返回值, 原来的表达式不变
+func mapExpression(exp expression, f func(expression) expression) expression {
+	mapList := func(list []*expression) []*expression {
+		var mapped []*expression
+		for _, e := range list {
+			m := mapExpression(*e, f)
+			mapped = append(mapped, &m)
+		}
+
+		return mapped
+	}
+
+	switch exp.kind {
+	case binaryKind:
+		binary := *exp.binary
+		binary.a = mapExpression(binary.a, f)
+		binary.b = mapExpression(binary.b, f)
+		exp.binary = &binary
+	case likeKind:
+		like := *exp.like
+		like.value = mapExpression(like.value, f)
+		like.pattern = mapExpression(like.pattern, f)
+		if like.escape != nil {
+			escape := mapExpression(*like.escape, f)
+			like.escape = &escape
+		}
+		exp.like = &like
+	case unaryKind:
+		unary := *exp.unary
+		unary.operand = mapExpression(unary.operand, f)
+		exp.unary = &unary
+	case inKind:
+		in := *exp.in
+		in.value = mapExpression(in.value, f)
+		in.list = mapList(in.list)
+		exp.in = &in
+	case betweenKind:
+		between := *exp.between
+		between.value = mapExpression(between.value, f)
+		between.low = mapExpression(between.low, f)
+		between.high = mapExpression(between.high, f)
+		exp.between = &between
+	case castKind:
+		cast := *exp.cast
+		cast.value = mapExpression(cast.value, f)
+		exp.cast = &cast
+	case functionKind:
+		function := *exp.function
+		function.args = mapList(function.args)
+		if function.over != nil {
+			over := *function.over
+			over.partitionBy = mapList(over.partitionBy)
+			over.orderBy = nil
+			for _, item := range function.over.orderBy {
+				o := *item
+				o.exp = mapExpression(o.exp, f)
+				over.orderBy = append(over.orderBy, &o)
+			}
+			if over.frame != nil {
+				frame := *over.frame
+				for _, bound := range []*frameBound{&frame.start, &frame.end} {
+					if bound.offset != nil {
+						offset := mapExpression(*bound.offset, f)
+						bound.offset = &offset
+					}
+				}
+				over.frame = &frame
+			}
+			function.over = &over
+		}
+		exp.function = &function
+	case arrayKind:
+		array := *exp.array
+		array.elements = mapList(array.elements)
+		exp.array = &array
+	case subscriptKind:
+		subscript := *exp.subscript
+		subscript.value = mapExpression(subscript.value, f)
+		subscript.index = mapExpression(subscript.index, f)
+		exp.subscript = &subscript
+	case quantifiedKind:
+		quantified := *exp.quantified
+		quantified.array = mapExpression(quantified.array, f)
+		exp.quantified = &quantified
+	}
+
+	return f(exp)
+}
+
+// mapIdentifiers 复制表达式并把其中的列名换成 f 的返回值, 原来的表达式不变
+func mapIdentifiers(exp expression, f func(string) string) expression {
+	return mapExpression(exp, func(exp expression) expression {
+		if exp.kind == literalKind && exp.literal.kind == identifierKind {
+			literal := *exp.literal
+			literal.value = f(literal.value)
+			exp.literal = &literal
+		}
+
+		return exp
+	})
+}
+
+// referencesColumn 判断表达式是否引用了列 name
+func referencesColumn(exp expression, name string) bool {
+	found := false
+	mapIdentifiers(exp, func(identifier string) string {
+		if identifier == name {
+			found = true
+		}
+
+		return identifier
+	})
+
+	return found
+}
+
+// AlterTable 在表的副本上依次执行所有操作, 全部成功并且已有的行满足新的约束后才替换原来的表
+func (mb *MemoryBackend) AlterTable(alt *AlterTableStatement) (err error) {
+	old, err := mb.writableTable(alt.table.value)
+	if err != nil {
+		return err
+	}
+
+	t := old.clone()
+
+	// columnMap[i] 是原来第 i 列现在的位置, 被删除的列为 -1, 提交时用来更新其他表引用这张表的外键
+	columnMap := make([]int, len(old.columns))
+	for i := range columnMap {
+		columnMap[i] = i
+	}
+
+	// 新加的 SERIAL 列要用序列填充已有的行, 所以序列马上注册, 失败时再删掉
+	sequences := map[string]*sequence{}
+	defer func() {
+		if err != nil {
+			for name := range sequences {
+				delete(mb.sequences, name)
+			}
+		}
+	}()
+
+	for _, action := range alt.actions {
+		switch action.kind {
+		case addColumnKind:
+			err = mb.alterAddColumn(t, action.column, sequences)
+		case dropColumnKind:
+			err = mb.alterDropColumn(t, old, action.name.value, columnMap)
+		case renameColumnKind:
+			// 视图按名字引用列, 改名以后就查不到了
+			if v := mb.dependentView(old.name, action.name.value); v != nil {
+				return fmt.Errorf("%w: view %s uses column %s", ErrDependentObjects, v.name, action.name.value)
+			}
+
+			err = t.renameColumn(action.name.value, action.newName.value)
+		case renameTableKind:
+			if v := mb.dependentView(old.name, ""); v != nil {
+				return fmt.Errorf("%w: view %s depends on table %s", ErrDependentObjects, v.name, old.name)
+			}
+
+			if _, ok := mb.tables[action.newName.value]; ok && action.newName.value != old.name {
+				return ErrTableAlreadyExists
+			}
+
+			if _, ok := mb.views[action.newName.value]; ok {
+				return ErrTableAlreadyExists
+			}
+
+			for k, fk := range t.foreignKeys {
+				if fk.table == t.name {
+					t.foreignKeys[k].table = action.newName.value
+				}
+			}
+			t.name = action.newName.value
+		case alterColumnTypeKind:
+			err = mb.alterColumnType(t, action)
+		case addConstraintKind:
+			err = mb.addConstraints(t, nil, []*constraint{action.constraint})
+		case dropConstraintKind:
+			err = t.dropConstraint(action.name.value)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	if err = mb.validateAlteredTable(t, old, columnMap); err != nil {
+		return err
+	}
+
+	delete(mb.tables, old.name)
+	mb.tables[t.name] = t
+
+	// 被删除的 SERIAL 和标识列拥有的序列也一起删除, 包括这次新加又删掉的列
+	owned := map[string]bool{}
+	for _, name := range t.columnSequences {
+		owned[name] = true
+	}
+	dropped := append([]string{}, old.columnSequences...)
+	for name := range sequences {
+		dropped = append(dropped, name)
+	}
+	for _, name := range dropped {
+		if name != "" && !owned[name] {
+			delete(mb.sequences, name)
+		}
+	}
+
+	for _, other := range mb.tables {
+		if other == t {
+			continue
+		}
+
+		for k, fk := range other.foreignKeys {
+			if fk.table != old.name {
+				continue
+			}
+
+			var references []int
+			for _, j := range fk.references {
+				references = append(references, columnMap[j])
+			}
+
+			other.foreignKeys[k].table = t.name
+			other.foreignKeys[k].references = references
+		}
+	}
+
+	return nil
+}
+
+// alterAddColumn 加上一列, 已有的行取这一列的默认值或者计算生成列
+func (mb *MemoryBackend) alterAddColumn(t *table, col *columnDefinition, sequences map[string]*sequence) error {
+	added := map[string]*sequence{}
+	if err := mb.addColumn(t, col, added); err != nil {
+		return err
+	}
+
+	for name, s := range added {
+		mb.sequences[name] = s
+		sequences[name] = s
+	}
+
+	i := len(t.columns) - 1
+	if err := mb.checkColumnExpression(t, i, col); err != nil {
+		return err
+	}
+
+	for r, row := range t.rows {
+		row = append(row, nil)
+		if col.generated == nil {
+			cell, err := mb.columnDefault(t, i)
+			if err != nil {
+				return err
+			}
+
+			row[i] = cell
+		} else if err := mb.computeGenerated(t, row); err != nil {
+			return err
+		}
+
+		t.rows[r] = row
+	}
+
+	return mb.addConstraints(t, []*columnDefinition{col}, nil)
+}
+
+// alterDropColumn 删除一列和只涉及这张表的相关约束. 生成列, 视图或者其他表的外键引用这一列时报错
+func (mb *MemoryBackend) alterDropColumn(t, old *table, name string, columnMap []int) error {
+	i := t.columnIndex(name)
+	if i < 0 {
+		return fmt.Errorf("%w: %s", ErrColumnDoesNotExist, name)
+	}
+
+	for j, exp := range t.columnGenerated {
+		if j != i && exp != nil && referencesColumn(*exp, name) {
+			return fmt.Errorf("%w: generated column %s uses column %s", ErrDependentObjects, t.columns[j], name)
+		}
+	}
+
+	if v := mb.dependentView(old.name, name); v != nil {
+		return fmt.Errorf("%w: view %s uses column %s", ErrDependentObjects, v.name, name)
+	}
+
+	for _, ref := range mb.referencingKeys(old.name) {
+		if ref.child == old {
+			continue
+		}
+
+		for _, j := range ref.fk.references {
+			if columnMap[j] == i {
+				return fmt.Errorf("%w: foreign key %s on table %s uses column %s", ErrDependentObjects, ref.fk.name, ref.child.name, name)
+			}
+		}
+	}
+
+	var checks []checkConstraint
+	for _, c := range t.checks {
+		if !referencesColumn(c.check, name) {
+			checks = append(checks, c)
+		}
+	}
+	t.checks = checks
+
+	// 删掉用到这一列的外键, 其余外键里后面的列往前移
+	shift := func(columns []int) ([]int, bool) {
+		var shifted []int
+		for _, j := range columns {
+			if j == i {
+				return nil, false
+			}
+
+			if j > i {
+				j--
+			}
+			shifted = append(shifted, j)
+		}
+
+		return shifted, true
+	}
+
+	var foreignKeys []foreignKeyConstraint
+	for _, fk := range t.foreignKeys {
+		columns, ok := shift(fk.columns)
+		if !ok {
+			continue
+		}
+
+		if fk.table == t.name {
+			fk.references, ok = shift(fk.references)
+			if !ok {
+				continue
+			}
+		}
+
+		fk.columns = columns
+		foreignKeys = append(foreignKeys, fk)
+	}
+	t.foreignKeys = foreignKeys
+
+	t.columns = append(t.columns[:i], t.columns[i+1:]...)
+	t.columnTypes = append(t.columnTypes[:i], t.columnTypes[i+1:]...)
+	t.columnModifiers = append(t.columnModifiers[:i], t.columnModifiers[i+1:]...)
+	t.columnDefaults = append(t.columnDefaults[:i], t.columnDefaults[i+1:]...)
+	t.columnGenerated = append(t.columnGenerated[:i], t.columnGenerated[i+1:]...)
+	t.columnIdentity = append(t.columnIdentity[:i], t.columnIdentity[i+1:]...)
+	t.columnSequences = append(t.columnSequences[:i], t.columnSequences[i+1:]...)
+	for r, row := range t.rows {
+		t.rows[r] = append(row[:i], row[i+1:]...)
+	}
+
+	for j, k := range columnMap {
+		if k == i {
+			columnMap[j] = -1
+		} else if k > i {
+			columnMap[j] = k - 1
+		}
+	}
+
+	return nil
+}
+
+// renameColumn 修改列名, 同时改写 CHECK 约束和生成列里对这一列的引用
+func (t *table) renameColumn(name, newName string) error {
+	i := t.columnIndex(name)
+	if i < 0 {
+		return fmt.Errorf("%w: %s", ErrColumnDoesNotExist, name)
+	}
+
+	if t.columnIndex(newName) >= 0 {
+		return fmt.Errorf("%w: %s", ErrDuplicateColumn, newName)
+	}
+
+	rename := func(identifier string) string {
+		if identifier == name {
+			return newName
+		}
+
+		return identifier
+	}
+
+	t.columns[i] = newName
+	for k, c := range t.checks {
+		t.checks[k].check = mapIdentifiers(c.check, rename)
+	}
+
+	for j, exp := range t.columnGenerated {
+		if exp != nil {
+			generated := mapIdentifiers(*exp, rename)
+			t.columnGenerated[j] = &generated
+		}
+	}
+
+	return nil
+}
+
+// alterColumnType 修改列的类型并转换已有的值. 没有 USING 时只允许赋值时的隐式转换
+func (mb *MemoryBackend) alterColumnType(t *table, action *alterTableAction) error {
+	name := action.name.value
+	i := t.columnIndex(name)
+	if i < 0 {
+		return fmt.Errorf("%w: %s", ErrColumnDoesNotExist, name)
+	}
+
+	for j, exp := range t.columnGenerated {
+		if j != i && exp != nil && referencesColumn(*exp, name) {
+			return fmt.Errorf("%w: generated column %s uses column %s", ErrDependentObjects, t.columns[j], name)
+		}
+	}
+
+	if v := mb.dependentView(t.name, name); v != nil {
+		return fmt.Errorf("%w: view %s uses column %s", ErrDependentObjects, v.name, name)
+	}
+
+	dt, mod, err := mb.resolveDataType(action.datatype)
+	if err != nil {
+		return err
+	}
+
+	// 先在旧的类型下算出所有值
+	from, untyped := t.columnTypes[i], false
+	values := make([]MemoryCell, len(t.rows))
+	for r, row := range t.rows {
+		values[r] = row[i]
+	}
+
+	if action.using != nil {
+		_, _, typ, err := mb.evaluateCell(t, nil, *action.using)
+		if err != nil {
+			return err
+		}
+
+		from, untyped = typ, isUntypedLiteral(*action.using)
+		for r, row := range t.rows {
+			values[r], _, _, err = mb.evaluateCell(t, row, *action.using)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	ctx := assignmentCoercion
+	if untyped {
+		ctx = explicitCoercion
+	}
+
+	if !canCoerce(from, dt, ctx) {
+		return fmt.Errorf("%w: column %s cannot be cast automatically to type %s", ErrInvalidCast, name, dt)
+	}
+
+	if exp := t.columnDefaults[i]; exp != nil {
+		_, _, typ, err := mb.evaluateCell(&table{}, nil, *exp)
+		if err != nil {
+			return err
+		}
+
+		ctx := assignmentCoercion
+		if isUntypedLiteral(*exp) {
+			ctx = explicitCoercion
+		}
+
+		if !canCoerce(typ, dt, ctx) {
+			return fmt.Errorf("%w: default for column %s cannot be cast automatically to type %s", ErrInvalidCast, name, dt)
+		}
+	}
+
+	t.columnTypes[i] = dt
+	t.columnModifiers[i] = mod
+	for r, row := range t.rows {
+		row[i], err = t.assign(i, values[r], from, untyped, mb.enums)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// dropConstraint 按名字删除 CHECK 约束或外键
+func (t *table) dropConstraint(name string) error {
+	for k, c := range t.checks {
+		if c.name == name {
+			t.checks = append(t.checks[:k], t.checks[k+1:]...)
+			return nil
+		}
+	}
+
+	for k, fk := range t.foreignKeys {
+		if fk.name == name {
+			t.foreignKeys = append(t.foreignKeys[:k], t.foreignKeys[k+1:]...)
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: %s", ErrConstraintDoesNotExist, name)
+}
+
+// validateAlteredTable 检查修改后的表: 约束仍然合法, 外键两边的类型一致, 已有的行满足所有约束
+func (mb *MemoryBackend) validateAlteredTable(t, old *table, columnMap []int) error {
+	for _, c := range t.checks {
+		_, _, typ, err := mb.evaluateCell(t, nil, c.check)
+		if err != nil {
+			return err
+		}
+
+		if typ != BoolType {
+			return ErrInvalidCondition
+		}
+	}
+
+	for _, fk := range t.foreignKeys {
+		parent := mb.tables[fk.table]
+		if fk.table == t.name {
+			parent = t
+		}
+
+		for k, column := range fk.columns {
+			if t.columnTypes[column] != parent.columnTypes[fk.references[k]] {
+				return fmt.Errorf("%w: foreign key %s compares %s with %s", ErrInvalidDatatype, fk.name, t.columnTypes[column], parent.columnTypes[fk.references[k]])
+			}
+		}
+	}
+
+	for _, ref := range mb.referencingKeys(old.name) {
+		if ref.child == old {
+			continue
+		}
+
+		for k, j := range ref.fk.references {
+			column := ref.child.columnTypes[ref.fk.columns[k]]
+			if column != t.columnTypes[columnMap[j]] {
+				return fmt.Errorf("%w: foreign key %s compares %s with %s", ErrInvalidDatatype, ref.fk.name, column, t.columnTypes[columnMap[j]])
+			}
+		}
+	}
+
+	for _, row := range t.rows {
+		if err := mb.checkConstraints(t, row); err != nil {
+			return err
+		}
+	}
+
+	return mb.checkReferentialIntegrity(tableChanges{
+		t: {rows: t.rows, changed: t.rows},
+	})
+}
@@ -0,0 +1,103 @@
+package jiesql
+
+import (
+	"testing"
+)
+
+func newAccountsBackend(t *testing.T) *MemoryBackend {
+	t.Helper()
+
+	mb := NewMemoryBackend()
+	mustRun(t, mb, `CREATE TABLE accounts (id INT, name TEXT, balance INT CHECK (balance >= 0));
+		INSERT INTO accounts VALUES (1, 'ann', 10);
+		INSERT INTO accounts VALUES (2, 'bo', null);`)
+	return mb
+}
+
+func TestAlterTableColumns(t *testing.T) {
+	mb := newAccountsBackend(t)
+
+	mustRun(t, mb, "ALTER TABLE accounts ADD COLUMN active BOOLEAN DEFAULT true")
+	mustRun(t, mb, "ALTER TABLE accounts ADD seq SERIAL")
+	mustRun(t, mb, "ALTER TABLE accounts ADD COLUMN double INT GENERATED ALWAYS AS (balance * 2) STORED")
+	expectRows(t, mb, "SELECT id, active, seq, double FROM accounts", [][]string{
+		{"1", "true", "1", "20"},
+		{"2", "true", "2", "null"},
+	})
+
+	mustRun(t, mb, "ALTER TABLE accounts RENAME COLUMN balance TO amount")
+	// CHECK 约束和生成列跟着改名
+	expectError(t, mb, "INSERT INTO accounts (id, amount) VALUES (3, -1)", ErrViolatesCheckConstraint)
+	mustRun(t, mb, "UPDATE accounts SET amount = 4 WHERE id = 2")
+	expectRows(t, mb, "SELECT double FROM accounts WHERE id = 2", [][]string{{"8"}})
+
+	mustRun(t, mb, "ALTER TABLE accounts DROP COLUMN double, DROP COLUMN active")
+	expectError(t, mb, "SELECT double FROM accounts", ErrColumnDoesNotExist)
+	expectError(t, mb, "SELECT active FROM accounts", ErrColumnDoesNotExist)
+	expectRows(t, mb, "SELECT id, seq FROM accounts", [][]string{{"1", "1"}, {"2", "2"}})
+
+	mustRun(t, mb, "ALTER TABLE accounts ALTER COLUMN amount TYPE BIGINT")
+	mustRun(t, mb, "ALTER TABLE accounts ALTER COLUMN name SET DATA TYPE VARCHAR(3)")
+	mustRun(t, mb, "ALTER TABLE accounts ALTER COLUMN id TYPE TEXT USING 'acct-' || id::text")
+	expectRows(t, mb, "SELECT id, name, amount FROM accounts", [][]string{{"acct-1", "ann", "10"}, {"acct-2", "bo", "4"}})
+
+	mustRun(t, mb, "ALTER TABLE accounts RENAME TO ledger")
+	expectRows(t, mb, "SELECT id FROM ledger WHERE amount > 5", [][]string{{"acct-1"}})
+	expectColumns(t, mb, "SELECT id, name, amount FROM ledger WHERE amount > 5", []column{
+		{Name: "id", Type: TextType},
+		{Name: "name", Type: TextType},
+		{Name: "amount", Type: BigIntType},
+	})
+	expectError(t, mb, "SELECT id FROM accounts", ErrTableDoesNotExist)
+}
+
+func TestAlterTableErrors(t *testing.T) {
+	mb := newAccountsBackend(t)
+	mustRun(t, mb, "CREATE TABLE other (id INT)")
+
+	expectError(t, mb, "ALTER TABLE nosuch ADD COLUMN a INT", ErrTableDoesNotExist)
+	expectError(t, mb, "ALTER TABLE accounts ADD COLUMN name TEXT", ErrDuplicateColumn)
+	expectError(t, mb, "ALTER TABLE accounts DROP COLUMN nosuch", ErrColumnDoesNotExist)
+	expectError(t, mb, "ALTER TABLE accounts RENAME COLUMN id TO name", ErrDuplicateColumn)
+	expectError(t, mb, "ALTER TABLE accounts RENAME TO other", ErrTableAlreadyExists)
+	expectError(t, mb, "ALTER TABLE accounts ALTER COLUMN name TYPE INT", ErrInvalidCast)
+	expectError(t, mb, "ALTER TABLE accounts ALTER COLUMN name TYPE INT USING name::int", ErrInvalidValue)
+	expectError(t, mb, "ALTER TABLE accounts ALTER COLUMN name TYPE VARCHAR(2)", ErrValueTooLong)
+
+	// 出错时前面的操作也不生效
+	expectError(t, mb, "ALTER TABLE accounts ADD COLUMN extra INT, DROP COLUMN nosuch", ErrColumnDoesNotExist)
+	expectError(t, mb, "ALTER TABLE accounts RENAME TO renamed, ADD COLUMN id INT", ErrDuplicateColumn)
+	expectRows(t, mb, "SELECT id, name, balance FROM accounts", [][]string{{"1", "ann", "10"}, {"2", "bo", "null"}})
+	expectError(t, mb, "SELECT extra FROM accounts", ErrColumnDoesNotExist)
+}
+
+func TestAlterTableConstraints(t *testing.T) {
+	mb := newAccountsBackend(t)
+	mustRun(t, mb, "CREATE TABLE transfers (account INT, amount INT)")
+
+	// 已有的行必须满足新的约束
+	expectError(t, mb, "ALTER TABLE accounts ADD CONSTRAINT small CHECK (balance < 5)", ErrViolatesCheckConstraint)
+	mustRun(t, mb, "ALTER TABLE accounts ADD CONSTRAINT small CHECK (balance < 50)")
+	expectError(t, mb, "UPDATE accounts SET balance = 60", ErrViolatesCheckConstraint)
+	expectError(t, mb, "ALTER TABLE accounts ADD CONSTRAINT small CHECK (id > 0)", ErrConstraintAlreadyExists)
+	mustRun(t, mb, "ALTER TABLE accounts DROP CONSTRAINT small")
+	mustRun(t, mb, "UPDATE accounts SET balance = 60")
+	expectError(t, mb, "ALTER TABLE accounts DROP CONSTRAINT small", ErrConstraintDoesNotExist)
+
+	mustRun(t, mb, "INSERT INTO transfers VALUES (1, 5)")
+	mustRun(t, mb, "ALTER TABLE transfers ADD CONSTRAINT fk FOREIGN KEY (account) REFERENCES accounts (id)")
+	expectError(t, mb, "INSERT INTO transfers VALUES (9, 1)", ErrViolatesForeignKey)
+
+	// 被其他表的外键引用的列不能删除, 类型也必须和外键一致
+	expectError(t, mb, "ALTER TABLE accounts DROP COLUMN id", ErrDependentObjects)
+	expectError(t, mb, "ALTER TABLE accounts ALTER COLUMN id TYPE BIGINT", ErrInvalidDatatype)
+
+	// 改名以后外键仍然指向这张表
+	mustRun(t, mb, "ALTER TABLE accounts RENAME TO ledger")
+	expectError(t, mb, "DELETE FROM ledger WHERE id = 1", ErrViolatesForeignKey)
+	mustRun(t, mb, "ALTER TABLE transfers DROP CONSTRAINT fk")
+	mustRun(t, mb, "DELETE FROM ledger WHERE id = 1")
+
+	mustRun(t, mb, "INSERT INTO transfers VALUES (7, 1)")
+	expectError(t, mb, "ALTER TABLE transfers ADD FOREIGN KEY (account) REFERENCES ledger (id)", ErrViolatesForeignKey)
+}
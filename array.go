@@ -0,0 +1,447 @@
+package jiesql
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+)
+
+// 数组类型用元素类型加上 arrayTypeFlag 表示, 例如 INT[] 是 IntType | arrayTypeFlag.
+// 目前只支持一维数组, 在 MemoryCell 里先存 4 字节的元素个数, 每个元素再存 4 字节的长度和内容,
+// 长度为 nullElementLength 表示元素是 NULL.
+const arrayTypeFlag ColumnType = 1 << 16
+
+const nullElementLength = ^uint32(0)
+
+func arrayType(elem ColumnType) ColumnType {
+	return elem | arrayTypeFlag
+}
+
+func isArrayType(typ ColumnType) bool {
+	return typ&arrayTypeFlag != 0
+}
+
+func elementType(typ ColumnType) ColumnType {
+	return typ &^ arrayTypeFlag
+}
+
+func arrayCell(elems []MemoryCell) MemoryCell {
+	cell := make(MemoryCell, 4)
+	binary.BigEndian.PutUint32(cell, uint32(len(elems)))
+
+	for _, e := range elems {
+		length := make([]byte, 4)
+		if e == nil {
+			binary.BigEndian.PutUint32(length, nullElementLength)
+			cell = append(cell, length...)
+			continue
+		}
+
+		binary.BigEndian.PutUint32(length, uint32(len(e)))
+		cell = append(cell, length...)
+		cell = append(cell, e...)
+	}
+
+	return cell
+}
+
+// asArray 解码数组的元素, 长度和内容对不上时返回 ErrInvalidCell
+func (mc MemoryCell) asArray() ([]MemoryCell, error) {
+	if len(mc) < 4 {
+		return nil, ErrInvalidCell
+	}
+
+	n := binary.BigEndian.Uint32(mc)
+	rest := mc[4:]
+	if uint64(n)*4 > uint64(len(rest)) {
+		return nil, ErrInvalidCell
+	}
+
+	elems := make([]MemoryCell, 0, n)
+	for i := uint32(0); i < n; i++ {
+		if len(rest) < 4 {
+			return nil, ErrInvalidCell
+		}
+
+		length := binary.BigEndian.Uint32(rest)
+		rest = rest[4:]
+		if length == nullElementLength {
+			elems = append(elems, nil)
+			continue
+		}
+
+		if uint64(length) > uint64(len(rest)) {
+			return nil, ErrInvalidCell
+		}
+
+		elems = append(elems, rest[:length:length])
+		rest = rest[length:]
+	}
+
+	if len(rest) != 0 {
+		return nil, ErrInvalidCell
+	}
+
+	return elems, nil
+}
+
+// formatArray 按 PostgreSQL 的格式输出, 例如 {1,2,NULL} 和 {a,"b c"}
+func formatArray(c MemoryCell, typ ColumnType, enums enumTypes) string {
+	elems, err := c.asArray()
+	if err != nil {
+		// 输出不能失败, 无法解码时按原始字节输出
+		return formatBytea(c)
+	}
+
+	var parts []string
+	for _, e := range elems {
+		if e == nil {
+			parts = append(parts, "NULL")
+			continue
+		}
+
+		parts = append(parts, quoteArrayElement(formatCell(e, elementType(typ), enums)))
+	}
+
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+func quoteArrayElement(s string) string {
+	if s != "" && !strings.EqualFold(s, "NULL") && !strings.ContainsAny(s, "{},\"\\ \t\n") {
+		return s
+	}
+
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	return `"` + s + `"`
+}
+
+// parseArray 解析 {1,2,NULL} 这样的文本, 元素可以用双引号括起来, 引号里用反斜杠转义
+func parseArray(s string, typ ColumnType, enums enumTypes) (MemoryCell, error) {
+	s = strings.TrimSpace(s)
+	if len(s) < 2 || s[0] != '{' || s[len(s)-1] != '}' {
+		return nil, ErrInvalidValue
+	}
+
+	body := s[1 : len(s)-1]
+	if strings.TrimSpace(body) == "" {
+		return arrayCell(nil), nil
+	}
+
+	var elems []MemoryCell
+	for i := 0; ; {
+		for i < len(body) && body[i] == ' ' {
+			i++
+		}
+
+		var text strings.Builder
+		quoted := i < len(body) && body[i] == '"'
+		if quoted {
+			i++
+			for ; i < len(body) && body[i] != '"'; i++ {
+				if body[i] == '\\' && i+1 < len(body) {
+					i++
+				}
+				text.WriteByte(body[i])
+			}
+
+			if i >= len(body) {
+				return nil, ErrInvalidValue
+			}
+			i++
+
+			for i < len(body) && body[i] == ' ' {
+				i++
+			}
+		} else {
+			for ; i < len(body) && body[i] != ','; i++ {
+				if strings.IndexByte("{}\"", body[i]) >= 0 {
+					return nil, ErrInvalidValue
+				}
+				text.WriteByte(body[i])
+			}
+		}
+
+		value := text.String()
+		if !quoted {
+			value = strings.TrimSpace(value)
+			if value == "" {
+				return nil, ErrInvalidValue
+			}
+		}
+
+		if !quoted && strings.EqualFold(value, "NULL") {
+			elems = append(elems, nil)
+		} else {
+			e, err := parseCell(value, elementType(typ), enums)
+			if err != nil {
+				return nil, err
+			}
+
+			elems = append(elems, e)
+		}
+
+		if i >= len(body) {
+			break
+		}
+
+		if body[i] != ',' {
+			return nil, ErrInvalidValue
+		}
+		i++
+	}
+
+	return arrayCell(elems), nil
+}
+
+// castArray 逐个转换数组元素
+func castArray(c MemoryCell, from, to ColumnType, ctx coercionContext, enums enumTypes) (MemoryCell, error) {
+	array, err := c.asArray()
+	if err != nil {
+		return nil, err
+	}
+
+	var elems []MemoryCell
+	for _, e := range array {
+		cast, err := castCell(e, elementType(from), elementType(to), ctx, enums)
+		if err != nil {
+			return nil, err
+		}
+
+		elems = append(elems, cast)
+	}
+
+	return arrayCell(elems), nil
+}
+
+// compareArrays 逐个比较元素, 和 PostgreSQL 一样 NULL 元素大于其他值, 前缀相同时较短的数组较小.
+// 无法解码的数组按原始字节比较
+func compareArrays(l, r MemoryCell, typ ColumnType) int {
+	le, lerr := l.asArray()
+	re, rerr := r.asArray()
+	if lerr != nil || rerr != nil {
+		return bytes.Compare(l, r)
+	}
+
+	for i := 0; i < len(le) && i < len(re); i++ {
+		switch {
+		case le[i] == nil && re[i] == nil:
+			continue
+		case le[i] == nil:
+			return 1
+		case re[i] == nil:
+			return -1
+		}
+
+		if c := compareCells(le[i], re[i], elementType(typ)); c != 0 {
+			return c
+		}
+	}
+
+	switch {
+	case len(le) < len(re):
+		return -1
+	case len(le) > len(re):
+		return 1
+	}
+
+	return 0
+}
+
+// evaluateArrayCell 计算 ARRAY[...], 元素类型取第一个有类型的元素, 数值类型会提升到最宽的那个,
+// 全是无类型字面量时元素类型是 text
+func (mb *MemoryBackend) evaluateArrayCell(t *table, row []MemoryCell, exp expression) (MemoryCell, string, ColumnType, error) {
+	elements := exp.array.elements
+
+	cells := make([]MemoryCell, len(elements))
+	types := make([]ColumnType, len(elements))
+	elemType, typed := TextType, false
+	for i, e := range elements {
+		cell, _, typ, err := mb.evaluateCell(t, row, *e)
+		if err != nil {
+			return nil, "", 0, err
+		}
+
+		cells[i], types[i] = cell, typ
+		if isUntypedLiteral(*e) {
+			continue
+		}
+
+		if !typed {
+			elemType, typed = typ, true
+		} else if isNumericType(typ) && isNumericType(elemType) && numericRank[typ] > numericRank[elemType] {
+			elemType = typ
+		}
+	}
+
+	// 只支持一维数组, 元素本身是数组时 arrayType 会丢掉一层
+	if isArrayType(elemType) {
+		return nil, "", 0, ErrInvalidOperands
+	}
+
+	for i, e := range elements {
+		ctx := assignmentCoercion
+		if isUntypedLiteral(*e) {
+			ctx = explicitCoercion
+		}
+
+		cell, err := castCell(cells[i], types[i], elemType, ctx, mb.enums)
+		if err != nil {
+			return nil, "", 0, ErrInvalidOperands
+		}
+
+		cells[i] = cell
+	}
+
+	typ := arrayType(elemType)
+	if row == nil {
+		return nil, "array", typ, nil
+	}
+
+	return arrayCell(cells), "array", typ, nil
+}
+
+// evaluateSubscriptCell 计算 array[index], 下标越界或为 NULL 时结果是 NULL
+func (mb *MemoryBackend) evaluateSubscriptCell(t *table, row []MemoryCell, exp expression) (MemoryCell, string, ColumnType, error) {
+	sub := exp.subscript
+
+	array, name, typ, err := mb.evaluateCell(t, row, sub.value)
+	if err != nil {
+		return nil, "", 0, err
+	}
+
+	index, _, it, err := mb.evaluateCell(t, row, sub.index)
+	if err != nil {
+		return nil, "", 0, err
+	}
+
+	if !isArrayType(typ) || !isIntegerType(it) {
+		return nil, "", 0, ErrInvalidOperands
+	}
+
+	if array == nil || index == nil {
+		return nil, name, elementType(typ), nil
+	}
+
+	elems, err := array.asArray()
+	if err != nil {
+		return nil, "", 0, err
+	}
+
+	i := index.AsInt64()
+	if i < 1 || i > int64(len(elems)) {
+		return nil, name, elementType(typ), nil
+	}
+
+	return elems[i-1], name, elementType(typ), nil
+}
+
+// evaluateQuantifiedCell 计算 expr op ANY(array) 和 expr op ALL(array), NULL 的处理和 IN 一样:
+// ANY 有一个为 true 就是 true, 否则有 NULL 时是 NULL; ALL 有一个为 false 就是 false, 否则有 NULL 时是 NULL
+func (mb *MemoryBackend) evaluateQuantifiedCell(t *table, row []MemoryCell, exp expression) (MemoryCell, string, ColumnType, error) {
+	bexp := exp.binary
+	quantified := bexp.b.quantified
+
+	l, _, lt, err := mb.evaluateCell(t, row, bexp.a)
+	if err != nil {
+		return nil, "", 0, err
+	}
+
+	r, _, rt, err := mb.evaluateCell(t, row, quantified.array)
+	if err != nil {
+		return nil, "", 0, err
+	}
+
+	if isUntypedLiteral(quantified.array) && !isUntypedLiteral(bexp.a) {
+		r, rt, err = coerceUntyped(quantified.array, r, rt, arrayType(lt), mb.enums)
+	} else if isArrayType(rt) {
+		l, lt, err = coerceUntyped(bexp.a, l, lt, elementType(rt), mb.enums)
+	}
+	if err != nil {
+		return nil, "", 0, err
+	}
+
+	if !isArrayType(rt) {
+		return nil, "", 0, ErrInvalidOperands
+	}
+
+	// 数值类型不同时把两边提升成同一种类型
+	if et := elementType(rt); et != lt && isNumericType(et) && isNumericType(lt) {
+		to := lt
+		if numericRank[et] > numericRank[lt] {
+			to = et
+		}
+
+		if l, err = castCell(l, lt, to, assignmentCoercion, nil); err != nil {
+			return nil, "", 0, err
+		}
+
+		if r, err = castCell(r, rt, arrayType(to), assignmentCoercion, nil); err != nil {
+			return nil, "", 0, err
+		}
+
+		lt, rt = to, arrayType(to)
+	}
+
+	if lt != elementType(rt) {
+		return nil, "", 0, ErrInvalidOperands
+	}
+
+	if r == nil {
+		return nil, "?column?", BoolType, nil
+	}
+
+	elems, err := r.asArray()
+	if err != nil {
+		return nil, "", 0, err
+	}
+
+	op := symbol(bexp.op.value)
+	result := boolCell(quantified.all)
+	for _, e := range elems {
+		c, err := compareWith(op, l, lt, e, lt)
+		if err != nil {
+			return nil, "", 0, err
+		}
+
+		if quantified.all {
+			result = logicalAnd(result, c)
+		} else {
+			result = logicalOr(result, c)
+		}
+	}
+
+	return result, "?column?", BoolType, nil
+}
+
+// isSetReturning 判断是不是 unnest(array) 这样会返回多行的函数调用, 它们只能直接出现在 SELECT 列表里
+func isSetReturning(exp expression) bool {
+	return exp.kind == functionKind && exp.function.name.value == "unnest"
+}
+
+// evaluateUnnestCell 计算 unnest 的参数, 返回数组的元素和元素类型
+func (mb *MemoryBackend) evaluateUnnestCell(t *table, row []MemoryCell, exp expression) ([]MemoryCell, ColumnType, error) {
+	if len(exp.function.args) != 1 {
+		return nil, 0, ErrFunctionDoesNotExist
+	}
+
+	array, _, typ, err := mb.evaluateCell(t, row, *exp.function.args[0])
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if !isArrayType(typ) {
+		return nil, 0, ErrFunctionDoesNotExist
+	}
+
+	if array == nil {
+		return nil, elementType(typ), nil
+	}
+
+	elems, err := array.asArray()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return elems, elementType(typ), nil
+}
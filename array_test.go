@@ -0,0 +1,118 @@
+package jiesql
+
+import (
+	"testing"
+)
+
+func TestNestedArray(t *testing.T) {
+	mb := NewMemoryBackend()
+	mustRun(t, mb, "CREATE TABLE a (nums INT[])")
+
+	// 只支持一维数组, 嵌套的 ARRAY 不能被当成 int[]
+	expectError(t, mb, "SELECT ARRAY[ARRAY[1, 2]]", ErrInvalidOperands)
+	expectError(t, mb, "SELECT ARRAY[1, ARRAY[2]]", ErrInvalidOperands)
+	expectError(t, mb, "INSERT INTO a VALUES (ARRAY[ARRAY[1, 2]])", ErrInvalidOperands)
+	expectError(t, mb, "SELECT 1 = ANY(ARRAY[ARRAY[1]])", ErrInvalidOperands)
+	expectError(t, mb, "SELECT '{{1,2}}'::int[]", ErrInvalidValue)
+	expectRows(t, mb, "SELECT nums FROM a", [][]string{})
+}
+
+func TestInvalidArrayCell(t *testing.T) {
+	tests := []MemoryCell{
+		nil,
+		{0, 0, 0},
+		// 声明了一个元素但没有内容
+		{0, 0, 0, 1},
+		// 元素长度超出剩下的字节
+		{0, 0, 0, 1, 0, 0, 0, 8, 1},
+		// 元素之后还有多余的字节
+		{0, 0, 0, 0, 1},
+	}
+
+	for _, c := range tests {
+		if _, err := c.asArray(); err != ErrInvalidCell {
+			t.Errorf("asArray(%v): expected %v, got %v", c, ErrInvalidCell, err)
+		}
+	}
+
+	if s := formatArray(MemoryCell{0, 0, 0, 1}, arrayType(IntType), nil); s != `\x00000001` {
+		t.Errorf("formatArray of an invalid cell = %q", s)
+	}
+
+	elems, err := arrayCell([]MemoryCell{intCell(1), nil}).asArray()
+	if err != nil || len(elems) != 2 || elems[0].AsInt() != 1 || elems[1] != nil {
+		t.Errorf("asArray round trip = %v, %v", elems, err)
+	}
+}
+
+func TestArray(t *testing.T) {
+	mb := NewMemoryBackend()
+	mustRun(t, mb, `CREATE TABLE posts (id INT, tags TEXT[], scores INT[]);
+		INSERT INTO posts VALUES (1, ARRAY['go', 'sql'], ARRAY[1, 2, 3]);
+		INSERT INTO posts VALUES (2, '{"a b",NULL,"q\"uote"}', '{}');
+		INSERT INTO posts VALUES (3, ARRAY['go'], ARRAY[null, 5]);
+		INSERT INTO posts VALUES (4, null, null);`)
+
+	expectRows(t, mb, "SELECT id, tags, scores FROM posts", [][]string{
+		{"1", "{go,sql}", "{1,2,3}"},
+		{"2", `{"a b",NULL,"q\"uote"}`, "{}"},
+		{"3", "{go}", "{NULL,5}"},
+		{"4", "null", "null"},
+	})
+
+	tests := []struct {
+		source   string
+		expected string
+	}{
+		{"SELECT ARRAY[1, 2]", "{1,2}"},
+		{"SELECT ARRAY['a', 'b']", "{a,b}"},
+		// 数值类型提升到最宽的那个
+		{"SELECT ARRAY[1, 2.5]", "{1,2.5}"},
+		{"SELECT ARRAY[1, null]", "{1,NULL}"},
+		{"SELECT ARRAY[1, 2, 3][2]", "2"},
+		{"SELECT ARRAY[1, 2, 3][0]", "null"},
+		{"SELECT ARRAY[1, 2, 3][4]", "null"},
+		{"SELECT ARRAY[1, 2, 3][null::int]", "null"},
+		{"SELECT ARRAY[1, 2] = ARRAY[1, 2]", "true"},
+		{"SELECT ARRAY[1, 2] < ARRAY[1, 2, 0]", "true"},
+		{"SELECT ARRAY[1, null] > ARRAY[1, 5]", "true"},
+		{"SELECT 2 = ANY(ARRAY[1, 2])", "true"},
+		{"SELECT 3 = ANY(ARRAY[1, 2])", "false"},
+		{"SELECT 3 = ANY(ARRAY[1, null])", "null"},
+		{"SELECT 1 = ANY(ARRAY[1, null])", "true"},
+		{"SELECT 3 > ALL(ARRAY[1, 2])", "true"},
+		{"SELECT 2 > ALL(ARRAY[1, 2])", "false"},
+		{"SELECT 3 > ALL(ARRAY[1, null])", "null"},
+		{"SELECT 1 = ANY('{1,2}')", "true"},
+		{"SELECT 1.5 = ANY(ARRAY[1, 2])", "false"},
+		{"SELECT 1 = ANY(null::int[])", "null"},
+		{"SELECT '{1, 2}'::int[]", "{1,2}"},
+		{"SELECT ARRAY[1, 2]::text[]", "{1,2}"},
+		{"SELECT ARRAY[1, 2]::text", "{1,2}"},
+	}
+
+	for _, test := range tests {
+		expectValue(t, mb, test.source, test.expected)
+	}
+
+	expectRows(t, mb, "SELECT tags[1], scores[1] FROM posts", [][]string{
+		{"go", "1"},
+		{"a b", "null"},
+		{"go", "null"},
+		{"null", "null"},
+	})
+	expectRows(t, mb, "SELECT id FROM posts WHERE 'go' = ANY(tags)", [][]string{{"1"}, {"3"}})
+	expectRows(t, mb, "SELECT unnest(tags) FROM posts WHERE id < 3", [][]string{{"go"}, {"sql"}, {"a b"}, {"null"}, {`q"uote`}})
+	expectRows(t, mb, "SELECT id, unnest(scores) FROM posts", [][]string{{"1", "1"}, {"1", "2"}, {"1", "3"}, {"3", "null"}, {"3", "5"}})
+	expectColumns(t, mb, "SELECT scores FROM posts WHERE id = 1", []column{{Name: "scores", Type: arrayType(IntType)}})
+
+	expectError(t, mb, "INSERT INTO posts VALUES (9, null, ARRAY['a'])", ErrInvalidDatatype)
+	expectError(t, mb, "INSERT INTO posts VALUES (9, null, '{1,x}')", ErrInvalidDatatype)
+	expectError(t, mb, "SELECT '{1,2'::int[]", ErrInvalidValue)
+	expectError(t, mb, "SELECT ARRAY[1, 'a'::text]", ErrInvalidOperands)
+	expectError(t, mb, "SELECT id[1] FROM posts", ErrInvalidOperands)
+	expectError(t, mb, "SELECT scores['a'::text] FROM posts", ErrInvalidOperands)
+	expectError(t, mb, "SELECT 1 = ANY(ARRAY['a'])", ErrInvalidOperands)
+	expectError(t, mb, "SELECT 1 = ANY(1)", ErrInvalidOperands)
+	expectError(t, mb, "SELECT unnest(1)", ErrFunctionDoesNotExist)
+}
@@ -10,42 +10,375 @@ const (
 	SelectKind AstKind = iota
 	CreateTableKind
 	InsertKind
+	CreateTypeKind
+	UpdateKind
+	DeleteKind
+	CreateSequenceKind
+	AlterTableKind
+	CreateViewKind
+	DropViewKind
+	RefreshMaterializedViewKind
+	ExplainKind
 )
 
 type Statement struct {
-	SelectStatement      *SelectStatement
-	CreateTableStatement *CreateTableStatement
-	InsertStatement      *InsertStatement
-	Kind                 AstKind
+	SelectStatement                  *SelectStatement
+	CreateTableStatement             *CreateTableStatement
+	InsertStatement                  *InsertStatement
+	CreateTypeStatement              *CreateTypeStatement
+	UpdateStatement                  *UpdateStatement
+	DeleteStatement                  *DeleteStatement
+	CreateSequenceStatement          *CreateSequenceStatement
+	AlterTableStatement              *AlterTableStatement
+	CreateViewStatement              *CreateViewStatement
+	DropViewStatement                *DropViewStatement
+	RefreshMaterializedViewStatement *RefreshMaterializedViewStatement
+	ExplainStatement                 *ExplainStatement
+	Kind                             AstKind
 }
 
+// INSERT INTO table [(column [, ...])] VALUES (...), 没有列名时按表的列顺序
 type InsertStatement struct {
-	table  token
-	values *[]*expression
+	table   token
+	columns []*token
+	values  *[]*expression
+}
+
+// UPDATE table SET column = expression [, ...] [WHERE expression]
+type UpdateStatement struct {
+	table token
+	set   []*assignment
+	where *expression
+}
+
+// DELETE FROM table [WHERE expression]
+type DeleteStatement struct {
+	table token
+	where *expression
+}
+
+type assignment struct {
+	column token
+	value  expression
 }
 
 type expressionKind uint
 
 const (
 	literalKind expressionKind = iota
+	binaryKind
+	likeKind
+	unaryKind
+	inKind
+	betweenKind
+	castKind
+	functionKind
+	arrayKind
+	subscriptKind
+	quantifiedKind
+	// VALUES 和 UPDATE SET 里的 DEFAULT
+	defaultKind
+)
+
+type binaryExpression struct {
+	a  expression
+	b  expression
+	op token
+}
+
+// expr [NOT] LIKE | ILIKE pattern [ESCAPE escape]
+type likeExpression struct {
+	value           expression
+	pattern         expression
+	escape          *expression
+	not             bool
+	caseInsensitive bool
+}
+
+// 前缀运算符, 目前只有 NOT
+type unaryExpression struct {
+	operand expression
+	op      token
+}
+
+// expr [NOT] IN ($expression [, ...])
+type inExpression struct {
+	value expression
+	list  []*expression
+	not   bool
+}
+
+// expr [NOT] BETWEEN low AND high
+type betweenExpression struct {
+	value expression
+	low   expression
+	high  expression
+	not   bool
+}
+
+// CAST(expr AS type) 或 expr::type
+type castExpression struct {
+	value    expression
+	datatype dataType
+}
+
+// name($expression [, ...])
+type functionCall struct {
+	name token
+	args []*expression
+	// count(*)
+	star bool
+	// 窗口函数的 OVER (...), 普通函数调用为 nil
+	over *windowDefinition
+}
+
+// OVER ([PARTITION BY $expression [, ...]] [ORDER BY $expression [ASC | DESC] [, ...]] [$frame])
+type windowDefinition struct {
+	partitionBy []*expression
+	orderBy     []*orderItem
+	frame       *windowFrame
+}
+
+type orderItem struct {
+	exp  expression
+	desc bool
+	// SQL 里直接写的整数常量, SELECT 的 ORDER BY 里表示结果的第几列. 解析时就确定,
+	// 所以绑定参数得到的整数不算
+	positional bool
+}
+
+type frameMode uint
+
+const (
+	rowsFrame frameMode = iota
+	rangeFrame
 )
 
+type frameBoundKind uint
+
+const (
+	unboundedPrecedingBound frameBoundKind = iota
+	precedingBound
+	currentRowBound
+	followingBound
+	unboundedFollowingBound
+)
+
+// UNBOUNDED PRECEDING | $offset PRECEDING | CURRENT ROW | $offset FOLLOWING | UNBOUNDED FOLLOWING
+type frameBound struct {
+	kind   frameBoundKind
+	offset *expression
+}
+
+// {ROWS | RANGE} {$start | BETWEEN $start AND $end}, 只写 $start 时 $end 是 CURRENT ROW
+type windowFrame struct {
+	mode  frameMode
+	start frameBound
+	end   frameBound
+}
+
+// ARRAY[$expression [, ...]]
+type arrayExpression struct {
+	elements []*expression
+}
+
+// expr[index], 下标从 1 开始
+type subscriptExpression struct {
+	value expression
+	index expression
+}
+
+// ANY(array) 或 ALL(array), 只能出现在比较运算符的右边
+type quantifiedExpression struct {
+	array expression
+	all   bool
+}
+
 type expression struct {
-	literal *token
-	kind    expressionKind
+	literal    *token
+	binary     *binaryExpression
+	like       *likeExpression
+	unary      *unaryExpression
+	in         *inExpression
+	between    *betweenExpression
+	cast       *castExpression
+	function   *functionCall
+	array      *arrayExpression
+	subscript  *subscriptExpression
+	quantified *quantifiedExpression
+	kind       expressionKind
+}
+
+// 类型名和括号里的参数, 例如 int, varchar(64), text[]
+type dataType struct {
+	name      token
+	modifiers []*token
+	array     bool
 }
 
 type columnDefinition struct {
 	name     token
-	datatype token
+	datatype dataType
+	// DEFAULT expression
+	defaultValue *expression
+	// GENERATED ALWAYS AS (expression) STORED
+	generated *expression
+	// GENERATED { ALWAYS | BY DEFAULT } AS IDENTITY
+	identity *identityColumn
+	// 写在列上的约束
+	constraints []*constraint
+}
+
+// [CONSTRAINT name] CHECK (expression) 或外键, 没有名字时由表名和列名生成
+type constraint struct {
+	name       *token
+	check      *expression
+	foreignKey *foreignKey
+}
+
+type foreignKeyAction uint
+
+const (
+	noAction foreignKeyAction = iota
+	restrictAction
+	cascadeAction
+	setNullAction
+)
+
+// FOREIGN KEY (columns) REFERENCES table (references) ON DELETE action
+type foreignKey struct {
+	columns    []*token
+	table      token
+	references []*token
+	onDelete   foreignKeyAction
 }
 
 type CreateTableStatement struct {
+	name        token
+	cols        *[]*columnDefinition
+	constraints []*constraint
+}
+
+// CREATE TYPE name AS ENUM ('label' [, ...])
+type CreateTypeStatement struct {
+	name   token
+	labels []*token
+}
+
+// CREATE SEQUENCE name [options]
+type CreateSequenceStatement struct {
+	name    token
+	options sequenceOptions
+}
+
+// INCREMENT [BY] n, MINVALUE n, MAXVALUE n, START [WITH] n, 没写的选项为 nil
+type sequenceOptions struct {
+	increment *int64
+	minValue  *int64
+	maxValue  *int64
+	start     *int64
+}
+
+// ALWAYS 的标识列不能写入显式的值, BY DEFAULT 的和 SERIAL 一样只是默认值
+type identityColumn struct {
+	always  bool
+	options sequenceOptions
+}
+
+// ALTER TABLE name action [, ...]
+type AlterTableStatement struct {
+	table   token
+	actions []*alterTableAction
+}
+
+type alterTableActionKind uint
+
+const (
+	// ADD [COLUMN] column-definition
+	addColumnKind alterTableActionKind = iota
+	// DROP [COLUMN] name
+	dropColumnKind
+	// RENAME [COLUMN] name TO new-name
+	renameColumnKind
+	// RENAME TO new-name
+	renameTableKind
+	// ALTER [COLUMN] name [SET DATA] TYPE type [USING expression]
+	alterColumnTypeKind
+	// ADD table-constraint
+	addConstraintKind
+	// DROP CONSTRAINT name
+	dropConstraintKind
+)
+
+type alterTableAction struct {
+	kind       alterTableActionKind
+	column     *columnDefinition
+	constraint *constraint
+	// 被删除, 重命名或修改类型的列, 或者被删除的约束
+	name    token
+	newName token
+	// ALTER COLUMN TYPE 的新类型和转换表达式
+	datatype dataType
+	using    *expression
+}
+
+// CREATE [OR REPLACE | MATERIALIZED] VIEW name [(column [, ...])] AS SELECT ...
+type CreateViewStatement struct {
+	name         token
+	columns      []*token
+	query        *SelectStatement
+	replace      bool
+	materialized bool
+}
+
+// DROP [MATERIALIZED] VIEW [IF EXISTS] name
+type DropViewStatement struct {
+	name         token
+	ifExists     bool
+	materialized bool
+}
+
+// REFRESH MATERIALIZED VIEW name
+type RefreshMaterializedViewStatement struct {
 	name token
-	cols *[]*columnDefinition
+}
+
+// EXPLAIN [ANALYZE] SELECT ...
+type ExplainStatement struct {
+	analyze bool
+	query   *SelectStatement
 }
 
 type SelectStatement struct {
-	item []*expression
-	from token
+	// WITH [RECURSIVE] 定义的临时表, 只在这条语句里可见
+	with      []*commonTableExpression
+	recursive bool
+	item      []*expression
+	from      []*tableReference
+	where     *expression
+	// UNION [ALL] 连接的其他查询, 从左到右依次合并
+	union []*setOperation
+	// ORDER BY, LIMIT 和 OFFSET 作用于整个查询, 包括 UNION 合并后的结果
+	orderBy []*orderItem
+	limit   *expression
+	offset  *expression
+}
+
+// FROM 里的 name [[AS] alias], 有多张表时列名要写成 alias.column 或者在所有表里唯一
+type tableReference struct {
+	name  token
+	alias *token
+}
+
+// name [(column [, ...])] AS (SELECT ...)
+type commonTableExpression struct {
+	name    token
+	columns []*token
+	query   *SelectStatement
+}
+
+// UNION [ALL] SELECT ...
+type setOperation struct {
+	all   bool
+	query *SelectStatement
 }
@@ -0,0 +1,61 @@
+package jiesql
+
+import (
+	"encoding/hex"
+	"strings"
+)
+
+// byteaCell 保存原始字节, MemoryCell 本身就是 []byte, 所以不需要额外编码
+func byteaCell(b []byte) MemoryCell {
+	return MemoryCell(append([]byte{}, b...))
+}
+
+// parseBytea 解析 bytea 的文本输入. 和 PostgreSQL 一样支持两种格式:
+// '\xDEADBEEF' 是十六进制格式, 其余按转义格式处理, 其中 \\ 表示反斜杠, \ooo 是三位八进制
+func parseBytea(s string) (MemoryCell, error) {
+	if strings.HasPrefix(s, `\x`) || strings.HasPrefix(s, `\X`) {
+		return parseHexBytes(s[2:])
+	}
+
+	var b []byte
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' {
+			b = append(b, s[i])
+			continue
+		}
+
+		switch {
+		case i+1 < len(s) && s[i+1] == '\\':
+			b = append(b, '\\')
+			i++
+		case i+3 < len(s) && isOctalDigit(s[i+1]) && isOctalDigit(s[i+2]) && isOctalDigit(s[i+3]) && s[i+1] <= '3':
+			b = append(b, (s[i+1]-'0')<<6|(s[i+2]-'0')<<3|(s[i+3]-'0'))
+			i += 3
+		default:
+			return nil, ErrInvalidValue
+		}
+	}
+
+	return byteaCell(b), nil
+}
+
+func isOctalDigit(c byte) bool {
+	return c >= '0' && c <= '7'
+}
+
+// parseHexBytes 解码十六进制, 和 PostgreSQL 一样允许字节之间有空白
+func parseHexBytes(s string) (MemoryCell, error) {
+	s = strings.Join(strings.Fields(s), "")
+
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidValue
+	}
+
+	return byteaCell(b), nil
+}
+
+// formatBytea 按 PostgreSQL 默认的 hex 格式输出, 例如 \xdeadbeef
+func formatBytea(c MemoryCell) string {
+	return `\x` + hex.EncodeToString(c)
+}
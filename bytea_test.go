@@ -0,0 +1,54 @@
+package jiesql
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestBytea(t *testing.T) {
+	mb := NewMemoryBackend()
+	mustRun(t, mb, `CREATE TABLE blobs (id INT, data BYTEA);
+		INSERT INTO blobs VALUES (1, X'DEADBEEF');
+		INSERT INTO blobs VALUES (2, '\x00ff');
+		INSERT INTO blobs VALUES (3, 'a\\b\101');
+		INSERT INTO blobs VALUES (4, X'');
+		INSERT INTO blobs VALUES (5, null);`)
+
+	expectRows(t, mb, "SELECT id, data FROM blobs", [][]string{
+		{"1", `\xdeadbeef`},
+		{"2", `\x00ff`},
+		{"3", `\x615c6241`},
+		{"4", `\x`},
+		{"5", "null"},
+	})
+
+	results := mustRun(t, mb, "SELECT data FROM blobs WHERE id = 1")
+	if b := results.Rows[0][0].AsBytes(); !bytes.Equal(b, []byte{0xde, 0xad, 0xbe, 0xef}) {
+		t.Errorf("AsBytes() = %x", b)
+	}
+
+	tests := []struct {
+		source   string
+		expected string
+	}{
+		{"SELECT X'de ad'", `\xdead`},
+		{"SELECT x'0A' = '\\x0a'::bytea", "true"},
+		{"SELECT X'01' < X'02'", "true"},
+		{"SELECT X'01' || X'02'", `\x0102`},
+		{"SELECT X'68690a'::text", `\x68690a`},
+		{"SELECT 'hi'::bytea", `\x6869`},
+		{"SELECT X'01' || null", "null"},
+	}
+
+	for _, test := range tests {
+		expectValue(t, mb, test.source, test.expected)
+	}
+
+	expectRows(t, mb, "SELECT id FROM blobs WHERE data = X'00FF'", [][]string{{"2"}})
+
+	expectError(t, mb, "SELECT X'ABC'", ErrInvalidValue)
+	expectError(t, mb, "SELECT X'ZZ'", ErrInvalidValue)
+	expectError(t, mb, "INSERT INTO blobs VALUES (6, '\\q')", ErrInvalidDatatype)
+	expectError(t, mb, "INSERT INTO blobs VALUES (6, 'abc'::text)", ErrInvalidDatatype)
+	expectError(t, mb, "SELECT X'01' + X'02'", ErrInvalidOperands)
+}
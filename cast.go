@@ -0,0 +1,508 @@
+package jiesql
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// coercionContext 表示一种类型转换在哪些场合可以发生
+type coercionContext uint
+
+const (
+	// 只能通过 CAST(expr AS type) 或 expr::type 显式转换
+	explicitCoercion coercionContext = iota
+	// INSERT 时可以自动转换, 显式转换当然也可以
+	assignmentCoercion
+)
+
+// coercions 是不同类型之间的转换矩阵, 同类型之间总是可以转换, 不在表里的组合不能转换.
+// 字符串字面量的类型未定, 可以在任何场合转换成目标类型, 见 isUntypedLiteral
+var coercions = map[[2]ColumnType]coercionContext{
+	{IntType, TextType}:  assignmentCoercion,
+	{BoolType, TextType}: assignmentCoercion,
+	{TextType, IntType}:  explicitCoercion,
+	{TextType, BoolType}: explicitCoercion,
+	{IntType, BoolType}:  explicitCoercion,
+	{BoolType, IntType}:  explicitCoercion,
+
+	{IntType, FloatType}:  assignmentCoercion,
+	{FloatType, IntType}:  assignmentCoercion,
+	{FloatType, TextType}: assignmentCoercion,
+	{TextType, FloatType}: explicitCoercion,
+
+	// 整数之间可以自动转换, 超出范围时报错
+	{SmallIntType, IntType}:    assignmentCoercion,
+	{SmallIntType, BigIntType}: assignmentCoercion,
+	{IntType, SmallIntType}:    assignmentCoercion,
+	{IntType, BigIntType}:      assignmentCoercion,
+	{BigIntType, SmallIntType}: assignmentCoercion,
+	{BigIntType, IntType}:      assignmentCoercion,
+
+	{SmallIntType, FloatType}: assignmentCoercion,
+	{BigIntType, FloatType}:   assignmentCoercion,
+	{FloatType, SmallIntType}: assignmentCoercion,
+	{FloatType, BigIntType}:   assignmentCoercion,
+
+	{SmallIntType, TextType}: assignmentCoercion,
+	{BigIntType, TextType}:   assignmentCoercion,
+	{TextType, SmallIntType}: explicitCoercion,
+	{TextType, BigIntType}:   explicitCoercion,
+
+	{DateType, TimestampType}: assignmentCoercion,
+	{TimestampType, DateType}: assignmentCoercion,
+	{DateType, TextType}:      assignmentCoercion,
+	{TimestampType, TextType}: assignmentCoercion,
+	{IntervalType, TextType}:  assignmentCoercion,
+	{TextType, DateType}:      explicitCoercion,
+	{TextType, TimestampType}: explicitCoercion,
+	{TextType, IntervalType}:  explicitCoercion,
+	{TimestampType, TimeType}: assignmentCoercion,
+	{TimeType, TextType}:      assignmentCoercion,
+	{TextType, TimeType}:      explicitCoercion,
+
+	// 和 PostgreSQL 一样, 整数到 numeric, numeric 到 float 都可以自动转换
+	{SmallIntType, NumericType}: assignmentCoercion,
+	{IntType, NumericType}:      assignmentCoercion,
+	{BigIntType, NumericType}:   assignmentCoercion,
+	{NumericType, SmallIntType}: assignmentCoercion,
+	{NumericType, IntType}:      assignmentCoercion,
+	{NumericType, BigIntType}:   assignmentCoercion,
+	{NumericType, FloatType}:    assignmentCoercion,
+	{FloatType, NumericType}:    assignmentCoercion,
+	{NumericType, TextType}:     assignmentCoercion,
+
+	{ByteaType, TextType}:   assignmentCoercion,
+	{TextType, ByteaType}:   explicitCoercion,
+	{JsonType, TextType}:    assignmentCoercion,
+	{TextType, JsonType}:    explicitCoercion,
+	{UUIDType, TextType}:    assignmentCoercion,
+	{TextType, UUIDType}:    explicitCoercion,
+	{TextType, NumericType}: explicitCoercion,
+}
+
+func canCoerce(from, to ColumnType, ctx coercionContext) bool {
+	if from == to {
+		return true
+	}
+
+	// 数组之间按元素类型判断, 数组和文本之间和其他类型一样
+	if isArrayType(from) && isArrayType(to) {
+		return canCoerce(elementType(from), elementType(to), ctx)
+	}
+
+	if isArrayType(from) && to == TextType {
+		return true
+	}
+
+	if from == TextType && isArrayType(to) {
+		return ctx == explicitCoercion
+	}
+
+	// 枚举和文本之间的转换规则也一样
+	if isEnumType(from) && to == TextType {
+		return true
+	}
+
+	if from == TextType && isEnumType(to) {
+		return ctx == explicitCoercion
+	}
+
+	allowed, ok := coercions[[2]ColumnType{from, to}]
+	if !ok {
+		return false
+	}
+
+	return ctx == explicitCoercion || allowed == assignmentCoercion
+}
+
+// castCell 把类型为 from 的值转换成 to 类型, NULL 转换后仍是 NULL. 转换枚举值时要从 enums 里找到标签
+func castCell(c MemoryCell, from, to ColumnType, ctx coercionContext, enums enumTypes) (MemoryCell, error) {
+	if !canCoerce(from, to, ctx) {
+		return nil, ErrInvalidCast
+	}
+
+	if c == nil || from == to {
+		return c, nil
+	}
+
+	switch {
+	case isArrayType(from) && isArrayType(to):
+		return castArray(c, from, to, ctx, enums)
+	case to == TextType:
+		return MemoryCell(formatCell(c, from, enums)), nil
+	case from == TextType:
+		return parseCell(c.AsText(), to, enums)
+	case from == IntType && to == BoolType:
+		return boolCell(c.AsInt() != 0), nil
+	case from == BoolType && to == IntType:
+		if c.AsBool() == true {
+			return intCell(1), nil
+		}
+
+		return intCell(0), nil
+	case isIntegerType(from) && isIntegerType(to):
+		return checkedIntegerCell(c.AsInt64(), to)
+	case isIntegerType(from) && to == FloatType:
+		return floatCell(float64(c.AsInt64())), nil
+	case from == FloatType && isIntegerType(to):
+		// 和 PostgreSQL 一样四舍六入五成双, 上界用 -min 比较是因为 float64 无法精确表示 MaxInt64
+		f := math.RoundToEven(c.AsFloat())
+		min, _ := integerRange(to)
+		if math.IsNaN(f) || f < float64(min) || f >= -float64(min) {
+			return nil, ErrOutOfRange
+		}
+
+		return integerCell(int64(f), to), nil
+	case isIntegerType(from) && to == NumericType:
+		return decimalCell(decimalFromInt(c.AsInt64())), nil
+	case from == NumericType && isIntegerType(to):
+		i, ok := c.asDecimal().toInt64()
+		if !ok {
+			return nil, ErrOutOfRange
+		}
+
+		return checkedIntegerCell(i, to)
+	case from == NumericType && to == FloatType:
+		return floatCell(c.asDecimal().float64()), nil
+	case from == FloatType && to == NumericType:
+		d, err := decimalFromFloat(c.AsFloat())
+		if err != nil {
+			return nil, err
+		}
+
+		return decimalCell(d), nil
+	case from == DateType && to == TimestampType:
+		return timestampCell(c.AsInt64() * microsPerDay), nil
+	case from == TimestampType && to == DateType:
+		return dateCell(timeToDate(c.AsTime())), nil
+	case from == TimestampType && to == TimeType:
+		micros := c.AsInt64()
+		return timeCell(micros - floorDiv(micros, microsPerDay)*microsPerDay), nil
+	}
+
+	return nil, ErrInvalidCast
+}
+
+// formatCell 把值转换成文本形式, 和 PostgreSQL 的输出函数对应
+func formatCell(c MemoryCell, typ ColumnType, enums enumTypes) string {
+	if isArrayType(typ) {
+		return formatArray(c, typ, enums)
+	}
+
+	if isEnumType(typ) {
+		return formatEnum(c, typ, enums)
+	}
+
+	switch typ {
+	case IntType, SmallIntType, BigIntType:
+		return strconv.FormatInt(c.AsInt64(), 10)
+	case BoolType:
+		if c.AsBool() == true {
+			return "true"
+		}
+
+		return "false"
+	case FloatType:
+		return formatFloat(c.AsFloat())
+	case DateType:
+		return formatDate(int32(c.AsInt64()))
+	case TimestampType:
+		return formatTimestamp(c.AsInt64())
+	case TimeType:
+		return formatTime(c.AsInt64())
+	case IntervalType:
+		return c.AsInterval().String()
+	case NumericType:
+		return c.asDecimal().String()
+	case ByteaType:
+		return formatBytea(c)
+	case UUIDType:
+		return formatUUID(c)
+	}
+
+	return c.AsText()
+}
+
+// parseCell 把文本解析成 typ 类型的值, 和 PostgreSQL 的输入函数对应
+func parseCell(s string, typ ColumnType, enums enumTypes) (MemoryCell, error) {
+	if isArrayType(typ) {
+		return parseArray(s, typ, enums)
+	}
+
+	if isEnumType(typ) {
+		return parseEnum(s, typ, enums)
+	}
+
+	switch typ {
+	case TextType:
+		return MemoryCell(s), nil
+	case IntType, SmallIntType, BigIntType:
+		i, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+		if err != nil {
+			if err.(*strconv.NumError).Err == strconv.ErrRange {
+				return nil, ErrOutOfRange
+			}
+
+			return nil, ErrInvalidValue
+		}
+
+		return checkedIntegerCell(i, typ)
+	case BoolType:
+		switch strings.ToLower(strings.TrimSpace(s)) {
+		case "t", "true", "y", "yes", "on", "1":
+			return boolCell(true), nil
+		case "f", "false", "n", "no", "off", "0":
+			return boolCell(false), nil
+		}
+
+		return nil, ErrInvalidValue
+	case FloatType:
+		f, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+		if err != nil {
+			return nil, ErrInvalidValue
+		}
+
+		return floatCell(f), nil
+	case DateType:
+		return parseDate(s)
+	case TimestampType:
+		return parseTimestamp(s)
+	case TimeType:
+		return parseTime(s)
+	case IntervalType:
+		return parseInterval(s)
+	case ByteaType:
+		return parseBytea(s)
+	case JsonType:
+		return parseJSON(s)
+	case UUIDType:
+		return parseUUID(s)
+	case NumericType:
+		d, err := parseDecimal(s)
+		if err != nil {
+			return nil, err
+		}
+
+		return decimalCell(d), nil
+	}
+
+	return nil, ErrInvalidDatatype
+}
+
+func isIntegerType(typ ColumnType) bool {
+	return typ == SmallIntType || typ == IntType || typ == BigIntType
+}
+
+// integerRange 返回整数类型能表示的范围
+func integerRange(typ ColumnType) (int64, int64) {
+	switch typ {
+	case SmallIntType:
+		return math.MinInt16, math.MaxInt16
+	case IntType:
+		return math.MinInt32, math.MaxInt32
+	}
+
+	return math.MinInt64, math.MaxInt64
+}
+
+// checkedIntegerCell 检查范围后按 typ 的宽度编码整数
+func checkedIntegerCell(i int64, typ ColumnType) (MemoryCell, error) {
+	min, max := integerRange(typ)
+	if i < min || i > max {
+		return nil, ErrOutOfRange
+	}
+
+	return integerCell(i, typ), nil
+}
+
+// formatFloat 用能准确还原的最短形式输出浮点数, 特殊值沿用 PostgreSQL 的写法
+func formatFloat(f float64) string {
+	switch {
+	case math.IsNaN(f):
+		return "NaN"
+	case math.IsInf(f, 1):
+		return "Infinity"
+	case math.IsInf(f, -1):
+		return "-Infinity"
+	}
+
+	// 和 PostgreSQL 一样, 数量级太大或太小时才用科学计数法
+	if abs := math.Abs(f); f != 0 && (abs < 1e-4 || abs >= 1e15) {
+		return strconv.FormatFloat(f, 'g', -1, 64)
+	}
+
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// columnTypeFromName 根据类型名找到对应的列类型
+func columnTypeFromName(name string) (ColumnType, bool) {
+	switch name {
+	case "int", "integer", "int4":
+		return IntType, true
+	case "bigint", "int8":
+		return BigIntType, true
+	case "smallint", "int2":
+		return SmallIntType, true
+	case "text", "varchar", "character varying", "char", "character", "bpchar":
+		return TextType, true
+	case "boolean", "bool":
+		return BoolType, true
+	case "real", "float4", "float8", "float", "double", "double precision":
+		return FloatType, true
+	case "date":
+		return DateType, true
+	case "timestamp":
+		return TimestampType, true
+	case "time":
+		return TimeType, true
+	case "interval":
+		return IntervalType, true
+	case "numeric", "decimal":
+		return NumericType, true
+	case "bytea", "blob":
+		return ByteaType, true
+	case "json":
+		return JsonType, true
+	case "uuid":
+		return UUIDType, true
+	}
+
+	return 0, false
+}
+
+// typeModifier 是类型名后面括号里的参数, 例如 VARCHAR(64) 的长度, 没有参数时为零值
+type typeModifier struct {
+	// 文本的最大字符数, 0 表示不限制
+	length int
+	// CHAR(n) 在末尾补空格到 n 个字符
+	padded bool
+	// NUMERIC(precision, scale) 的总位数和小数位数, precision 为 0 表示不限制
+	precision int
+	scale     int
+}
+
+// resolveDataType 找到类型名对应的列类型并检查参数, 内置类型优先于 CREATE TYPE 创建的类型
+func (mb *MemoryBackend) resolveDataType(dt dataType) (ColumnType, typeModifier, error) {
+	typ, ok := columnTypeFromName(dt.name.value)
+	if !ok {
+		typ, ok = mb.types[dt.name.value]
+	}
+	if !ok {
+		return 0, typeModifier{}, ErrInvalidDatatype
+	}
+
+	var args []int
+	for _, m := range dt.modifiers {
+		i, err := strconv.Atoi(m.value)
+		if err != nil {
+			return 0, typeModifier{}, ErrInvalidDatatype
+		}
+
+		args = append(args, i)
+	}
+
+	mod := typeModifier{}
+	switch dt.name.value {
+	case "varchar", "character varying", "char", "character", "bpchar":
+		// CHAR 不写长度时是 CHAR(1), VARCHAR 不写长度时不限制
+		if len(args) == 0 && dt.name.value != "varchar" && dt.name.value != "character varying" {
+			args = []int{1}
+		}
+
+		if len(args) > 1 || (len(args) == 1 && args[0] < 1) {
+			return 0, typeModifier{}, ErrInvalidDatatype
+		}
+
+		if len(args) == 1 {
+			mod.length = args[0]
+		}
+
+		mod.padded = dt.name.value != "varchar" && dt.name.value != "character varying"
+	case "numeric", "decimal":
+		// NUMERIC(p) 等价于 NUMERIC(p, 0)
+		if len(args) > 2 || (len(args) > 0 && (args[0] < 1 || args[0] > maxNumericScale)) {
+			return 0, typeModifier{}, ErrInvalidDatatype
+		}
+
+		if len(args) > 0 {
+			mod.precision = args[0]
+		}
+
+		if len(args) == 2 {
+			if args[1] < 0 || args[1] > args[0] {
+				return 0, typeModifier{}, ErrInvalidDatatype
+			}
+
+			mod.scale = args[1]
+		}
+	default:
+		if len(args) > 0 {
+			return 0, typeModifier{}, ErrInvalidDatatype
+		}
+	}
+
+	if dt.array {
+		typ = arrayType(typ)
+	}
+
+	return typ, mod, nil
+}
+
+// applyTypeModifier 检查值是否符合类型参数. NUMERIC(p, s) 先四舍五入到 s 位小数, 整数部分超出时报错.
+// 和 PostgreSQL 一样, 超长的文本在显式转换时截断, 赋值时只有超出的部分全是空格才截断, 否则报错.
+// CHAR(n) 不够 n 个字符时在末尾补空格. CHAR 的值就是补齐后的文本, 和 PostgreSQL 不同, 比较时不会忽略末尾的空格.
+func applyTypeModifier(c MemoryCell, typ ColumnType, mod typeModifier, ctx coercionContext) (MemoryCell, error) {
+	// 数组的类型参数作用在每个元素上, 例如 varchar(8)[]
+	if c != nil && isArrayType(typ) {
+		array, err := c.asArray()
+		if err != nil {
+			return nil, err
+		}
+
+		var elems []MemoryCell
+		for _, e := range array {
+			e, err := applyTypeModifier(e, elementType(typ), mod, ctx)
+			if err != nil {
+				return nil, err
+			}
+
+			elems = append(elems, e)
+		}
+
+		return arrayCell(elems), nil
+	}
+
+	if c != nil && typ == NumericType {
+		d, err := checkNumericModifier(c.asDecimal(), mod)
+		if err != nil {
+			return nil, err
+		}
+
+		return decimalCell(d), nil
+	}
+
+	if c == nil || typ != TextType || mod.length == 0 {
+		return c, nil
+	}
+
+	runes := []rune(c.AsText())
+	if len(runes) < mod.length && mod.padded {
+		return MemoryCell(string(runes) + strings.Repeat(" ", mod.length-len(runes))), nil
+	}
+
+	if len(runes) <= mod.length {
+		return c, nil
+	}
+
+	if ctx != explicitCoercion && strings.TrimRight(string(runes[mod.length:]), " ") != "" {
+		return nil, ErrValueTooLong
+	}
+
+	return MemoryCell(string(runes[:mod.length])), nil
+}
+
+// isUntypedLiteral 字符串和 NULL 字面量在 PostgreSQL 里是 unknown 类型, 会按上下文转换成需要的类型
+func isUntypedLiteral(exp expression) bool {
+	return exp.kind == literalKind && (exp.literal.kind == stringKind || exp.literal.kind == nullKind)
+}
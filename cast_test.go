@@ -0,0 +1,104 @@
+package jiesql
+
+import (
+	"testing"
+)
+
+func TestCast(t *testing.T) {
+	mb := NewMemoryBackend()
+
+	tests := []struct {
+		source   string
+		expected string
+	}{
+		{"SELECT CAST('12' AS INT)", "12"},
+		{"SELECT ' 12 '::int", "12"},
+		{"SELECT CAST(12 AS TEXT)", "12"},
+		{"SELECT 12::text || 'a'", "12a"},
+		{"SELECT CAST(true AS TEXT)", "true"},
+		{"SELECT 'yes'::boolean", "true"},
+		{"SELECT 'off'::boolean", "false"},
+		{"SELECT CAST(0 AS BOOLEAN)", "false"},
+		{"SELECT CAST(5 AS BOOLEAN)", "true"},
+		{"SELECT true::int", "1"},
+		{"SELECT false::int", "0"},
+		{"SELECT 1::int::text", "1"},
+		{"SELECT CAST(null AS INT)", "null"},
+		{"SELECT null::text", "null"},
+	}
+
+	for _, test := range tests {
+		expectValue(t, mb, test.source, test.expected)
+	}
+
+	results := mustRun(t, mb, "SELECT CAST(1 AS TEXT), 1::boolean")
+	if results.Columns[0].Type != TextType || results.Columns[1].Type != BoolType {
+		t.Errorf("unexpected column types %v", results.Columns)
+	}
+
+	expectError(t, mb, "SELECT CAST('abc' AS INT)", ErrInvalidValue)
+	expectError(t, mb, "SELECT 'maybe'::boolean", ErrInvalidValue)
+	expectError(t, mb, "SELECT CAST('99999999999' AS INT)", ErrOutOfRange)
+	expectError(t, mb, "SELECT CAST(1 AS nosuchtype)", ErrInvalidDatatype)
+}
+
+func TestInsertCoercion(t *testing.T) {
+	mb := NewMemoryBackend()
+	mustRun(t, mb, "CREATE TABLE t (i INT, s TEXT, b BOOLEAN)")
+
+	// 字符串字面量的类型未定, 按列的类型解析
+	mustRun(t, mb, "INSERT INTO t VALUES ('12', 'x', 'true')")
+	// 整数和布尔值可以自动转换成文本
+	mustRun(t, mb, "INSERT INTO t VALUES (3, 4, false)")
+	mustRun(t, mb, "INSERT INTO t VALUES (null, null, null)")
+	expectRows(t, mb, "SELECT i + 1, s, b FROM t", [][]string{
+		{"13", "x", "true"},
+		{"4", "4", "false"},
+		{"null", "null", "null"},
+	})
+
+	// 文本到整数, 整数到布尔只能显式转换
+	expectError(t, mb, "INSERT INTO t (i) VALUES ('1'::text)", ErrInvalidDatatype)
+	expectError(t, mb, "INSERT INTO t (b) VALUES (1)", ErrInvalidDatatype)
+	expectError(t, mb, "INSERT INTO t (i) VALUES ('abc')", ErrInvalidDatatype)
+	expectError(t, mb, "UPDATE t SET b = 2", ErrInvalidDatatype)
+	mustRun(t, mb, "INSERT INTO t (i, b) VALUES ('7'::int, 1::boolean)")
+	expectRows(t, mb, "SELECT i, b FROM t WHERE i = 7", [][]string{{"7", "true"}})
+}
+
+func TestVarchar(t *testing.T) {
+	mb := NewMemoryBackend()
+	mustRun(t, mb, "CREATE TABLE users (name VARCHAR(5), code CHAR(2), bio TEXT, flag CHAR)")
+
+	mustRun(t, mb, "INSERT INTO users VALUES ('alice', 'ab', 'x', 'y')")
+	// 按字符而不是字节计算长度
+	mustRun(t, mb, "INSERT INTO users VALUES ('你好世界啊', 'c', null, '')")
+	// 超出的部分全是空格时截断
+	mustRun(t, mb, "INSERT INTO users VALUES ('bob  ', 'de   ', null, 'n ')")
+	mustRun(t, mb, "INSERT INTO users VALUES (null, null, null, null)")
+	// CHAR(n) 补空格到 n 个字符, 不写长度时是 CHAR(1)
+	expectRows(t, mb, "SELECT name, code, flag FROM users", [][]string{
+		{"alice", "ab", "y"},
+		{"你好世界啊", "c ", " "},
+		{"bob  ", "de", "n"},
+		{"null", "null", "null"},
+	})
+	expectRows(t, mb, "SELECT name FROM users WHERE code = 'c '", [][]string{{"你好世界啊"}})
+
+	// 显式转换时截断
+	expectValue(t, mb, "SELECT 'abcdef'::varchar(3)", "abc")
+	expectValue(t, mb, "SELECT CAST('abcdef' AS CHAR(2))", "ab")
+	expectValue(t, mb, "SELECT 'abc'::varchar", "abc")
+	expectValue(t, mb, "SELECT 'a'::char(3) || '|'", "a  |")
+	expectValue(t, mb, "SELECT 'abc'::char", "a")
+	expectValue(t, mb, "SELECT ''::char", " ")
+	expectValue(t, mb, "SELECT 'a'::varchar(3) || '|'", "a|")
+
+	expectError(t, mb, "INSERT INTO users (name) VALUES ('alice!')", ErrValueTooLong)
+	expectError(t, mb, "INSERT INTO users (code) VALUES (123)", ErrValueTooLong)
+	expectError(t, mb, "UPDATE users SET code = 'xyz'", ErrValueTooLong)
+	expectError(t, mb, "INSERT INTO users (flag) VALUES ('no')", ErrValueTooLong)
+	expectError(t, mb, "CREATE TABLE bad (name VARCHAR(0))", ErrInvalidDatatype)
+	expectError(t, mb, "CREATE TABLE bad (n INT(3))", ErrInvalidDatatype)
+	expectError(t, mb, "CREATE TABLE bad (name VARCHAR(1, 2))", ErrInvalidDatatype)
+}
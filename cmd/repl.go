@@ -32,12 +32,77 @@ func main() {
 					panic(err)
 				}
 				fmt.Println("ok")
+			case jiesql.CreateTypeKind:
+				err = mb.CreateType(stmt.CreateTypeStatement)
+				if err != nil {
+					panic(err)
+				}
+
+				fmt.Println("ok")
+			case jiesql.CreateSequenceKind:
+				err = mb.CreateSequence(stmt.CreateSequenceStatement)
+				if err != nil {
+					panic(err)
+				}
+
+				fmt.Println("ok")
+			case jiesql.AlterTableKind:
+				err = mb.AlterTable(stmt.AlterTableStatement)
+				if err != nil {
+					panic(err)
+				}
+
+				fmt.Println("ok")
+			case jiesql.CreateViewKind:
+				err = mb.CreateView(stmt.CreateViewStatement)
+				if err != nil {
+					panic(err)
+				}
+
+				fmt.Println("ok")
+			case jiesql.DropViewKind:
+				err = mb.DropView(stmt.DropViewStatement)
+				if err != nil {
+					panic(err)
+				}
+
+				fmt.Println("ok")
+			case jiesql.RefreshMaterializedViewKind:
+				err = mb.RefreshMaterializedView(stmt.RefreshMaterializedViewStatement)
+				if err != nil {
+					panic(err)
+				}
+
+				fmt.Println("ok")
+			case jiesql.ExplainKind:
+				results, err := mb.Explain(stmt.ExplainStatement)
+				if err != nil {
+					panic(err)
+				}
+
+				for i := range results.Rows {
+					fmt.Println(results.Format(i, 0))
+				}
 			case jiesql.InsertKind:
 				err = mb.Insert(stmt.InsertStatement)
 				if err != nil {
 					panic(err)
 				}
 
+				fmt.Println("ok")
+			case jiesql.UpdateKind:
+				err = mb.Update(stmt.UpdateStatement)
+				if err != nil {
+					panic(err)
+				}
+
+				fmt.Println("ok")
+			case jiesql.DeleteKind:
+				err = mb.Delete(stmt.DeleteStatement)
+				if err != nil {
+					panic(err)
+				}
+
 				fmt.Println("ok")
 			case jiesql.SelectKind:
 				results, err := mb.Select(stmt.SelectStatement)
@@ -55,20 +120,11 @@ func main() {
 				}
 				fmt.Println()
 
-				for _, result := range results.Rows {
+				for i := range results.Rows {
 					fmt.Printf("|")
 
-					for i, cell := range result {
-						typ := results.Columns[i].Type
-						s := ""
-						switch typ {
-						case jiesql.IntType:
-							s = fmt.Sprintf("%d", cell.AsInt())
-						case jiesql.TextType:
-							s = cell.AsText()
-						}
-
-						fmt.Printf(" %s | ", s)
+					for j := range results.Rows[i] {
+						fmt.Printf(" %s | ", results.Format(i, j))
 					}
 
 					fmt.Println()
@@ -0,0 +1,522 @@
+package jiesql
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// relationNames 返回查询中 FROM 用到的所有名字, 包括 WITH 和 UNION 里的查询
+func relationNames(slct *SelectStatement) []string {
+	var names []string
+	for _, cte := range slct.with {
+		names = append(names, relationNames(cte.query)...)
+	}
+
+	for _, ref := range slct.from {
+		names = append(names, ref.name.value)
+	}
+
+	for _, op := range slct.union {
+		names = append(names, relationNames(op.query)...)
+	}
+
+	return names
+}
+
+// commonTable 是 WITH 定义的一个临时表. 它在第一次被读取时才开始执行, 产生的行保存在 result 里
+// 给所有的引用共用, 所以外面的 LIMIT 够了以后就不会再算下去, 无限递归的查询也可以用 LIMIT 截断
+type commonTable struct {
+	name string
+	plan *planNode
+	// 只在规划递归项时为 true, 这时对自己的引用读取 working
+	recursive bool
+	estimate  int
+	result    *table
+	done      bool
+	// 递归的临时表上一轮新产生的行
+	working *table
+}
+
+func (ct *commonTable) open() (*table, error) {
+	if ct.result == nil {
+		schema, err := ct.plan.Open()
+		if err != nil {
+			return nil, err
+		}
+
+		result := *schema
+		result.rows = nil
+		ct.result = &result
+	}
+
+	return ct.result, nil
+}
+
+// fetch 返回第 i 行, 还没有产生时从查询里继续取
+func (ct *commonTable) fetch(i int) ([]MemoryCell, bool, error) {
+	for i >= len(ct.result.rows) {
+		if ct.done {
+			return nil, false, nil
+		}
+
+		row, ok, err := ct.plan.Next()
+		if err != nil {
+			return nil, false, err
+		}
+
+		if !ok {
+			ct.done = true
+			continue
+		}
+
+		ct.result.rows = append(ct.result.rows, row)
+	}
+
+	return ct.result.rows[i], true, nil
+}
+
+func (ct *commonTable) close() error {
+	var err error
+	if ct.result != nil {
+		err = ct.plan.Close()
+	}
+
+	ct.result = nil
+	ct.done = false
+	return err
+}
+
+// cteScanOp 按顺序读取 WITH 临时表, 每个引用有自己的位置
+type cteScanOp struct {
+	ct *commonTable
+	i  int
+}
+
+func (c *cteScanOp) Open() (*table, error) {
+	c.i = 0
+	return c.ct.open()
+}
+
+func (c *cteScanOp) Next() ([]MemoryCell, bool, error) {
+	row, ok, err := c.ct.fetch(c.i)
+	if ok {
+		c.i++
+	}
+
+	return row, ok, err
+}
+
+func (c *cteScanOp) Close() error {
+	return nil
+}
+
+// withOp 是带 WITH 的查询最外层的算子, 查询结束时关闭所有的临时表
+type withOp struct {
+	child operator
+	ctes  []*commonTable
+}
+
+func (w *withOp) Open() (*table, error) {
+	t, err := w.child.Open()
+	if err != nil {
+		// 调用方不会再 Close, 打开失败之前可能已经有临时表开始执行了
+		for _, ct := range w.ctes {
+			ct.close()
+		}
+
+		return nil, err
+	}
+
+	return t, nil
+}
+
+func (w *withOp) Next() ([]MemoryCell, bool, error) {
+	return w.child.Next()
+}
+
+func (w *withOp) Close() error {
+	err := w.child.Close()
+	for _, ct := range w.ctes {
+		if e := ct.close(); err == nil {
+			err = e
+		}
+	}
+
+	return err
+}
+
+// planCommonTable 规划 WITH 里的一个临时表. WITH RECURSIVE 时如果最后一个 UNION 引用了自己,
+// 它就是递归项: 每一轮只把上一轮新产生的行作为这个临时表的内容, 直到不再产生新行,
+// 轮数超过 MaxRecursion 时报错
+func (mb *MemoryBackend) planCommonTable(cte *commonTableExpression, recursive bool, scope map[string]*commonTable) (*commonTable, error) {
+	name := cte.name.value
+	var columns []string
+	for _, column := range cte.columns {
+		columns = append(columns, column.value)
+	}
+
+	ct := &commonTable{name: name}
+	query := cte.query
+	n := len(query.union)
+	if !recursive || n == 0 || !containsName(relationNames(query.union[n-1].query), name) {
+		plan, err := mb.planSelect(query, scope)
+		if err != nil {
+			return nil, err
+		}
+
+		plan.op = &renameOp{child: plan.op, name: name, columns: columns}
+		ct.plan = plan
+		ct.estimate = plan.estimate
+		return ct, nil
+	}
+
+	// 非递归项不能引用自己, 这时 name 还不在 scope 里, 会报表不存在
+	anchorQuery := *query
+	anchorQuery.union = query.union[:n-1]
+	step := query.union[n-1]
+
+	anchor, err := mb.planSelect(&anchorQuery, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	inner := map[string]*commonTable{}
+	for n, t := range scope {
+		inner[n] = t
+	}
+	inner[name] = ct
+
+	ct.recursive = true
+	ct.estimate = anchor.estimate
+	iterate, err := mb.planSelect(step.query, inner)
+	ct.recursive = false
+	if err != nil {
+		return nil, err
+	}
+
+	ct.plan = &planNode{
+		title:    "Recursive Union",
+		estimate: anchor.estimate + 10*iterate.estimate,
+		children: []*planNode{anchor, iterate},
+		op: &recursiveUnionOp{
+			mb:      mb,
+			ct:      ct,
+			columns: columns,
+			anchor:  anchor,
+			iterate: iterate,
+			all:     step.all,
+		},
+	}
+	ct.estimate = ct.plan.estimate
+	return ct, nil
+}
+
+// recursiveUnionOp 先返回非递归项的行, 再一轮一轮地执行递归项, 每一轮产生的行
+// 一边返回一边收集起来, 作为下一轮的 WorkTable
+type recursiveUnionOp struct {
+	mb      *MemoryBackend
+	ct      *commonTable
+	columns []string
+	anchor  operator
+	iterate operator
+	all     bool
+
+	schema *table
+	// 当前正在读的是 anchor 还是 iterate, 都读完时为 nil
+	current operator
+	types   []ColumnType
+	rounds  int
+	// 这一轮新产生的行, 以及没有 ALL 时用来去重的所有返回过的行
+	found [][]MemoryCell
+	seen  *rowSet
+}
+
+func (r *recursiveUnionOp) Open() (*table, error) {
+	t, err := r.anchor.Open()
+	if err != nil {
+		return nil, err
+	}
+
+	r.schema, err = renameTable(r.ct.name, r.columns, t)
+	if err != nil {
+		r.anchor.Close()
+		return nil, err
+	}
+
+	r.current = r.anchor
+	r.types = r.schema.columnTypes
+	r.rounds = 0
+	r.found = nil
+	r.seen = newRowSet(r.schema.columnTypes)
+	return r.schema, nil
+}
+
+func (r *recursiveUnionOp) Next() ([]MemoryCell, bool, error) {
+	for r.current != nil {
+		row, ok, err := r.current.Next()
+		if err != nil {
+			return nil, false, err
+		}
+
+		if !ok {
+			if err := r.nextRound(); err != nil {
+				return nil, false, err
+			}
+
+			continue
+		}
+
+		row, err = castRow(row, r.types, r.schema.columnTypes)
+		if err != nil {
+			return nil, false, err
+		}
+
+		if !r.all && !r.seen.add(row) {
+			continue
+		}
+
+		r.found = append(r.found, row)
+		return row, true, nil
+	}
+
+	return nil, false, nil
+}
+
+// nextRound 用上一轮新产生的行作为 WorkTable 重新执行递归项, 没有新行时结束
+func (r *recursiveUnionOp) nextRound() error {
+	if err := r.current.Close(); err != nil {
+		return err
+	}
+	r.current = nil
+
+	if len(r.found) == 0 {
+		return nil
+	}
+
+	if r.rounds >= r.mb.MaxRecursion {
+		return fmt.Errorf("%w: %s stopped after %d iterations", ErrRecursionLimit, r.ct.name, r.mb.MaxRecursion)
+	}
+	r.rounds++
+
+	working := *r.schema
+	working.rows = r.found
+	r.ct.working = &working
+	r.found = nil
+
+	next, err := r.iterate.Open()
+	if err != nil {
+		return err
+	}
+	r.current = r.iterate
+
+	if len(next.columns) != len(r.schema.columns) {
+		return fmt.Errorf("%w: each UNION query must have the same number of columns", ErrInvalidSelectItem)
+	}
+
+	// 和 PostgreSQL 一样, 递归项不能改变非递归项决定的列类型
+	for j, typ := range next.columnTypes {
+		typ, err := unionType(r.schema.columnTypes[j], typ)
+		if err != nil {
+			return err
+		}
+
+		if typ != r.schema.columnTypes[j] {
+			return fmt.Errorf("%w: recursive query %s column %d has type %s in non-recursive term but type %s overall", ErrInvalidDatatype, r.ct.name, j+1, r.schema.columnTypes[j], typ)
+		}
+	}
+
+	r.types = next.columnTypes
+	return nil
+}
+
+func (r *recursiveUnionOp) Close() error {
+	var err error
+	if r.current != nil {
+		err = r.current.Close()
+		r.current = nil
+	}
+
+	r.ct.working = nil
+	r.found = nil
+	r.seen = nil
+	return err
+}
+
+// renameTable 把查询结果当作一张名为 name 的只读表, columns 是给出的列名, 没给出的列沿用结果的列名
+func renameTable(name string, columns []string, t *table) (*table, error) {
+	if len(columns) > len(t.columns) {
+		return nil, fmt.Errorf("%w: %s specifies more column names than columns", ErrInvalidSelectItem, name)
+	}
+
+	var cols []column
+	for i, c := range t.columns {
+		if i < len(columns) {
+			c = columns[i]
+		}
+
+		cols = append(cols, column{Name: c, Type: t.columnTypes[i]})
+	}
+
+	renamed := newResultTable(cols)
+	renamed.name = name
+	renamed.rows = t.rows
+	return renamed, nil
+}
+
+func containsName(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// unionType 决定 UNION 两边同一列合并后的类型, 数值类型提升到范围更大的类型, 其他类型必须相同
+func unionType(a, b ColumnType) (ColumnType, error) {
+	if a == b {
+		return a, nil
+	}
+
+	if isNumericType(a) && isNumericType(b) {
+		if numericRank[b] > numericRank[a] {
+			return b, nil
+		}
+
+		return a, nil
+	}
+
+	return 0, fmt.Errorf("%w: UNION types %s and %s cannot be matched", ErrInvalidDatatype, a, b)
+}
+
+// castRow 把一行的每一列从 from 里的类型转换成 to 里的类型. UNION 两边的类型相同或者都是数值,
+// 见 unionType, 所以不会遇到枚举
+func castRow(row []MemoryCell, from, to []ColumnType) ([]MemoryCell, error) {
+	cast := make([]MemoryCell, len(row))
+	for i, cell := range row {
+		var err error
+		cast[i], err = castCell(cell, from[i], to[i], assignmentCoercion, nil)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return cast, nil
+}
+
+// rowSet 是去重用的行集合, NULL 和 NULL 算相同. 行先按 hashRow 分组, 组内再用 compareCells
+// 逐行比较, 所以 1.0 和 1.00 这样字节不同但是相等的值也算相同
+type rowSet struct {
+	types   []ColumnType
+	buckets map[string][][]MemoryCell
+}
+
+func newRowSet(types []ColumnType) *rowSet {
+	return &rowSet{types: types, buckets: map[string][][]MemoryCell{}}
+}
+
+// add 把 row 加入集合, 集合里已经有相同的行时返回 false
+func (s *rowSet) add(row []MemoryCell) bool {
+	key := hashRow(s.types, row)
+	for _, other := range s.buckets[key] {
+		equal := true
+		for i := range row {
+			if (row[i] == nil) != (other[i] == nil) ||
+				row[i] != nil && compareCells(row[i], other[i], s.types[i]) != 0 {
+				equal = false
+				break
+			}
+		}
+
+		if equal {
+			return false
+		}
+	}
+
+	s.buckets[key] = append(s.buckets[key], row)
+	return true
+}
+
+// hashRow 返回行的分组键, 相等的行分组键一定相同
+func hashRow(types []ColumnType, row []MemoryCell) string {
+	var key []byte
+	for i, cell := range row {
+		key = appendCellHash(key, cell, types[i])
+	}
+
+	return string(key)
+}
+
+// appendCellHash 把值的分组键加在 key 后面. 浮点数, numeric 和 interval 先换算成统一的形式,
+// 其他类型相等时字节也相同
+func appendCellHash(key []byte, cell MemoryCell, typ ColumnType) []byte {
+	if cell == nil {
+		return append(key, 0)
+	}
+
+	var b [8]byte
+	switch {
+	case typ == FloatType || typ == NumericType:
+		var f float64
+		if typ == FloatType {
+			f = cell.AsFloat()
+		} else {
+			f = cell.asDecimal().float64()
+		}
+
+		// 所有的 NaN 相等, -0 等于 0
+		switch {
+		case math.IsNaN(f):
+			f = math.NaN()
+		case f == 0:
+			f = 0
+		}
+
+		binary.BigEndian.PutUint64(b[:], math.Float64bits(f))
+		return append(append(key, 1), b[:]...)
+	case typ == IntervalType:
+		days, micros := normalizeInterval(cell.AsInterval())
+		binary.BigEndian.PutUint64(b[:], uint64(days))
+		key = append(append(key, 1), b[:]...)
+		binary.BigEndian.PutUint64(b[:], uint64(micros))
+		return append(key, b[:]...)
+	case isArrayType(typ):
+		elems, err := cell.asArray()
+		if err != nil {
+			return append(key, 1)
+		}
+
+		key = append(key, 2)
+		for _, elem := range elems {
+			key = appendCellHash(key, elem, elementType(typ))
+		}
+
+		return append(key, 3)
+	}
+
+	binary.BigEndian.PutUint64(b[:], uint64(len(cell)))
+	return append(append(append(key, 1), b[:]...), cell...)
+}
+
+// unionNode 合并 UNION 两边的结果, UNION ALL 直接拼接, 没有 ALL 时再去掉重复的行
+func unionNode(left, right *planNode, all bool) *planNode {
+	node := &planNode{
+		title:    "Append",
+		estimate: left.estimate + right.estimate,
+		children: []*planNode{left, right},
+		op:       &appendOp{left: left, right: right},
+	}
+
+	if all {
+		return node
+	}
+
+	return &planNode{
+		title:    "Unique",
+		estimate: node.estimate,
+		children: []*planNode{node},
+		op:       &uniqueOp{child: node},
+	}
+}
@@ -0,0 +1,107 @@
+package jiesql
+
+import (
+	"testing"
+)
+
+func newOrgChartBackend(t *testing.T) *MemoryBackend {
+	t.Helper()
+
+	mb := NewMemoryBackend()
+	mustRun(t, mb, `CREATE TABLE employees (id INT, name TEXT, manager INT);
+		INSERT INTO employees VALUES (1, 'ceo', null);
+		INSERT INTO employees VALUES (2, 'cto', 1);
+		INSERT INTO employees VALUES (3, 'cfo', 1);
+		INSERT INTO employees VALUES (4, 'dev', 2);
+		INSERT INTO employees VALUES (5, 'intern', 4);`)
+	return mb
+}
+
+func TestCommonTableExpressions(t *testing.T) {
+	mb := newOrgChartBackend(t)
+
+	expectRows(t, mb, "WITH top AS (SELECT id, name FROM employees WHERE manager < 2) SELECT name FROM top", [][]string{{"cto"}, {"cfo"}})
+	// 后面的临时表可以引用前面的, 列名可以在 WITH 里重新指定
+	expectRows(t, mb, `WITH a (x) AS (SELECT id FROM employees WHERE id < 4),
+		b (y) AS (SELECT x * 10 FROM a WHERE x > 1)
+		SELECT y FROM b`, [][]string{{"20"}, {"30"}})
+	expectColumns(t, mb, "WITH a (x) AS (SELECT id, name FROM employees) SELECT x, name FROM a WHERE x = 1", []column{
+		{Name: "x", Type: IntType},
+		{Name: "name", Type: TextType},
+	})
+	// 临时表的名字优先于同名的表
+	expectRows(t, mb, "WITH employees (x) AS (SELECT 42) SELECT x FROM employees", [][]string{{"42"}})
+	expectRows(t, mb, "WITH empty AS (SELECT id FROM employees WHERE id > 10) SELECT id FROM empty", [][]string{})
+	expectRows(t, mb, "WITH n AS (SELECT manager FROM employees WHERE id = 1) SELECT manager FROM n", [][]string{{"null"}})
+
+	expectError(t, mb, "WITH a (x, y) AS (SELECT id FROM employees) SELECT x FROM a", ErrInvalidSelectItem)
+	expectError(t, mb, "WITH a AS (SELECT id FROM employees) SELECT nosuch FROM a", ErrColumnDoesNotExist)
+	expectError(t, mb, "WITH a AS (SELECT id FROM b), b AS (SELECT 1) SELECT id FROM a", ErrTableDoesNotExist)
+	// 没有 RECURSIVE 时不能引用自己
+	expectError(t, mb, "WITH a AS (SELECT 1 UNION SELECT 2 FROM a) SELECT 1 FROM a", ErrTableDoesNotExist)
+}
+
+func TestUnion(t *testing.T) {
+	mb := newOrgChartBackend(t)
+
+	expectRows(t, mb, "SELECT 1 UNION SELECT 2 UNION SELECT 1", [][]string{{"1"}, {"2"}})
+	expectRows(t, mb, "SELECT 1 UNION ALL SELECT 2 UNION ALL SELECT 1", [][]string{{"1"}, {"2"}, {"1"}})
+	// 去重时 NULL 和 NULL 算相同
+	expectRows(t, mb, "SELECT manager FROM employees UNION SELECT null::int", [][]string{{"null"}, {"1"}, {"2"}, {"4"}})
+	expectRows(t, mb, "SELECT id FROM employees WHERE id > 3 UNION SELECT 1.5", [][]string{{"4"}, {"5"}, {"1.5"}})
+	expectColumns(t, mb, "SELECT id FROM employees UNION SELECT 1.5", []column{{Name: "id", Type: NumericType}})
+
+	// 字节不同但是相等的值也要去掉
+	expectRows(t, mb, "SELECT 1.0 UNION SELECT 1.00 UNION SELECT 1.5", [][]string{{"1.0"}, {"1.5"}})
+	expectRows(t, mb, "SELECT 0::float UNION SELECT -0::float UNION SELECT 'NaN'::float UNION SELECT 'NaN'::float", [][]string{{"0"}, {"NaN"}})
+	expectRows(t, mb, "SELECT '1 mon'::interval UNION SELECT '30 days'::interval UNION SELECT '24 hours'::interval UNION SELECT '1 day'::interval", [][]string{
+		{"1 mon"}, {"24:00:00"},
+	})
+	expectRows(t, mb, "SELECT ARRAY[1.0, null] UNION SELECT ARRAY[1.00, null] UNION SELECT ARRAY[1.0]", [][]string{{"{1.0,NULL}"}, {"{1.0}"}})
+	expectRows(t, mb, "SELECT 'a', null::int UNION SELECT 'a', null::int UNION SELECT 'a', 1", [][]string{{"a", "null"}, {"a", "1"}})
+	expectRows(t, mb, "SELECT 'ab', 'c' UNION SELECT 'a', 'bc'", [][]string{{"ab", "c"}, {"a", "bc"}})
+
+	expectError(t, mb, "SELECT 1 UNION SELECT 1, 2", ErrInvalidSelectItem)
+	expectError(t, mb, "SELECT 1 UNION SELECT 'a'::text", ErrInvalidDatatype)
+}
+
+func TestRecursiveCommonTableExpressions(t *testing.T) {
+	mb := newOrgChartBackend(t)
+
+	// 从 cto 开始找出所有下属和层级
+	expectRows(t, mb, `WITH RECURSIVE reports (id, name, depth) AS (
+			SELECT id, name, 0 FROM employees WHERE name = 'cto'
+			UNION ALL
+			SELECT employees.id, employees.name, reports.depth + 1 FROM employees, reports WHERE employees.manager = reports.id
+		)
+		SELECT name, depth FROM reports`, [][]string{{"cto", "0"}, {"dev", "1"}, {"intern", "2"}})
+
+	// 向上找出 intern 的所有上级
+	expectRows(t, mb, `WITH RECURSIVE chain (id, manager) AS (
+			SELECT id, manager FROM employees WHERE id = 5
+			UNION
+			SELECT employees.id, employees.manager FROM employees, chain WHERE employees.id = chain.manager
+		)
+		SELECT id FROM chain`, [][]string{{"5"}, {"4"}, {"2"}, {"1"}})
+
+	expectRows(t, mb, "WITH RECURSIVE n (i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n WHERE i < 5) SELECT i FROM n", [][]string{
+		{"1"}, {"2"}, {"3"}, {"4"}, {"5"},
+	})
+	// 没有 ALL 时重复的行不再参与下一轮, 所以环也能结束
+	expectRows(t, mb, "WITH RECURSIVE n (i) AS (SELECT 1 UNION SELECT 3 - i FROM n) SELECT i FROM n", [][]string{{"1"}, {"2"}})
+	// 非递归项没有行时结果是空的
+	expectRows(t, mb, "WITH RECURSIVE n (i) AS (SELECT id FROM employees WHERE id > 10 UNION ALL SELECT i + 1 FROM n) SELECT i FROM n", [][]string{})
+	// LIMIT 够了以后就不再递归下去
+	expectRows(t, mb, "WITH RECURSIVE n (i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n) SELECT i FROM n LIMIT 3", [][]string{
+		{"1"}, {"2"}, {"3"},
+	})
+
+	expectError(t, mb, "WITH RECURSIVE n (i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n) SELECT i FROM n", ErrRecursionLimit)
+	mb.MaxRecursion = 3
+	expectRows(t, mb, "WITH RECURSIVE n (i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n WHERE i < 3) SELECT i FROM n", [][]string{{"1"}, {"2"}, {"3"}})
+	expectError(t, mb, "WITH RECURSIVE n (i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n WHERE i < 4) SELECT i FROM n", ErrRecursionLimit)
+
+	expectError(t, mb, "WITH RECURSIVE n (i) AS (SELECT 1 UNION ALL SELECT i, i FROM n) SELECT i FROM n", ErrInvalidSelectItem)
+	expectError(t, mb, "WITH RECURSIVE n (i) AS (SELECT 1 UNION ALL SELECT i::bigint FROM n) SELECT i FROM n", ErrInvalidDatatype)
+	expectError(t, mb, "WITH RECURSIVE n (i) AS (SELECT i FROM n UNION ALL SELECT 1) SELECT i FROM n", ErrTableDoesNotExist)
+}
@@ -0,0 +1,611 @@
+package jiesql
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DATE 存成距 1970-01-01 的天数 (4 字节), TIMESTAMP 存成距 1970-01-01 00:00:00 的微秒数 (8 字节),
+// 都不带时区. INTERVAL 和 PostgreSQL 一样分成月, 天, 微秒三部分存储, 因为一个月的天数和
+// 一天的小时数都不是固定的.
+
+const (
+	microsPerSecond = int64(1000000)
+	microsPerMinute = 60 * microsPerSecond
+	microsPerHour   = 60 * microsPerMinute
+	microsPerDay    = 24 * microsPerHour
+	// 比较 interval 的大小时和 PostgreSQL 一样按一个月 30 天算
+	daysPerMonth = 30
+)
+
+// Interval 是 INTERVAL 类型的值
+type Interval struct {
+	Months       int32
+	Days         int32
+	Microseconds int64
+}
+
+// String 按 PostgreSQL 默认的格式输出, 例如 1 year 2 mons 3 days 04:05:06
+func (iv Interval) String() string {
+	var parts []string
+
+	// 和 PostgreSQL 一样只有 1 用单数, -1 也用复数
+	plural := func(n int64, unit string) string {
+		if n == 1 {
+			return fmt.Sprintf("%d %s", n, unit)
+		}
+
+		return fmt.Sprintf("%d %ss", n, unit)
+	}
+
+	years, months := iv.Months/12, iv.Months%12
+	if years != 0 {
+		parts = append(parts, plural(int64(years), "year"))
+	}
+	if months != 0 {
+		parts = append(parts, plural(int64(months), "mon"))
+	}
+	if iv.Days != 0 {
+		parts = append(parts, plural(int64(iv.Days), "day"))
+	}
+
+	if iv.Microseconds != 0 || len(parts) == 0 {
+		micros := iv.Microseconds
+		sign := ""
+		if micros < 0 {
+			sign = "-"
+			micros = -micros
+		}
+
+		clock := fmt.Sprintf("%s%02d:%02d:%02d", sign, micros/microsPerHour, micros%microsPerHour/microsPerMinute, micros%microsPerMinute/microsPerSecond)
+		if frac := micros % microsPerSecond; frac != 0 {
+			clock += strings.TrimRight(fmt.Sprintf(".%06d", frac), "0")
+		}
+		parts = append(parts, clock)
+	}
+
+	return strings.Join(parts, " ")
+}
+
+func (mc MemoryCell) AsTime() time.Time {
+	switch len(mc) {
+	case 4:
+		return dateToTime(int32(mc.AsInt64()))
+	case 8:
+		return timestampToTime(mc.AsInt64())
+	}
+
+	panic(ErrInvalidCell)
+}
+
+func (mc MemoryCell) AsInterval() Interval {
+	var iv Interval
+	err := binary.Read(bytes.NewBuffer(mc), binary.BigEndian, &iv)
+	if err != nil {
+		panic(err)
+	}
+
+	return iv
+}
+
+func intervalCell(iv Interval) MemoryCell {
+	buf := new(bytes.Buffer)
+	err := binary.Write(buf, binary.BigEndian, iv)
+	if err != nil {
+		panic(err)
+	}
+
+	return MemoryCell(buf.Bytes())
+}
+
+func dateCell(days int32) MemoryCell {
+	return integerCell(int64(days), IntType)
+}
+
+func timestampCell(micros int64) MemoryCell {
+	return integerCell(micros, BigIntType)
+}
+
+// time 保存从零点开始的微秒数, 和 timestamp 一样是 8 个字节, 所以不能用 AsTime
+func timeCell(micros int64) MemoryCell {
+	return integerCell(micros, BigIntType)
+}
+
+// floorDiv 向下取整的除法, 用于 1970 年以前的时间
+func floorDiv(a, b int64) int64 {
+	q := a / b
+	if a%b != 0 && (a < 0) != (b < 0) {
+		q--
+	}
+
+	return q
+}
+
+func dateToTime(days int32) time.Time {
+	return time.Unix(int64(days)*86400, 0).UTC()
+}
+
+func timeToDate(t time.Time) int32 {
+	return int32(floorDiv(t.Unix(), 86400))
+}
+
+func timestampToTime(micros int64) time.Time {
+	return time.Unix(floorDiv(micros, microsPerSecond), (micros-floorDiv(micros, microsPerSecond)*microsPerSecond)*1000).UTC()
+}
+
+func timeToTimestamp(t time.Time) int64 {
+	return t.Unix()*microsPerSecond + int64(t.Nanosecond())/1000
+}
+
+func formatDate(days int32) string {
+	return dateToTime(days).Format("2006-01-02")
+}
+
+func formatTimestamp(micros int64) string {
+	t := timestampToTime(micros)
+	s := t.Format("2006-01-02 15:04:05")
+	if frac := t.Nanosecond() / 1000; frac != 0 {
+		s += strings.TrimRight(fmt.Sprintf(".%06d", frac), "0")
+	}
+
+	return s
+}
+
+// formatTime 和 PostgreSQL 一样输出 HH:MM:SS, 有小数秒时才输出小数部分
+func formatTime(micros int64) string {
+	s := fmt.Sprintf("%02d:%02d:%02d", micros/microsPerHour, micros%microsPerHour/microsPerMinute, micros%microsPerMinute/microsPerSecond)
+	if frac := micros % microsPerSecond; frac != 0 {
+		s += strings.TrimRight(fmt.Sprintf(".%06d", frac), "0")
+	}
+
+	return s
+}
+
+var timestampLayouts = []string{
+	"2006-01-02 15:04:05.999999",
+	"2006-01-02T15:04:05.999999",
+	"2006-01-02 15:04",
+	"2006-01-02T15:04",
+	"2006-01-02",
+}
+
+func parseDate(s string) (MemoryCell, error) {
+	t, err := time.Parse("2006-01-02", strings.TrimSpace(s))
+	if err != nil {
+		return nil, ErrInvalidValue
+	}
+
+	return dateCell(timeToDate(t)), nil
+}
+
+func parseTimestamp(s string) (MemoryCell, error) {
+	s = strings.TrimSpace(s)
+	for _, layout := range timestampLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return timestampCell(timeToTimestamp(t)), nil
+		}
+	}
+
+	return nil, ErrInvalidValue
+}
+
+// parseTime 解析 HH:MM[:SS[.ffffff]], 和 PostgreSQL 一样允许 24:00:00
+func parseTime(s string) (MemoryCell, error) {
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, "-") {
+		return nil, ErrInvalidValue
+	}
+
+	micros, err := parseClock(s)
+	if err != nil || micros > microsPerDay {
+		return nil, ErrInvalidValue
+	}
+
+	return timeCell(micros), nil
+}
+
+// intervalUnits 是 interval 文本里每个单位对应的月数, 天数或微秒数, 只有一项非零
+var intervalUnits = map[string]struct {
+	months int64
+	days   int64
+	micros int64
+}{
+	"microsecond": {micros: 1},
+	"millisecond": {micros: 1000},
+	"second":      {micros: microsPerSecond},
+	"sec":         {micros: microsPerSecond},
+	"s":           {micros: microsPerSecond},
+	"minute":      {micros: microsPerMinute},
+	"min":         {micros: microsPerMinute},
+	"m":           {micros: microsPerMinute},
+	"hour":        {micros: microsPerHour},
+	"hr":          {micros: microsPerHour},
+	"h":           {micros: microsPerHour},
+	"day":         {days: 1},
+	"d":           {days: 1},
+	"week":        {days: 7},
+	"w":           {days: 7},
+	"month":       {months: 1},
+	"mon":         {months: 1},
+	"year":        {months: 12},
+	"yr":          {months: 12},
+	"y":           {months: 12},
+	"decade":      {months: 120},
+	"century":     {months: 1200},
+	"millennium":  {months: 12000},
+}
+
+// parseInterval 解析 PostgreSQL 风格的 interval, 例如 '1 day 2 hours', '1 year 02:00:00', '3 days ago'
+func parseInterval(s string) (MemoryCell, error) {
+	fields := strings.Fields(strings.ToLower(strings.TrimPrefix(strings.TrimSpace(s), "@")))
+	if len(fields) == 0 {
+		return nil, ErrInvalidValue
+	}
+
+	ago := false
+	if fields[len(fields)-1] == "ago" {
+		ago = true
+		fields = fields[:len(fields)-1]
+	}
+
+	var months, days, micros float64
+	for i := 0; i < len(fields); i++ {
+		field := fields[i]
+
+		if strings.Contains(field, ":") {
+			m, err := parseClock(field)
+			if err != nil {
+				return nil, err
+			}
+
+			micros += float64(m)
+			continue
+		}
+
+		n, err := strconv.ParseFloat(field, 64)
+		if err != nil || i+1 >= len(fields) {
+			return nil, ErrInvalidValue
+		}
+		i++
+
+		unit := fields[i]
+		u, ok := intervalUnits[unit]
+		if !ok && strings.HasSuffix(unit, "s") {
+			u, ok = intervalUnits[strings.TrimSuffix(unit, "s")]
+		}
+		if !ok {
+			return nil, ErrInvalidValue
+		}
+
+		months += n * float64(u.months)
+		days += n * float64(u.days)
+		micros += n * float64(u.micros)
+	}
+
+	iv := justifyFractions(months, days, micros)
+	if ago {
+		iv = negateInterval(iv)
+	}
+
+	return intervalCell(iv), nil
+}
+
+// parseClock 解析 [-]HH:MM[:SS[.ffffff]] 形式的时间, 返回微秒数
+func parseClock(s string) (int64, error) {
+	sign := int64(1)
+	if strings.HasPrefix(s, "-") {
+		sign = -1
+		s = s[1:]
+	}
+
+	parts := strings.Split(s, ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return 0, ErrInvalidValue
+	}
+
+	hours, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return 0, ErrInvalidValue
+	}
+
+	minutes, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil || minutes >= 60 {
+		return 0, ErrInvalidValue
+	}
+
+	seconds := 0.0
+	if len(parts) == 3 {
+		seconds, err = strconv.ParseFloat(parts[2], 64)
+		if err != nil || seconds >= 60 {
+			return 0, ErrInvalidValue
+		}
+	}
+
+	micros := hours*microsPerHour + minutes*microsPerMinute + int64(math.Round(seconds*float64(microsPerSecond)))
+	return sign * micros, nil
+}
+
+// justifyFractions 把月和天的小数部分转移到更小的单位上, 例如 1.5 month = 1 mon 15 days
+func justifyFractions(months, days, micros float64) Interval {
+	wholeMonths := math.Trunc(months)
+	days += (months - wholeMonths) * daysPerMonth
+
+	wholeDays := math.Trunc(days)
+	micros += (days - wholeDays) * float64(microsPerDay)
+
+	return Interval{
+		Months:       int32(wholeMonths),
+		Days:         int32(wholeDays),
+		Microseconds: int64(math.Round(micros)),
+	}
+}
+
+func negateInterval(iv Interval) Interval {
+	return Interval{
+		Months:       -iv.Months,
+		Days:         -iv.Days,
+		Microseconds: -iv.Microseconds,
+	}
+}
+
+// compareIntervals 按一个月 30 天, 一天 24 小时换算后比较
+func compareIntervals(a, b Interval) int {
+	ad, am := normalizeInterval(a)
+	bd, bm := normalizeInterval(b)
+	switch {
+	case ad < bd, ad == bd && am < bm:
+		return -1
+	case ad > bd, ad == bd && am > bm:
+		return 1
+	}
+
+	return 0
+}
+
+// normalizeInterval 按一个月 30 天, 一天 24 小时把 interval 换算成天数和不到一天的微秒数,
+// 换算结果相同的 interval 相等
+func normalizeInterval(iv Interval) (int64, int64) {
+	days := int64(iv.Months)*daysPerMonth + int64(iv.Days) + floorDiv(iv.Microseconds, microsPerDay)
+	return days, iv.Microseconds - floorDiv(iv.Microseconds, microsPerDay)*microsPerDay
+}
+
+// addInterval 在时间上加一个 interval, 月份相加后如果日期超出当月天数就取当月最后一天,
+// 例如 2026-01-31 + 1 mon = 2026-02-28
+func addInterval(t time.Time, iv Interval) time.Time {
+	if iv.Months != 0 {
+		year, month, day := t.Date()
+		first := time.Date(year, month+time.Month(iv.Months), 1, 0, 0, 0, 0, time.UTC)
+		last := first.AddDate(0, 1, -1).Day()
+		if day > last {
+			day = last
+		}
+		t = time.Date(first.Year(), first.Month(), day, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+	}
+
+	t = t.AddDate(0, 0, int(iv.Days))
+	return t.Add(time.Duration(iv.Microseconds) * time.Microsecond)
+}
+
+func isDatetimeType(typ ColumnType) bool {
+	return typ == DateType || typ == TimestampType || typ == TimeType || typ == IntervalType
+}
+
+// evaluateDatetimeArithmetic 计算日期时间相关的 + - * /
+func evaluateDatetimeArithmetic(op symbol, l MemoryCell, lt ColumnType, r MemoryCell, rt ColumnType) (MemoryCell, ColumnType, error) {
+	// 交换律: interval + timestamp, int + date, number * interval
+	if (op == plusSymbol || op == asteriskSymbol) && !isDatetimeType(lt) || (op == plusSymbol && lt == IntervalType && rt != IntervalType) {
+		l, lt, r, rt = r, rt, l, lt
+	}
+
+	var typ ColumnType
+	switch {
+	case lt == DateType && isIntegerType(rt) && (op == plusSymbol || op == minusSymbol):
+		typ = DateType
+	case lt == DateType && rt == DateType && op == minusSymbol:
+		typ = IntType
+	case (lt == DateType || lt == TimestampType) && rt == IntervalType && (op == plusSymbol || op == minusSymbol):
+		typ = TimestampType
+	case lt == TimeType && rt == IntervalType && (op == plusSymbol || op == minusSymbol):
+		typ = TimeType
+	case (lt == TimestampType && rt == TimestampType || lt == TimeType && rt == TimeType) && op == minusSymbol:
+		typ = IntervalType
+	case lt == IntervalType && rt == IntervalType && (op == plusSymbol || op == minusSymbol):
+		typ = IntervalType
+	case lt == IntervalType && isNumericType(rt) && (op == asteriskSymbol || op == slashSymbol):
+		typ = IntervalType
+	default:
+		return nil, 0, ErrInvalidOperands
+	}
+
+	if l == nil || r == nil {
+		return nil, typ, nil
+	}
+
+	switch {
+	case lt == DateType && isIntegerType(rt):
+		days := int64(l.AsInt64())
+		if op == plusSymbol {
+			days += r.AsInt64()
+		} else {
+			days -= r.AsInt64()
+		}
+
+		if days < math.MinInt32 || days > math.MaxInt32 {
+			return nil, 0, ErrOutOfRange
+		}
+
+		return dateCell(int32(days)), DateType, nil
+	case lt == DateType && rt == DateType:
+		return intCell(int32(l.AsInt64() - r.AsInt64())), IntType, nil
+	case lt == TimeType && rt == IntervalType:
+		// 和 PostgreSQL 一样只加上 interval 里的时间部分, 超过一天时绕回
+		micros := r.AsInterval().Microseconds
+		if op == minusSymbol {
+			micros = -micros
+		}
+
+		micros += l.AsInt64()
+		return timeCell(micros - floorDiv(micros, microsPerDay)*microsPerDay), TimeType, nil
+	case lt == TimeType && rt == TimeType:
+		return intervalCell(Interval{Microseconds: l.AsInt64() - r.AsInt64()}), IntervalType, nil
+	case rt == IntervalType && lt != IntervalType:
+		iv := r.AsInterval()
+		if op == minusSymbol {
+			iv = negateInterval(iv)
+		}
+
+		return timestampCell(timeToTimestamp(addInterval(l.AsTime(), iv))), TimestampType, nil
+	case lt == TimestampType && rt == TimestampType:
+		// 和 PostgreSQL 一样把整天数放到天上, 剩下的放到微秒上
+		diff := l.AsInt64() - r.AsInt64()
+		return intervalCell(Interval{
+			Days:         int32(diff / microsPerDay),
+			Microseconds: diff % microsPerDay,
+		}), IntervalType, nil
+	case rt == IntervalType:
+		a, b := l.AsInterval(), r.AsInterval()
+		if op == minusSymbol {
+			b = negateInterval(b)
+		}
+
+		return intervalCell(Interval{
+			Months:       a.Months + b.Months,
+			Days:         a.Days + b.Days,
+			Microseconds: a.Microseconds + b.Microseconds,
+		}), IntervalType, nil
+	}
+
+	var factor float64
+	switch rt {
+	case FloatType:
+		factor = r.AsFloat()
+	case NumericType:
+		factor = r.asDecimal().float64()
+	default:
+		factor = float64(r.AsInt64())
+	}
+	if op == slashSymbol {
+		if factor == 0 {
+			return nil, 0, ErrDivisionByZero
+		}
+		factor = 1 / factor
+	}
+
+	iv := l.AsInterval()
+	return intervalCell(justifyFractions(float64(iv.Months)*factor, float64(iv.Days)*factor, float64(iv.Microseconds)*factor)), IntervalType, nil
+}
+
+// dateTrunc 实现 date_trunc(field, source), 截断到指定精度
+func dateTrunc(field string, t time.Time) (time.Time, error) {
+	year, month, day := t.Date()
+	switch strings.ToLower(field) {
+	case "microseconds":
+		return t.Truncate(time.Microsecond), nil
+	case "milliseconds":
+		return t.Truncate(time.Millisecond), nil
+	case "second":
+		return t.Truncate(time.Second), nil
+	case "minute":
+		return t.Truncate(time.Minute), nil
+	case "hour":
+		return t.Truncate(time.Hour), nil
+	case "day":
+		return time.Date(year, month, day, 0, 0, 0, 0, time.UTC), nil
+	case "week":
+		// ISO 周从周一开始
+		offset := (int(t.Weekday()) + 6) % 7
+		return time.Date(year, month, day-offset, 0, 0, 0, 0, time.UTC), nil
+	case "month":
+		return time.Date(year, month, 1, 0, 0, 0, 0, time.UTC), nil
+	case "quarter":
+		return time.Date(year, month-(month-1)%3, 1, 0, 0, 0, 0, time.UTC), nil
+	case "year":
+		return time.Date(year, 1, 1, 0, 0, 0, 0, time.UTC), nil
+	case "decade":
+		return time.Date(year-year%10, 1, 1, 0, 0, 0, 0, time.UTC), nil
+	case "century":
+		return time.Date((year-1)/100*100+1, 1, 1, 0, 0, 0, 0, time.UTC), nil
+	case "millennium":
+		return time.Date((year-1)/1000*1000+1, 1, 1, 0, 0, 0, 0, time.UTC), nil
+	}
+
+	return time.Time{}, ErrInvalidValue
+}
+
+// extractFromTime 实现 EXTRACT(field FROM timestamp)
+func extractFromTime(field string, t time.Time) (float64, error) {
+	switch strings.ToLower(field) {
+	case "microseconds":
+		return float64(t.Second()*1000000 + t.Nanosecond()/1000), nil
+	case "milliseconds":
+		return float64(t.Second())*1000 + float64(t.Nanosecond())/1e6, nil
+	case "second":
+		return float64(t.Second()) + float64(t.Nanosecond())/1e9, nil
+	case "minute":
+		return float64(t.Minute()), nil
+	case "hour":
+		return float64(t.Hour()), nil
+	case "day":
+		return float64(t.Day()), nil
+	case "dow":
+		return float64(t.Weekday()), nil
+	case "isodow":
+		return float64((int(t.Weekday())+6)%7 + 1), nil
+	case "doy":
+		return float64(t.YearDay()), nil
+	case "week":
+		_, week := t.ISOWeek()
+		return float64(week), nil
+	case "isoyear":
+		year, _ := t.ISOWeek()
+		return float64(year), nil
+	case "month":
+		return float64(t.Month()), nil
+	case "quarter":
+		return float64((int(t.Month())-1)/3 + 1), nil
+	case "year":
+		return float64(t.Year()), nil
+	case "decade":
+		return math.Floor(float64(t.Year()) / 10), nil
+	case "century":
+		return math.Ceil(float64(t.Year()) / 100), nil
+	case "millennium":
+		return math.Ceil(float64(t.Year()) / 1000), nil
+	case "epoch":
+		return float64(timeToTimestamp(t)) / float64(microsPerSecond), nil
+	}
+
+	return 0, ErrInvalidValue
+}
+
+// extractFromInterval 实现 EXTRACT(field FROM interval)
+func extractFromInterval(field string, iv Interval) (float64, error) {
+	switch strings.ToLower(field) {
+	case "microseconds":
+		return float64(iv.Microseconds % microsPerMinute), nil
+	case "milliseconds":
+		return float64(iv.Microseconds%microsPerMinute) / 1000, nil
+	case "second":
+		return float64(iv.Microseconds%microsPerMinute) / float64(microsPerSecond), nil
+	case "minute":
+		return float64(iv.Microseconds % microsPerHour / microsPerMinute), nil
+	case "hour":
+		return float64(iv.Microseconds / microsPerHour), nil
+	case "day":
+		return float64(iv.Days), nil
+	case "month":
+		return float64(iv.Months % 12), nil
+	case "year":
+		return float64(iv.Months / 12), nil
+	case "epoch":
+		days := float64(iv.Months)*daysPerMonth + float64(iv.Days)
+		return days*86400 + float64(iv.Microseconds)/float64(microsPerSecond), nil
+	}
+
+	return 0, ErrInvalidValue
+}
@@ -0,0 +1,140 @@
+package jiesql
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDatetime(t *testing.T) {
+	mb := NewMemoryBackend()
+	mb.Clock = func() time.Time {
+		return time.Date(2026, 3, 15, 10, 30, 45, 0, time.FixedZone("UTC+8", 8*3600))
+	}
+
+	tests := []struct {
+		source   string
+		expected string
+	}{
+		{"SELECT DATE '2026-01-31'", "2026-01-31"},
+		{"SELECT TIMESTAMP '2026-01-01 10:00'", "2026-01-01 10:00:00"},
+		{"SELECT TIMESTAMP '2026-01-01 10:00:01.5'", "2026-01-01 10:00:01.5"},
+		{"SELECT INTERVAL '1 day 2 hours'", "1 day 02:00:00"},
+		{"SELECT INTERVAL '1 year 2 mons'", "1 year 2 mons"},
+		{"SELECT DATE '2026-01-31' + 1", "2026-02-01"},
+		{"SELECT 1 + DATE '2026-01-31'", "2026-02-01"},
+		{"SELECT DATE '2026-03-01' - DATE '2026-02-01'", "28"},
+		// 月末加一个月落在下个月的最后一天
+		{"SELECT DATE '2026-01-31' + INTERVAL '1 month'", "2026-02-28 00:00:00"},
+		{"SELECT TIMESTAMP '2026-01-01 10:00' - INTERVAL '1 hour'", "2026-01-01 09:00:00"},
+		{"SELECT INTERVAL '1 hour' + TIMESTAMP '2026-01-01 10:00'", "2026-01-01 11:00:00"},
+		{"SELECT TIMESTAMP '2026-01-02 12:00' - TIMESTAMP '2026-01-01 10:00'", "1 day 02:00:00"},
+		{"SELECT INTERVAL '1 day' - INTERVAL '1 hour'", "1 day -01:00:00"},
+		{"SELECT INTERVAL '1 hour' * 3", "03:00:00"},
+		{"SELECT 2 * INTERVAL '1 day'", "2 days"},
+		{"SELECT INTERVAL '1 day' / 2", "12:00:00"},
+		{"SELECT INTERVAL '1 day' * 1.5::float", "1 day 12:00:00"},
+		// 带小数点的字面量是 numeric
+		{"SELECT INTERVAL '1 month' * 0.5", "15 days"},
+		{"SELECT 1.5 * INTERVAL '1 day'", "1 day 12:00:00"},
+		{"SELECT INTERVAL '1 day' / 0.5", "2 days"},
+		{"SELECT INTERVAL '1 day' * null::numeric", "null"},
+		{"SELECT -INTERVAL '1 day'", "-1 days"},
+		{"SELECT DATE '2026-01-01' < DATE '2026-01-02'", "true"},
+		{"SELECT TIMESTAMP '2026-01-01' = DATE '2026-01-01'", "true"},
+		{"SELECT INTERVAL '1 day' = INTERVAL '24 hours'", "true"},
+		{"SELECT INTERVAL '1 month' > INTERVAL '29 days'", "true"},
+		{"SELECT DATE '2026-01-01' + null", "null"},
+		{"SELECT INTERVAL '1 day' * null::float", "null"},
+		// NOW() 取时钟所在时区的墙上时间
+		{"SELECT NOW()", "2026-03-15 10:30:45"},
+		{"SELECT DATE_TRUNC('month', NOW())", "2026-03-01 00:00:00"},
+		{"SELECT DATE_TRUNC('hour', TIMESTAMP '2026-01-01 10:59:59')", "2026-01-01 10:00:00"},
+		{"SELECT DATE_TRUNC('week', DATE '2026-03-15')", "2026-03-09 00:00:00"},
+		{"SELECT DATE_TRUNC('day', null::timestamp)", "null"},
+		{"SELECT EXTRACT(YEAR FROM NOW())", "2026"},
+		{"SELECT EXTRACT(dow FROM DATE '2026-03-15')", "0"},
+		{"SELECT EXTRACT(second FROM TIMESTAMP '2026-01-01 10:00:01.5')", "1.5"},
+		{"SELECT EXTRACT(epoch FROM INTERVAL '1 day')", "86400"},
+		{"SELECT EXTRACT(hour FROM INTERVAL '1 day 3 hours')", "3"},
+		{"SELECT EXTRACT(year FROM null::date)", "null"},
+	}
+
+	for _, test := range tests {
+		expectValue(t, mb, test.source, test.expected)
+	}
+
+	mustRun(t, mb, `CREATE TABLE audit (id INT, at TIMESTAMP, day DATE);
+		INSERT INTO audit VALUES (1, '2026-01-01 10:00', '2026-01-01');
+		INSERT INTO audit VALUES (2, NOW(), NOW());
+		INSERT INTO audit VALUES (3, null, null);`)
+	expectRows(t, mb, "SELECT id, at, day FROM audit", [][]string{
+		{"1", "2026-01-01 10:00:00", "2026-01-01"},
+		{"2", "2026-03-15 10:30:45", "2026-03-15"},
+		{"3", "null", "null"},
+	})
+	expectRows(t, mb, "SELECT id FROM audit WHERE at > NOW() - INTERVAL '1 day'", [][]string{{"2"}})
+
+	expectError(t, mb, "SELECT DATE '2026-02-30'", ErrInvalidValue)
+	expectError(t, mb, "SELECT TIMESTAMP 'tomorrow-ish'", ErrInvalidValue)
+	expectError(t, mb, "SELECT INTERVAL '1 fortnight'", ErrInvalidValue)
+	expectError(t, mb, "SELECT DATE '2026-01-01' + DATE '2026-01-01'", ErrInvalidOperands)
+	expectError(t, mb, "SELECT INTERVAL '1 day' * INTERVAL '1 day'", ErrInvalidOperands)
+	expectError(t, mb, "SELECT INTERVAL '1 day' / 0", ErrDivisionByZero)
+	expectError(t, mb, "SELECT INTERVAL '1 day' / 0.0", ErrDivisionByZero)
+	expectError(t, mb, "SELECT DATE_TRUNC('fortnight', NOW())", ErrInvalidValue)
+	expectError(t, mb, "SELECT EXTRACT(fortnight FROM NOW())", ErrInvalidValue)
+	expectError(t, mb, "SELECT DATE_TRUNC('day', 1)", ErrFunctionDoesNotExist)
+	expectError(t, mb, "SELECT NOW(1)", ErrFunctionDoesNotExist)
+	expectError(t, mb, "INSERT INTO audit VALUES (4, 1, null)", ErrInvalidDatatype)
+}
+
+func TestTime(t *testing.T) {
+	mb := NewMemoryBackend()
+
+	tests := []struct {
+		source   string
+		expected string
+	}{
+		{"SELECT TIME '10:30'", "10:30:00"},
+		{"SELECT TIME '07:05:09.25'", "07:05:09.25"},
+		{"SELECT TIME '24:00'", "24:00:00"},
+		{"SELECT '23:59:59.999999'::time", "23:59:59.999999"},
+		{"SELECT TIMESTAMP '2026-01-01 10:00:01.5'::time", "10:00:01.5"},
+		{"SELECT TIMESTAMP '1969-12-31 23:00'::time", "23:00:00"},
+		{"SELECT TIME '10:30'::text", "10:30:00"},
+		{"SELECT TIME '10:30' < TIME '9:00'", "false"},
+		{"SELECT TIME '10:30' = '10:30:00'", "true"},
+		{"SELECT TIME '10:30' + INTERVAL '1 hour'", "11:30:00"},
+		// 只看 interval 的时间部分, 超过一天时绕回
+		{"SELECT TIME '23:30' + INTERVAL '1 day 1 hour'", "00:30:00"},
+		{"SELECT TIME '00:30' - INTERVAL '1 hour'", "23:30:00"},
+		{"SELECT TIME '12:00' - TIME '10:30'", "01:30:00"},
+		{"SELECT EXTRACT(minute FROM TIME '10:30')", "30"},
+		{"SELECT null::time", "null"},
+	}
+
+	for _, test := range tests {
+		expectValue(t, mb, test.source, test.expected)
+	}
+
+	mustRun(t, mb, `CREATE TABLE shifts (id INT, starts TIME);
+		INSERT INTO shifts VALUES (1, '09:00');
+		INSERT INTO shifts VALUES (2, TIMESTAMP '2026-01-01 06:15');
+		INSERT INTO shifts VALUES (3, '22:00');
+		INSERT INTO shifts VALUES (4, null);`)
+	expectRows(t, mb, "SELECT id, starts FROM shifts ORDER BY starts", [][]string{
+		{"2", "06:15:00"},
+		{"1", "09:00:00"},
+		{"3", "22:00:00"},
+		{"4", "null"},
+	})
+	expectRows(t, mb, "SELECT id FROM shifts WHERE starts >= '09:00' AND starts < TIME '22:00'", [][]string{{"1"}})
+
+	expectError(t, mb, "SELECT TIME '25:00'", ErrInvalidValue)
+	expectError(t, mb, "SELECT TIME '10:60'", ErrInvalidValue)
+	expectError(t, mb, "SELECT TIME '-01:00'", ErrInvalidValue)
+	expectError(t, mb, "SELECT TIME 'noon'", ErrInvalidValue)
+	expectError(t, mb, "SELECT TIME '10:00' + TIME '01:00'", ErrInvalidOperands)
+	expectError(t, mb, "SELECT TIME '10:00'::date", ErrInvalidCast)
+	expectError(t, mb, "INSERT INTO shifts VALUES (5, DATE '2026-01-01')", ErrInvalidDatatype)
+}
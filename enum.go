@@ -0,0 +1,81 @@
+package jiesql
+
+import (
+	"encoding/binary"
+)
+
+// 枚举类型在 CREATE TYPE 时分配一个新的 ColumnType, 类型号和标签保存在创建它的 MemoryBackend 里,
+// 所以转换和输出枚举值的函数都要传入 enumTypes. 类型号从 firstEnumType 开始, 不能和数组标志位重叠.
+// 值在 MemoryCell 里存成 4 字节大端的标签序号, 按字节比较就是声明顺序.
+const firstEnumType ColumnType = 1 << 8
+
+type enumType struct {
+	name   string
+	labels []string
+}
+
+// enumTypes 按 ColumnType 查找一个 MemoryBackend 的枚举类型, 为 nil 时表示没有枚举类型
+type enumTypes map[ColumnType]*enumType
+
+func isEnumType(typ ColumnType) bool {
+	return typ >= firstEnumType && typ < arrayTypeFlag
+}
+
+// parseEnum 把标签转换成序号, 标签区分大小写, 不存在时报错
+func parseEnum(s string, typ ColumnType, enums enumTypes) (MemoryCell, error) {
+	e, ok := enums[typ]
+	if !ok {
+		return nil, ErrInvalidDatatype
+	}
+
+	for i, label := range e.labels {
+		if label == s {
+			cell := make(MemoryCell, 4)
+			binary.BigEndian.PutUint32(cell, uint32(i))
+			return cell, nil
+		}
+	}
+
+	return nil, ErrInvalidValue
+}
+
+func formatEnum(c MemoryCell, typ ColumnType, enums enumTypes) string {
+	e, ok := enums[typ]
+	if !ok {
+		panic(ErrInvalidDatatype)
+	}
+
+	return e.labels[binary.BigEndian.Uint32(c)]
+}
+
+// CreateType 执行 CREATE TYPE name AS ENUM (...)
+func (mb *MemoryBackend) CreateType(crt *CreateTypeStatement) error {
+	if _, ok := columnTypeFromName(crt.name.value); ok {
+		return ErrTypeAlreadyExists
+	}
+
+	if _, ok := mb.types[crt.name.value]; ok {
+		return ErrTypeAlreadyExists
+	}
+
+	seen := map[string]bool{}
+	var labels []string
+	for _, label := range crt.labels {
+		if seen[label.value] {
+			return ErrInvalidValue
+		}
+
+		seen[label.value] = true
+		labels = append(labels, label.value)
+	}
+
+	if mb.nextEnumType >= arrayTypeFlag {
+		return ErrOutOfRange
+	}
+
+	typ := mb.nextEnumType
+	mb.nextEnumType++
+	mb.enums[typ] = &enumType{name: crt.name.value, labels: labels}
+	mb.types[crt.name.value] = typ
+	return nil
+}
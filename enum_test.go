@@ -0,0 +1,82 @@
+package jiesql
+
+import (
+	"testing"
+)
+
+func TestEnumTypesPerBackend(t *testing.T) {
+	a := NewMemoryBackend()
+	b := NewMemoryBackend()
+
+	// 两个 MemoryBackend 的类型号各自分配, 同一个类型号对应各自的标签
+	mustRun(t, a, "CREATE TYPE status AS ENUM ('new', 'done'); CREATE TABLE t (s status); INSERT INTO t VALUES ('done')")
+	mustRun(t, b, "CREATE TYPE mood AS ENUM ('sad', 'happy'); CREATE TABLE t (m mood); INSERT INTO t VALUES ('happy')")
+	if a.types["status"] != firstEnumType || b.types["mood"] != firstEnumType {
+		t.Errorf("expected both types to be %d, got %d and %d", firstEnumType, a.types["status"], b.types["mood"])
+	}
+
+	expectValue(t, a, "SELECT s FROM t", "done")
+	expectValue(t, b, "SELECT m FROM t", "happy")
+	expectValue(t, b, "SELECT m::text FROM t", "happy")
+
+	// 另一个 MemoryBackend 的类型在这里不存在
+	expectError(t, b, "SELECT 'new'::status", ErrInvalidDatatype)
+	expectError(t, b, "INSERT INTO t VALUES ('done')", ErrInvalidDatatype)
+}
+
+func TestEnumTypesExhausted(t *testing.T) {
+	mb := NewMemoryBackend()
+	mb.nextEnumType = arrayTypeFlag - 1
+
+	mustRun(t, mb, "CREATE TYPE last AS ENUM ('a')")
+	expectError(t, mb, "CREATE TYPE overflow AS ENUM ('a')", ErrOutOfRange)
+	if !isEnumType(mb.types["last"]) || isArrayType(mb.types["last"]) {
+		t.Errorf("unexpected type %d", mb.types["last"])
+	}
+}
+
+func TestEnum(t *testing.T) {
+	mb := NewMemoryBackend()
+	mustRun(t, mb, `CREATE TYPE status AS ENUM ('new', 'doing', 'done');
+		CREATE TABLE tasks (id INT, s status);
+		INSERT INTO tasks VALUES (1, 'done');
+		INSERT INTO tasks VALUES (2, 'new');
+		INSERT INTO tasks VALUES (3, 'doing');
+		INSERT INTO tasks VALUES (4, null);`)
+
+	expectRows(t, mb, "SELECT id, s FROM tasks", [][]string{{"1", "done"}, {"2", "new"}, {"3", "doing"}, {"4", "null"}})
+	// 按声明顺序而不是文本顺序比较
+	expectRows(t, mb, "SELECT id FROM tasks WHERE s > 'new'", [][]string{{"1"}, {"3"}})
+	expectRows(t, mb, "SELECT id FROM tasks ORDER BY s", [][]string{{"2"}, {"3"}, {"1"}, {"4"}})
+	expectRows(t, mb, "SELECT id FROM tasks WHERE s IN ('new', 'done')", [][]string{{"1"}, {"2"}})
+	expectColumns(t, mb, "SELECT s FROM tasks WHERE id = 1", []column{{Name: "s", Type: mb.types["status"]}})
+
+	tests := []struct {
+		source   string
+		expected string
+	}{
+		{"SELECT 'doing'::status", "doing"},
+		{"SELECT 'done'::status < 'new'::status", "false"},
+		{"SELECT 'done'::status::text || '!'", "done!"},
+		{"SELECT ARRAY['new', 'done']::status[]", "{new,done}"},
+		{"SELECT '{done,new}'::status[]", "{done,new}"},
+		{"SELECT null::status", "null"},
+	}
+
+	for _, test := range tests {
+		expectValue(t, mb, test.source, test.expected)
+	}
+
+	mustRun(t, mb, "UPDATE tasks SET s = 'done' WHERE id = 2")
+	expectValue(t, mb, "SELECT s FROM tasks WHERE id = 2", "done")
+
+	// 标签区分大小写
+	expectError(t, mb, "INSERT INTO tasks VALUES (5, 'Done')", ErrInvalidDatatype)
+	expectError(t, mb, "SELECT 'later'::status", ErrInvalidValue)
+	expectError(t, mb, "INSERT INTO tasks VALUES (5, 'new'::text)", ErrInvalidDatatype)
+	expectError(t, mb, "INSERT INTO tasks VALUES (5, 1)", ErrInvalidDatatype)
+	expectError(t, mb, "CREATE TYPE status AS ENUM ('a')", ErrTypeAlreadyExists)
+	expectError(t, mb, "CREATE TYPE uuid AS ENUM ('a')", ErrTypeAlreadyExists)
+	expectError(t, mb, "CREATE TYPE dup AS ENUM ('a', 'a')", ErrInvalidValue)
+	expectError(t, mb, "CREATE TABLE bad (s nosuchtype)", ErrInvalidDatatype)
+}
@@ -1,6 +1,9 @@
 package jiesql
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+)
 
 var (
 	ErrTableDoesNotExist         = errors.New("Table does not exist")
@@ -15,4 +18,53 @@ var (
 	ErrInvalidCell               = errors.New("Cell is invalid")
 	ErrInvalidOperands           = errors.New("Operands are invalid")
 	ErrPrimaryKeyAlreadyExists   = errors.New("Primary key already exists")
+	ErrInvalidCondition          = errors.New("Condition must be a boolean expression")
+	ErrInvalidEscape             = errors.New("Invalid escape string")
+	ErrInvalidCast               = errors.New("Cannot cast between these types")
+	ErrInvalidValue              = errors.New("Invalid input value for type")
+	ErrDivisionByZero            = errors.New("Division by zero")
+	ErrOutOfRange                = errors.New("Value out of range")
+	ErrFunctionDoesNotExist      = errors.New("Function does not exist")
+	ErrValueTooLong              = errors.New("Value too long for type")
+	ErrSetReturningFunction      = errors.New("Set-returning functions are not allowed here")
+	ErrTypeAlreadyExists         = errors.New("Type already exists")
+	ErrDefaultNotAllowed         = errors.New("DEFAULT is not allowed in this context")
+	ErrGeneratedColumn           = errors.New("Cannot assign a value to a generated column")
+	ErrDuplicateColumn           = errors.New("Column specified more than once")
+	ErrConstraintAlreadyExists   = errors.New("Constraint already exists")
+	ErrViolatesCheckConstraint   = errors.New("New row violates check constraint")
+	ErrViolatesForeignKey        = errors.New("Insert, update or delete violates foreign key constraint")
+	ErrSequenceDoesNotExist      = errors.New("Sequence does not exist")
+	ErrSequenceAlreadyExists     = errors.New("Sequence already exists")
+	ErrCurrvalNotDefined         = errors.New("Currval of sequence is not yet defined in this session")
+	ErrConstraintDoesNotExist    = errors.New("Constraint does not exist")
+	ErrDependentObjects          = errors.New("Other objects depend on it")
+	ErrViewDoesNotExist          = errors.New("View does not exist")
+	ErrRecursiveView             = errors.New("Infinite recursion detected in view")
+	ErrCannotChangeView          = errors.New("Cannot change the columns of an existing view")
+	ErrMaterializedView          = errors.New("Not allowed on a materialized view")
+	ErrDuplicateTableName        = errors.New("Table name specified more than once")
+	ErrAmbiguousColumn           = errors.New("Column reference is ambiguous")
+	ErrWindowFunction            = errors.New("Window functions are not allowed here")
+	ErrOverRequired              = errors.New("Function requires an OVER clause")
+	ErrInvalidFrame              = errors.New("Window frame is invalid")
+	ErrInvalidParameter          = errors.New("Parameter is invalid")
+	ErrMultipleStatements        = errors.New("Cannot prepare multiple statements")
+	ErrNotQuery                  = errors.New("Statement does not return rows")
+	ErrRecursionLimit            = errors.New("Recursive query exceeded the iteration limit")
+	ErrInvalidLimit              = errors.New("LIMIT or OFFSET is invalid")
 )
+
+// ConstraintError 带上被违反的约束名, 可以用 errors.Is 判断是哪一类约束
+type ConstraintError struct {
+	Constraint string
+	Err        error
+}
+
+func (e *ConstraintError) Error() string {
+	return fmt.Sprintf("%s %q", e.Err, e.Constraint)
+}
+
+func (e *ConstraintError) Unwrap() error {
+	return e.Err
+}
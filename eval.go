@@ -0,0 +1,816 @@
+package jiesql
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// evaluateCell 在表 t 的一行上计算表达式, 返回结果、列名以及结果类型.
+// row 为 nil 时表示一行全是 NULL 的数据, 只用来推导列名和类型.
+func (mb *MemoryBackend) evaluateCell(t *table, row []MemoryCell, exp expression) (MemoryCell, string, ColumnType, error) {
+	switch exp.kind {
+	case literalKind:
+		return mb.evaluateLiteralCell(t, row, exp)
+	case binaryKind:
+		return mb.evaluateBinaryCell(t, row, exp)
+	case likeKind:
+		return mb.evaluateLikeCell(t, row, exp)
+	case unaryKind:
+		return mb.evaluateUnaryCell(t, row, exp)
+	case inKind:
+		return mb.evaluateInCell(t, row, exp)
+	case betweenKind:
+		return mb.evaluateBetweenCell(t, row, exp)
+	case castKind:
+		return mb.evaluateCastCell(t, row, exp)
+	case functionKind:
+		return mb.evaluateFunctionCell(t, row, exp)
+	case arrayKind:
+		return mb.evaluateArrayCell(t, row, exp)
+	case subscriptKind:
+		return mb.evaluateSubscriptCell(t, row, exp)
+	case defaultKind:
+		return nil, "", 0, ErrDefaultNotAllowed
+	}
+
+	return nil, "", 0, ErrInvalidCell
+}
+
+// evaluateConstant 计算不能引用列的表达式, 例如 VALUES 里的值和列的默认值
+func (mb *MemoryBackend) evaluateConstant(exp expression) (MemoryCell, ColumnType, error) {
+	cell, _, typ, err := mb.evaluateCell(&table{}, []MemoryCell{}, exp)
+	return cell, typ, err
+}
+
+// evaluateCondition 计算 WHERE 这类条件, 只有结果为 true 时才返回 true, NULL 视为 false
+func (mb *MemoryBackend) evaluateCondition(t *table, row []MemoryCell, exp expression) (bool, error) {
+	cell, _, typ, err := mb.evaluateCell(t, row, exp)
+	if err != nil {
+		return false, err
+	}
+
+	if typ != BoolType {
+		return false, ErrInvalidCondition
+	}
+
+	return cell.AsBool() == true, nil
+}
+
+func (mb *MemoryBackend) evaluateLiteralCell(t *table, row []MemoryCell, exp expression) (MemoryCell, string, ColumnType, error) {
+	lit := exp.literal
+
+	switch lit.kind {
+	case identifierKind:
+		i, err := t.resolveColumn(lit.value)
+		if err != nil {
+			return nil, "", 0, err
+		}
+
+		// 结果的列名不带表名
+		name := t.columns[i][strings.LastIndex(t.columns[i], ".")+1:]
+		if row == nil {
+			return nil, name, t.columnTypes[i], nil
+		}
+
+		return row[i], name, t.columnTypes[i], nil
+	case numericKind:
+		cell, typ, err := numericToCell(lit.value)
+		if err != nil {
+			return nil, "", 0, err
+		}
+
+		return cell, "?column?", typ, nil
+	case stringKind:
+		return mb.tokenToCell(lit), "?column?", TextType, nil
+	case boolKind:
+		return mb.tokenToCell(lit), "bool", BoolType, nil
+	case byteaKind:
+		cell, err := parseHexBytes(lit.value)
+		if err != nil {
+			return nil, "", 0, err
+		}
+
+		return cell, "?column?", ByteaType, nil
+	case nullKind:
+		return nil, "?column?", TextType, nil
+	case parameterKind:
+		// 参数要先通过 Stmt 绑定成值
+		return nil, "", 0, fmt.Errorf("%w: there is no parameter $%s", ErrInvalidParameter, lit.value)
+	}
+
+	return nil, "", 0, ErrInvalidCell
+}
+
+func (mb *MemoryBackend) evaluateBinaryCell(t *table, row []MemoryCell, exp expression) (MemoryCell, string, ColumnType, error) {
+	bexp := exp.binary
+
+	if op := symbol(bexp.op.value); op == jsonArrowSymbol || op == jsonArrowTextSymbol {
+		return mb.evaluateJsonArrowCell(t, row, exp)
+	}
+
+	if bexp.b.kind == quantifiedKind {
+		return mb.evaluateQuantifiedCell(t, row, exp)
+	}
+
+	l, lt, r, rt, err := mb.evaluateOperands(t, row, symbol(bexp.op.value), bexp.a, bexp.b)
+	if err != nil {
+		return nil, "", 0, err
+	}
+
+	switch bexp.op.kind {
+	case keywordKind:
+		l, lt, err = coerceUntyped(bexp.a, l, lt, BoolType, mb.enums)
+		if err != nil {
+			return nil, "", 0, err
+		}
+
+		r, rt, err = coerceUntyped(bexp.b, r, rt, BoolType, mb.enums)
+		if err != nil {
+			return nil, "", 0, err
+		}
+
+		if lt != BoolType || rt != BoolType {
+			return nil, "", 0, ErrInvalidOperands
+		}
+
+		switch keyword(bexp.op.value) {
+		case andKeyword:
+			return logicalAnd(l, r), "?column?", BoolType, nil
+		case orKeyword:
+			return logicalOr(l, r), "?column?", BoolType, nil
+		}
+	case symbolKind:
+		switch symbol(bexp.op.value) {
+		case eqSymbol, neqSymbol, ltSymbol, lteSymbol, gtSymbol, gteSymbol:
+			cell, err := compareWith(symbol(bexp.op.value), l, lt, r, rt)
+			if err != nil {
+				return nil, "", 0, err
+			}
+
+			return cell, "?column?", BoolType, nil
+		case concatSymbol:
+			if lt != rt || (lt != TextType && lt != ByteaType) {
+				return nil, "", 0, ErrInvalidOperands
+			}
+
+			if l == nil || r == nil {
+				return nil, "?column?", lt, nil
+			}
+
+			return MemoryCell(l.AsText() + r.AsText()), "?column?", lt, nil
+		case plusSymbol, minusSymbol, asteriskSymbol, slashSymbol:
+			arithmetic := evaluateArithmetic
+			if isDatetimeType(lt) || isDatetimeType(rt) {
+				arithmetic = evaluateDatetimeArithmetic
+			}
+
+			cell, typ, err := arithmetic(symbol(bexp.op.value), l, lt, r, rt)
+			if err != nil {
+				return nil, "", 0, err
+			}
+
+			return cell, "?column?", typ, nil
+		}
+	}
+
+	return nil, "", 0, ErrInvalidOperands
+}
+
+// evaluateOperands 计算二元运算 op 的两个操作数, 类型不同时把未定类型的字面量转换成另一边的类型,
+// 例如 id = '1' 里的 '1' 会被当作 int, 而 ts + '1 day' 里的 '1 day' 会被当作 interval
+func (mb *MemoryBackend) evaluateOperands(t *table, row []MemoryCell, op symbol, a, b expression) (MemoryCell, ColumnType, MemoryCell, ColumnType, error) {
+	l, _, lt, err := mb.evaluateCell(t, row, a)
+	if err != nil {
+		return nil, 0, nil, 0, err
+	}
+
+	r, _, rt, err := mb.evaluateCell(t, row, b)
+	if err != nil {
+		return nil, 0, nil, 0, err
+	}
+
+	untypedTarget := func(other ColumnType) ColumnType {
+		if (op == plusSymbol || op == minusSymbol) && (other == DateType || other == TimestampType || other == TimeType) {
+			return IntervalType
+		}
+
+		return other
+	}
+
+	if lt != rt {
+		if isUntypedLiteral(a) && !isUntypedLiteral(b) {
+			l, lt, err = coerceUntyped(a, l, lt, untypedTarget(rt), mb.enums)
+		} else if isUntypedLiteral(b) {
+			r, rt, err = coerceUntyped(b, r, rt, untypedTarget(lt), mb.enums)
+		}
+		if err != nil {
+			return nil, 0, nil, 0, err
+		}
+	}
+
+	// date 和 timestamp 混合时把 date 提升成当天零点
+	if (lt == DateType && rt == TimestampType) || (lt == TimestampType && rt == DateType) {
+		l, err = castCell(l, lt, TimestampType, assignmentCoercion, nil)
+		if err != nil {
+			return nil, 0, nil, 0, err
+		}
+
+		r, err = castCell(r, rt, TimestampType, assignmentCoercion, nil)
+		if err != nil {
+			return nil, 0, nil, 0, err
+		}
+
+		lt, rt = TimestampType, TimestampType
+	}
+
+	// int 和 float 混合运算时把 int 提升成 float
+	if lt != rt && isNumericType(lt) && isNumericType(rt) {
+		l, lt, r, rt, err = promoteNumeric(l, lt, r, rt)
+		if err != nil {
+			return nil, 0, nil, 0, err
+		}
+	}
+
+	return l, lt, r, rt, nil
+}
+
+func isNumericType(typ ColumnType) bool {
+	return isIntegerType(typ) || typ == NumericType || typ == FloatType
+}
+
+// numericRank 决定混合运算时提升到哪个类型: smallint < int < bigint < numeric < float
+var numericRank = map[ColumnType]int{
+	SmallIntType: 0,
+	IntType:      1,
+	BigIntType:   2,
+	NumericType:  3,
+	FloatType:    4,
+}
+
+// promoteNumeric 把两个数值转换成它们中范围更大的那个类型
+func promoteNumeric(l MemoryCell, lt ColumnType, r MemoryCell, rt ColumnType) (MemoryCell, ColumnType, MemoryCell, ColumnType, error) {
+	to := lt
+	if numericRank[rt] > numericRank[lt] {
+		to = rt
+	}
+
+	l, err := castCell(l, lt, to, assignmentCoercion, nil)
+	if err != nil {
+		return nil, 0, nil, 0, err
+	}
+
+	r, err = castCell(r, rt, to, assignmentCoercion, nil)
+	if err != nil {
+		return nil, 0, nil, 0, err
+	}
+
+	return l, to, r, to, nil
+}
+
+// evaluateArithmetic 计算 + - * /, 两边必须是同一种数值类型
+func evaluateArithmetic(op symbol, l MemoryCell, lt ColumnType, r MemoryCell, rt ColumnType) (MemoryCell, ColumnType, error) {
+	if lt != rt || !isNumericType(lt) {
+		return nil, 0, ErrInvalidOperands
+	}
+
+	if l == nil || r == nil {
+		return nil, lt, nil
+	}
+
+	if lt == FloatType {
+		a, b := l.AsFloat(), r.AsFloat()
+		var f float64
+		switch op {
+		case plusSymbol:
+			f = a + b
+		case minusSymbol:
+			f = a - b
+		case asteriskSymbol:
+			f = a * b
+		case slashSymbol:
+			if b == 0 {
+				return nil, 0, ErrDivisionByZero
+			}
+			f = a / b
+		}
+
+		return floatCell(f), FloatType, nil
+	}
+
+	if lt == NumericType {
+		d, err := decimalArithmetic(op, l.asDecimal(), r.asDecimal())
+		if err != nil {
+			return nil, 0, err
+		}
+
+		return decimalCell(d), NumericType, nil
+	}
+
+	i, err := integerArithmetic(op, l.AsInt64(), r.AsInt64())
+	if err != nil {
+		return nil, 0, err
+	}
+
+	cell, err := checkedIntegerCell(i, lt)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return cell, lt, nil
+}
+
+// integerArithmetic 用 int64 计算, 结果溢出 int64 时报错, 更窄类型的范围由调用方检查
+func integerArithmetic(op symbol, a, b int64) (int64, error) {
+	switch op {
+	case plusSymbol:
+		if (b > 0 && a > math.MaxInt64-b) || (b < 0 && a < math.MinInt64-b) {
+			return 0, ErrOutOfRange
+		}
+		return a + b, nil
+	case minusSymbol:
+		if (b < 0 && a > math.MaxInt64+b) || (b > 0 && a < math.MinInt64+b) {
+			return 0, ErrOutOfRange
+		}
+		return a - b, nil
+	case asteriskSymbol:
+		if a == 0 || b == 0 {
+			return 0, nil
+		}
+		i := a * b
+		if i/b != a || (a == -1 && b == math.MinInt64) || (b == -1 && a == math.MinInt64) {
+			return 0, ErrOutOfRange
+		}
+		return i, nil
+	case slashSymbol:
+		if b == 0 {
+			return 0, ErrDivisionByZero
+		}
+		if a == math.MinInt64 && b == -1 {
+			return 0, ErrOutOfRange
+		}
+		// 整数除法向零取整
+		return a / b, nil
+	}
+
+	return 0, ErrInvalidOperands
+}
+
+// coerceUntyped 如果 exp 是未定类型的字面量, 就把它的值转换成 target 类型
+func coerceUntyped(exp expression, c MemoryCell, typ, target ColumnType, enums enumTypes) (MemoryCell, ColumnType, error) {
+	if typ == target || !isUntypedLiteral(exp) {
+		return c, typ, nil
+	}
+
+	cell, err := castCell(c, typ, target, explicitCoercion, enums)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return cell, target, nil
+}
+
+// 三值逻辑: false 优先于 NULL
+func logicalAnd(l, r MemoryCell) MemoryCell {
+	lb, rb := l.AsBool(), r.AsBool()
+	if lb == false || rb == false {
+		return boolCell(false)
+	}
+
+	if lb == nil || rb == nil {
+		return nil
+	}
+
+	return boolCell(true)
+}
+
+// 三值逻辑: true 优先于 NULL
+func logicalOr(l, r MemoryCell) MemoryCell {
+	lb, rb := l.AsBool(), r.AsBool()
+	if lb == true || rb == true {
+		return boolCell(true)
+	}
+
+	if lb == nil || rb == nil {
+		return nil
+	}
+
+	return boolCell(false)
+}
+
+func logicalNot(c MemoryCell) MemoryCell {
+	if c == nil {
+		return nil
+	}
+
+	return boolCell(c.AsBool() == false)
+}
+
+// compareWith 用比较运算符 op 比较两个值, 任意一边为 NULL 时结果为 NULL
+func compareWith(op symbol, l MemoryCell, lt ColumnType, r MemoryCell, rt ColumnType) (MemoryCell, error) {
+	if l == nil || r == nil {
+		return nil, nil
+	}
+
+	if lt != rt {
+		return nil, ErrInvalidOperands
+	}
+
+	c := compareCells(l, r, lt)
+	var b bool
+	switch op {
+	case eqSymbol:
+		b = c == 0
+	case neqSymbol:
+		b = c != 0
+	case ltSymbol:
+		b = c < 0
+	case lteSymbol:
+		b = c <= 0
+	case gtSymbol:
+		b = c > 0
+	case gteSymbol:
+		b = c >= 0
+	default:
+		return nil, ErrInvalidOperands
+	}
+
+	return boolCell(b), nil
+}
+
+// compareCells 比较同类型的两个非 NULL 值, 返回 -1, 0, 1
+func compareCells(l, r MemoryCell, typ ColumnType) int {
+	switch typ {
+	case SmallIntType, IntType, BigIntType, DateType, TimestampType, TimeType:
+		li, ri := l.AsInt64(), r.AsInt64()
+		if li < ri {
+			return -1
+		}
+		if li > ri {
+			return 1
+		}
+		return 0
+	case FloatType:
+		// 和 PostgreSQL 一样认为 NaN 等于自己并且大于其他所有值
+		lf, rf := l.AsFloat(), r.AsFloat()
+		switch {
+		case math.IsNaN(lf) && math.IsNaN(rf):
+			return 0
+		case math.IsNaN(lf):
+			return 1
+		case math.IsNaN(rf):
+			return -1
+		case lf < rf:
+			return -1
+		case lf > rf:
+			return 1
+		}
+		return 0
+	case IntervalType:
+		return compareIntervals(l.AsInterval(), r.AsInterval())
+	case NumericType:
+		return l.asDecimal().cmp(r.asDecimal())
+	}
+
+	if isArrayType(typ) {
+		return compareArrays(l, r, typ)
+	}
+
+	return bytes.Compare(l, r)
+}
+
+func (mb *MemoryBackend) evaluateUnaryCell(t *table, row []MemoryCell, exp expression) (MemoryCell, string, ColumnType, error) {
+	uexp := exp.unary
+
+	operand, _, typ, err := mb.evaluateCell(t, row, uexp.operand)
+	if err != nil {
+		return nil, "", 0, err
+	}
+
+	if uexp.op.kind == symbolKind && symbol(uexp.op.value) == minusSymbol && typ == IntervalType {
+		if operand == nil {
+			return nil, "?column?", IntervalType, nil
+		}
+
+		return intervalCell(negateInterval(operand.AsInterval())), "?column?", IntervalType, nil
+	}
+
+	// -x 按 0 - x 计算
+	if uexp.op.kind == symbolKind && symbol(uexp.op.value) == minusSymbol {
+		zero, err := castCell(intCell(0), IntType, typ, assignmentCoercion, nil)
+		if err != nil {
+			return nil, "", 0, ErrInvalidOperands
+		}
+
+		cell, typ, err := evaluateArithmetic(minusSymbol, zero, typ, operand, typ)
+		if err != nil {
+			return nil, "", 0, err
+		}
+
+		return cell, "?column?", typ, nil
+	}
+
+	switch keyword(uexp.op.value) {
+	case notKeyword:
+		operand, typ, err = coerceUntyped(uexp.operand, operand, typ, BoolType, mb.enums)
+		if err != nil {
+			return nil, "", 0, err
+		}
+
+		if typ != BoolType {
+			return nil, "", 0, ErrInvalidOperands
+		}
+
+		return logicalNot(operand), "?column?", BoolType, nil
+	}
+
+	return nil, "", 0, ErrInvalidOperands
+}
+
+// x IN (a, b) 等价于 x = a OR x = b
+func (mb *MemoryBackend) evaluateInCell(t *table, row []MemoryCell, exp expression) (MemoryCell, string, ColumnType, error) {
+	in := exp.in
+
+	result := boolCell(false)
+	for _, item := range in.list {
+		value, vt, cell, typ, err := mb.evaluateOperands(t, row, eqSymbol, in.value, *item)
+		if err != nil {
+			return nil, "", 0, err
+		}
+
+		eq, err := compareWith(eqSymbol, value, vt, cell, typ)
+		if err != nil {
+			return nil, "", 0, err
+		}
+
+		result = logicalOr(result, eq)
+	}
+
+	if in.not {
+		result = logicalNot(result)
+	}
+
+	return result, "?column?", BoolType, nil
+}
+
+// x BETWEEN a AND b 等价于 x >= a AND x <= b
+func (mb *MemoryBackend) evaluateBetweenCell(t *table, row []MemoryCell, exp expression) (MemoryCell, string, ColumnType, error) {
+	between := exp.between
+
+	value, vt, low, lowType, err := mb.evaluateOperands(t, row, gteSymbol, between.value, between.low)
+	if err != nil {
+		return nil, "", 0, err
+	}
+
+	gte, err := compareWith(gteSymbol, value, vt, low, lowType)
+	if err != nil {
+		return nil, "", 0, err
+	}
+
+	value, vt, high, highType, err := mb.evaluateOperands(t, row, lteSymbol, between.value, between.high)
+	if err != nil {
+		return nil, "", 0, err
+	}
+
+	lte, err := compareWith(lteSymbol, value, vt, high, highType)
+	if err != nil {
+		return nil, "", 0, err
+	}
+
+	result := logicalAnd(gte, lte)
+	if between.not {
+		result = logicalNot(result)
+	}
+
+	return result, "?column?", BoolType, nil
+}
+
+func (mb *MemoryBackend) evaluateCastCell(t *table, row []MemoryCell, exp expression) (MemoryCell, string, ColumnType, error) {
+	cast := exp.cast
+
+	value, name, typ, err := mb.evaluateCell(t, row, cast.value)
+	if err != nil {
+		return nil, "", 0, err
+	}
+
+	to, mod, err := mb.resolveDataType(cast.datatype)
+	if err != nil {
+		return nil, "", 0, err
+	}
+
+	cell, err := castCell(value, typ, to, explicitCoercion, mb.enums)
+	if err != nil {
+		return nil, "", 0, err
+	}
+
+	cell, err = applyTypeModifier(cell, to, mod, explicitCoercion)
+	if err != nil {
+		return nil, "", 0, err
+	}
+
+	return cell, name, to, nil
+}
+
+func (mb *MemoryBackend) evaluateFunctionCell(t *table, row []MemoryCell, exp expression) (MemoryCell, string, ColumnType, error) {
+	fn := exp.function
+
+	if isSetReturning(exp) {
+		return nil, "", 0, ErrSetReturningFunction
+	}
+
+	// 窗口函数已经在 windowTable 里算好了
+	if fn.over != nil {
+		i, ok := t.windowColumns[fn]
+		if !ok {
+			return nil, "", 0, ErrWindowFunction
+		}
+
+		if row == nil {
+			return nil, fn.name.value, t.columnTypes[i], nil
+		}
+
+		return row[i], fn.name.value, t.columnTypes[i], nil
+	}
+
+	f, ok := builtinFunctions[fn.name.value]
+	if !ok && windowFunctions[fn.name.value] {
+		return nil, "", 0, fmt.Errorf("%w: %s", ErrOverRequired, fn.name.value)
+	}
+	if !ok || fn.star {
+		return nil, "", 0, ErrFunctionDoesNotExist
+	}
+
+	var args []MemoryCell
+	var types []ColumnType
+	for _, arg := range fn.args {
+		cell, _, typ, err := mb.evaluateCell(t, row, *arg)
+		if err != nil {
+			return nil, "", 0, err
+		}
+
+		// 推导类型时参数都当作 NULL, 避免 nextval 这类函数产生副作用
+		if row == nil {
+			cell = nil
+		}
+
+		args = append(args, cell)
+		types = append(types, typ)
+	}
+
+	cell, typ, err := f(mb, args, types)
+	if err != nil {
+		return nil, "", 0, err
+	}
+
+	return cell, fn.name.value, typ, nil
+}
+
+func (mb *MemoryBackend) evaluateLikeCell(t *table, row []MemoryCell, exp expression) (MemoryCell, string, ColumnType, error) {
+	like := exp.like
+
+	value, _, vt, err := mb.evaluateCell(t, row, like.value)
+	if err != nil {
+		return nil, "", 0, err
+	}
+
+	pattern, _, pt, err := mb.evaluateCell(t, row, like.pattern)
+	if err != nil {
+		return nil, "", 0, err
+	}
+
+	if vt != TextType || pt != TextType {
+		return nil, "", 0, ErrInvalidOperands
+	}
+
+	// 默认和 PostgreSQL 一样用反斜杠转义, ESCAPE '' 表示不转义
+	escape, hasEscape := '\\', true
+	if like.escape != nil {
+		e, _, et, err := mb.evaluateCell(t, row, *like.escape)
+		if err != nil {
+			return nil, "", 0, err
+		}
+
+		if et != TextType {
+			return nil, "", 0, ErrInvalidOperands
+		}
+
+		if e == nil {
+			return nil, "?column?", BoolType, nil
+		}
+
+		runes := []rune(e.AsText())
+		switch len(runes) {
+		case 0:
+			hasEscape = false
+		case 1:
+			escape = runes[0]
+		default:
+			return nil, "", 0, ErrInvalidEscape
+		}
+	}
+
+	if value == nil || pattern == nil {
+		return nil, "?column?", BoolType, nil
+	}
+
+	v, p := value.AsText(), pattern.AsText()
+	if like.caseInsensitive {
+		v, p = strings.ToLower(v), strings.ToLower(p)
+		escape = []rune(strings.ToLower(string(escape)))[0]
+	}
+
+	compiled, err := compileLikePattern([]rune(p), escape, hasEscape)
+	if err != nil {
+		return nil, "", 0, err
+	}
+
+	matched := matchLikePattern([]rune(v), compiled)
+	if like.not {
+		matched = !matched
+	}
+
+	return boolCell(matched), "?column?", BoolType, nil
+}
+
+type likePatternKind uint
+
+const (
+	likeLiteral likePatternKind = iota
+	// _
+	likeAnyChar
+	// %
+	likeAnyString
+)
+
+type likePatternElement struct {
+	kind likePatternKind
+	r    rune
+}
+
+// compileLikePattern 把 LIKE 的模式串拆成字面字符和通配符, 处理转义字符
+func compileLikePattern(pattern []rune, escape rune, hasEscape bool) ([]likePatternElement, error) {
+	var elements []likePatternElement
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+
+		if hasEscape && c == escape {
+			// 转义字符后面必须还有一个字符
+			if i+1 >= len(pattern) {
+				return nil, ErrInvalidEscape
+			}
+
+			i++
+			elements = append(elements, likePatternElement{kind: likeLiteral, r: pattern[i]})
+			continue
+		}
+
+		switch c {
+		case '%':
+			elements = append(elements, likePatternElement{kind: likeAnyString})
+		case '_':
+			elements = append(elements, likePatternElement{kind: likeAnyChar})
+		default:
+			elements = append(elements, likePatternElement{kind: likeLiteral, r: c})
+		}
+	}
+
+	return elements, nil
+}
+
+// matchLikePattern 贪心匹配, 遇到失败时回退到上一个 % 的位置重试
+func matchLikePattern(value []rune, pattern []likePatternElement) bool {
+	vi, pi := 0, 0
+	star, mark := -1, 0
+
+	for vi < len(value) {
+		if pi < len(pattern) {
+			p := pattern[pi]
+			if p.kind == likeAnyChar || (p.kind == likeLiteral && p.r == value[vi]) {
+				vi++
+				pi++
+				continue
+			}
+
+			if p.kind == likeAnyString {
+				star, mark = pi, vi
+				pi++
+				continue
+			}
+		}
+
+		if star >= 0 {
+			mark++
+			pi, vi = star+1, mark
+			continue
+		}
+
+		return false
+	}
+
+	for pi < len(pattern) && pattern[pi].kind == likeAnyString {
+		pi++
+	}
+
+	return pi == len(pattern)
+}
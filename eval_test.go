@@ -0,0 +1,213 @@
+package jiesql
+
+import (
+	"testing"
+)
+
+func newPeopleBackend(t *testing.T) *MemoryBackend {
+	t.Helper()
+
+	mb := NewMemoryBackend()
+	mustRun(t, mb, `CREATE TABLE people (id INT, name TEXT);
+		INSERT INTO people VALUES (1, 'Alice');
+		INSERT INTO people VALUES (2, 'bob');
+		INSERT INTO people VALUES (3, '50% off');
+		INSERT INTO people VALUES (4, null);`)
+	return mb
+}
+
+func TestConcat(t *testing.T) {
+	mb := newPeopleBackend(t)
+
+	expectValue(t, mb, "SELECT 'foo' || 'bar'", "foobar")
+	expectValue(t, mb, "SELECT 'a' || 'b' || 'c'", "abc")
+	expectValue(t, mb, "SELECT '' || ''", "")
+	expectRows(t, mb, "SELECT name || '!' FROM people WHERE id = 1", [][]string{{"Alice!"}})
+
+	// 任何一边是 NULL 结果都是 NULL
+	expectValue(t, mb, "SELECT 'a' || null", "null")
+	expectRows(t, mb, "SELECT name || 'x' FROM people WHERE id = 4", [][]string{{"null"}})
+
+	expectError(t, mb, "SELECT name || id FROM people", ErrInvalidOperands)
+	// 无类型的字符串按另一边的类型解析
+	expectError(t, mb, "SELECT 'a' || 1", ErrInvalidValue)
+}
+
+func TestLike(t *testing.T) {
+	mb := newPeopleBackend(t)
+
+	tests := []struct {
+		source   string
+		expected string
+	}{
+		{"SELECT 'abc' LIKE 'abc'", "true"},
+		{"SELECT 'abc' LIKE 'a%'", "true"},
+		{"SELECT 'abc' LIKE '%c'", "true"},
+		{"SELECT 'abc' LIKE '%b%'", "true"},
+		{"SELECT 'abc' LIKE 'a_c'", "true"},
+		{"SELECT 'abc' LIKE '_'", "false"},
+		{"SELECT 'abc' LIKE 'ab'", "false"},
+		{"SELECT '' LIKE '%'", "true"},
+		{"SELECT '' LIKE '_'", "false"},
+		{"SELECT 'aXbXc' LIKE '%X%X%'", "true"},
+		{"SELECT 'abc' LIKE 'ABC'", "false"},
+		{"SELECT 'abc' ILIKE 'A%C'", "true"},
+		{"SELECT 'abc' NOT LIKE 'a%'", "false"},
+		{"SELECT 'abc' NOT ILIKE 'X%'", "true"},
+		// 默认用反斜杠转义
+		{"SELECT '50% off' LIKE '50\\%%'", "true"},
+		{"SELECT '50 off' LIKE '50\\%%'", "false"},
+		{"SELECT 'a_b' LIKE 'a#_b' ESCAPE '#'", "true"},
+		{"SELECT 'axb' LIKE 'a#_b' ESCAPE '#'", "false"},
+		// ESCAPE '' 关闭转义
+		{"SELECT 'a\\b' LIKE 'a\\b' ESCAPE ''", "true"},
+		{"SELECT null LIKE 'a%'", "null"},
+		{"SELECT 'a' LIKE null", "null"},
+		{"SELECT 'a' LIKE 'a' ESCAPE null", "null"},
+	}
+
+	for _, test := range tests {
+		expectValue(t, mb, test.source, test.expected)
+	}
+
+	expectRows(t, mb, "SELECT id FROM people WHERE name LIKE '%o%'", [][]string{{"2"}, {"3"}})
+	expectRows(t, mb, "SELECT id FROM people WHERE name ILIKE 'a%'", [][]string{{"1"}})
+	// NULL 既不满足 LIKE 也不满足 NOT LIKE
+	expectRows(t, mb, "SELECT id FROM people WHERE name NOT LIKE 'A%'", [][]string{{"2"}, {"3"}})
+
+	expectError(t, mb, "SELECT 'a' LIKE 'a' ESCAPE 'ab'", ErrInvalidEscape)
+	expectError(t, mb, "SELECT 'a' LIKE 'a#' ESCAPE '#'", ErrInvalidEscape)
+	expectError(t, mb, "SELECT 1 LIKE '1'", ErrInvalidOperands)
+}
+
+func TestInBetweenNot(t *testing.T) {
+	mb := newPeopleBackend(t)
+
+	tests := []struct {
+		source   string
+		expected string
+	}{
+		{"SELECT 2 IN (1, 2, 3)", "true"},
+		{"SELECT 4 IN (1, 2, 3)", "false"},
+		{"SELECT 4 NOT IN (1, 2, 3)", "true"},
+		{"SELECT 'b' IN ('a', 'b')", "true"},
+		// 和 PostgreSQL 一样, 没找到并且列表里有 NULL 时结果是 NULL
+		{"SELECT 4 IN (1, null)", "null"},
+		{"SELECT 1 IN (1, null)", "true"},
+		{"SELECT 4 NOT IN (1, null)", "null"},
+		{"SELECT null IN (1, 2)", "null"},
+		{"SELECT 2 BETWEEN 1 AND 3", "true"},
+		{"SELECT 1 BETWEEN 1 AND 3", "true"},
+		{"SELECT 3 BETWEEN 1 AND 3", "true"},
+		{"SELECT 4 BETWEEN 1 AND 3", "false"},
+		{"SELECT 4 NOT BETWEEN 1 AND 3", "true"},
+		{"SELECT 2 BETWEEN 3 AND 1", "false"},
+		{"SELECT 'b' BETWEEN 'a' AND 'c'", "true"},
+		{"SELECT null BETWEEN 1 AND 3", "null"},
+		{"SELECT 5 BETWEEN null AND 3", "false"},
+		{"SELECT 2 BETWEEN null AND 3", "null"},
+		// BETWEEN 里的 AND 不是逻辑与
+		{"SELECT 2 BETWEEN 1 AND 3 AND true", "true"},
+		{"SELECT NOT true", "false"},
+		{"SELECT NOT false", "true"},
+		{"SELECT NOT null", "null"},
+		{"SELECT NOT 1 = 2", "true"},
+		{"SELECT NOT true AND false", "false"},
+		{"SELECT NOT NOT true", "true"},
+	}
+
+	for _, test := range tests {
+		expectValue(t, mb, test.source, test.expected)
+	}
+
+	expectRows(t, mb, "SELECT name FROM people WHERE id IN (1, 3)", [][]string{{"Alice"}, {"50% off"}})
+	expectRows(t, mb, "SELECT id FROM people WHERE id NOT BETWEEN 2 AND 3", [][]string{{"1"}, {"4"}})
+	// id = 4 时 name = 'x' 是 NULL, NOT 以后还是 NULL
+	expectRows(t, mb, "SELECT id FROM people WHERE NOT (id < 3 OR name = 'x')", [][]string{{"3"}})
+
+	expectError(t, mb, "SELECT NOT 1", ErrInvalidOperands)
+	expectError(t, mb, "SELECT 1 IN ('a')", ErrInvalidValue)
+	expectError(t, mb, "SELECT id IN (name) FROM people", ErrInvalidOperands)
+}
+
+func TestFloat(t *testing.T) {
+	mb := NewMemoryBackend()
+	mustRun(t, mb, `CREATE TABLE m (id INT, x DOUBLE PRECISION);
+		INSERT INTO m VALUES (1, 1.5);
+		INSERT INTO m VALUES (2, 2);
+		INSERT INTO m VALUES (3, 1.5e-3);
+		INSERT INTO m VALUES (4, null);`)
+
+	tests := []struct {
+		source   string
+		expected string
+	}{
+		// 带小数点的字面量是 numeric, 这里都显式转换成 float
+		{"SELECT 1.5::float", "1.5"},
+		{"SELECT 1.5e3::float", "1500"},
+		{"SELECT 2.5e-5::float", "2.5e-05"},
+		{"SELECT 1e20::float", "1e+20"},
+		{"SELECT 0.1::float + 0.2::float", "0.30000000000000004"},
+		// 整数和浮点数混合运算时提升为浮点数
+		{"SELECT 1 + 0.5::float", "1.5"},
+		{"SELECT 3 / 2::float", "1.5"},
+		{"SELECT 3 / 2", "1"},
+		{"SELECT 1 < 1.5::float", "true"},
+		{"SELECT 2 = 2::float", "true"},
+		{"SELECT -1.5::float", "-1.5"},
+		{"SELECT 1.5::float + null", "null"},
+		{"SELECT 'NaN'::float", "NaN"},
+		{"SELECT '-Infinity'::float", "-Infinity"},
+		{"SELECT 'NaN'::float > 1e300::float", "true"},
+		// 和 PostgreSQL 一样四舍六入五成双
+		{"SELECT 2.5::float::int", "2"},
+		{"SELECT 3.5::float::int", "4"},
+	}
+
+	for _, test := range tests {
+		expectValue(t, mb, test.source, test.expected)
+	}
+
+	expectRows(t, mb, "SELECT x * 2 FROM m", [][]string{{"3"}, {"4"}, {"0.003"}, {"null"}})
+	expectRows(t, mb, "SELECT id FROM m WHERE x > 1", [][]string{{"1"}, {"2"}})
+	expectColumns(t, mb, "SELECT x FROM m WHERE id = 1", []column{{Name: "x", Type: FloatType}})
+
+	expectError(t, mb, "SELECT 1.5::float / 0", ErrDivisionByZero)
+	expectError(t, mb, "SELECT 1e300::float::int", ErrOutOfRange)
+	expectError(t, mb, "SELECT 1.5::float + 'a'", ErrInvalidValue)
+	expectError(t, mb, "INSERT INTO m VALUES (5, true)", ErrInvalidDatatype)
+}
+
+func TestIntegerTypes(t *testing.T) {
+	mb := NewMemoryBackend()
+	mustRun(t, mb, `CREATE TABLE ids (s SMALLINT, i INT, b BIGINT);
+		INSERT INTO ids VALUES (32767, 2147483647, 9223372036854775807);
+		INSERT INTO ids VALUES (-32768, -2147483648, -9223372036854775808);
+		INSERT INTO ids VALUES (null, null, 3000000000);`)
+
+	expectRows(t, mb, "SELECT s, i, b FROM ids", [][]string{
+		{"32767", "2147483647", "9223372036854775807"},
+		{"-32768", "-2147483648", "-9223372036854775808"},
+		{"null", "null", "3000000000"},
+	})
+	expectRows(t, mb, "SELECT b FROM ids WHERE b > 2147483647", [][]string{{"9223372036854775807"}, {"3000000000"}})
+	expectColumns(t, mb, "SELECT s + s, s + i, i + b FROM ids WHERE b = 3000000000", []column{
+		{Name: "?column?", Type: SmallIntType},
+		{Name: "?column?", Type: IntType},
+		{Name: "?column?", Type: BigIntType},
+	})
+
+	// 整数字面量超过 int 的范围时是 bigint
+	expectValue(t, mb, "SELECT 3000000000 + 1", "3000000001")
+	expectValue(t, mb, "SELECT 2147483647::bigint + 1", "2147483648")
+	expectValue(t, mb, "SELECT '123'::smallint", "123")
+
+	expectError(t, mb, "INSERT INTO ids (s) VALUES (32768)", ErrOutOfRange)
+	expectError(t, mb, "INSERT INTO ids (i) VALUES (2147483648)", ErrOutOfRange)
+	expectError(t, mb, "INSERT INTO ids (b) VALUES (9223372036854775808)", ErrOutOfRange)
+	expectError(t, mb, "SELECT 2147483647 + 1", ErrOutOfRange)
+	expectError(t, mb, "SELECT 9223372036854775807 * 2", ErrOutOfRange)
+	expectError(t, mb, "SELECT s + s FROM ids", ErrOutOfRange)
+	expectError(t, mb, "SELECT 1 / 0", ErrDivisionByZero)
+	expectError(t, mb, "UPDATE ids SET s = 40000", ErrOutOfRange)
+}
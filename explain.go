@@ -0,0 +1,251 @@
+package jiesql
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Explain 返回查询的执行计划, 每行一个算子, 子算子缩进显示. ANALYZE 时会真正执行查询,
+// 显示每个算子实际的行数和耗时, 查询的结果被丢弃
+func (mb *MemoryBackend) Explain(explain *ExplainStatement) (*Results, error) {
+	start := time.Now()
+	plan, err := mb.planSelect(explain.query, nil)
+	if err != nil {
+		return nil, err
+	}
+	planning := time.Since(start)
+
+	var execution time.Duration
+	if explain.analyze {
+		start = time.Now()
+		if _, err := drain(plan); err != nil {
+			return nil, err
+		}
+		execution = time.Since(start)
+	}
+
+	var lines []string
+	writePlan(&lines, plan, 0, false, explain.analyze)
+	if explain.analyze {
+		lines = append(lines,
+			fmt.Sprintf("Planning Time: %.3f ms", milliseconds(planning)),
+			fmt.Sprintf("Execution Time: %.3f ms", milliseconds(execution)))
+	}
+
+	results := &Results{
+		Columns: []column{{Type: TextType, Name: "QUERY PLAN"}},
+		Rows:    [][]Cell{},
+	}
+	for _, line := range lines {
+		results.Rows = append(results.Rows, []Cell{MemoryCell(line)})
+	}
+
+	return results, nil
+}
+
+// writePlan 和 PostgreSQL 一样缩进: 子算子前面加上 ->, 算子的详细信息比标题多缩进两格
+func writePlan(lines *[]string, node *planNode, indent int, arrow bool, analyze bool) {
+	prefix := strings.Repeat(" ", indent)
+	if arrow {
+		prefix = strings.Repeat(" ", indent-4) + "->  "
+	}
+
+	line := fmt.Sprintf("%s%s  (rows=%d)", prefix, node.title, node.estimate)
+	if analyze {
+		if node.loops == 0 {
+			line += " (never executed)"
+		} else {
+			line += fmt.Sprintf(" (actual time=%.3f rows=%d loops=%d)",
+				milliseconds(node.elapsed)/float64(node.loops), node.rows/node.loops, node.loops)
+		}
+	}
+	*lines = append(*lines, line)
+
+	details := strings.Repeat(" ", indent+2)
+	for _, detail := range node.details {
+		*lines = append(*lines, details+detail)
+
+		if analyze && node.loops > 0 && strings.Contains(detail, "Filter: ") {
+			*lines = append(*lines, fmt.Sprintf("%sRows Removed by Filter: %d", details, node.removed/node.loops))
+		}
+	}
+
+	for _, ct := range node.subplans {
+		*lines = append(*lines, details+"CTE "+ct.name)
+		writePlan(lines, ct.plan, indent+8, true, analyze)
+	}
+
+	for _, child := range node.children {
+		writePlan(lines, child, indent+6, true, analyze)
+	}
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// formatExpression 把表达式还原成 SQL, 用于显示执行计划里的条件
+func formatExpression(exp expression) string {
+	switch exp.kind {
+	case literalKind:
+		switch exp.literal.kind {
+		case stringKind:
+			return "'" + strings.ReplaceAll(exp.literal.value, "'", "''") + "'"
+		case nullKind:
+			return "NULL"
+		case byteaKind:
+			return "X'" + exp.literal.value + "'"
+		case parameterKind:
+			return "$" + exp.literal.value
+		}
+
+		return exp.literal.value
+	case binaryKind:
+		return fmt.Sprintf("(%s %s %s)", formatExpression(exp.binary.a), formatOperator(exp.binary.op), formatExpression(exp.binary.b))
+	case unaryKind:
+		return fmt.Sprintf("(%s %s)", formatOperator(exp.unary.op), formatExpression(exp.unary.operand))
+	case likeKind:
+		op := "LIKE"
+		if exp.like.caseInsensitive {
+			op = "ILIKE"
+		}
+		if exp.like.not {
+			op = "NOT " + op
+		}
+
+		s := fmt.Sprintf("%s %s %s", formatExpression(exp.like.value), op, formatExpression(exp.like.pattern))
+		if exp.like.escape != nil {
+			s += " ESCAPE " + formatExpression(*exp.like.escape)
+		}
+
+		return "(" + s + ")"
+	case inKind:
+		op := "IN"
+		if exp.in.not {
+			op = "NOT IN"
+		}
+
+		return fmt.Sprintf("(%s %s (%s))", formatExpression(exp.in.value), op, formatExpressions(exp.in.list))
+	case betweenKind:
+		op := "BETWEEN"
+		if exp.between.not {
+			op = "NOT BETWEEN"
+		}
+
+		return fmt.Sprintf("(%s %s %s AND %s)", formatExpression(exp.between.value), op,
+			formatExpression(exp.between.low), formatExpression(exp.between.high))
+	case castKind:
+		return fmt.Sprintf("(%s)::%s", formatExpression(exp.cast.value), formatDataType(exp.cast.datatype))
+	case functionKind:
+		return formatFunctionCall(exp.function)
+	case arrayKind:
+		return "ARRAY[" + formatExpressions(exp.array.elements) + "]"
+	case subscriptKind:
+		return fmt.Sprintf("(%s)[%s]", formatExpression(exp.subscript.value), formatExpression(exp.subscript.index))
+	case quantifiedKind:
+		op := "ANY"
+		if exp.quantified.all {
+			op = "ALL"
+		}
+
+		return fmt.Sprintf("%s (%s)", op, formatExpression(exp.quantified.array))
+	case defaultKind:
+		return "DEFAULT"
+	}
+
+	return "?"
+}
+
+func formatExpressions(exps []*expression) string {
+	var parts []string
+	for _, exp := range exps {
+		parts = append(parts, formatExpression(*exp))
+	}
+
+	return strings.Join(parts, ", ")
+}
+
+// formatOperator 关键字运算符用大写, 例如 AND, NOT
+func formatOperator(op token) string {
+	if op.kind == keywordKind {
+		return strings.ToUpper(op.value)
+	}
+
+	return op.value
+}
+
+func formatDataType(datatype dataType) string {
+	s := datatype.name.value
+	if len(datatype.modifiers) > 0 {
+		var modifiers []string
+		for _, modifier := range datatype.modifiers {
+			modifiers = append(modifiers, modifier.value)
+		}
+
+		s += "(" + strings.Join(modifiers, ",") + ")"
+	}
+
+	if datatype.array {
+		s += "[]"
+	}
+
+	return s
+}
+
+func formatFunctionCall(fn *functionCall) string {
+	args := formatExpressions(fn.args)
+	if fn.star {
+		args = "*"
+	}
+
+	s := fmt.Sprintf("%s(%s)", fn.name.value, args)
+	if fn.over == nil {
+		return s
+	}
+
+	var clauses []string
+	if len(fn.over.partitionBy) > 0 {
+		clauses = append(clauses, "PARTITION BY "+formatExpressions(fn.over.partitionBy))
+	}
+
+	if len(fn.over.orderBy) > 0 {
+		var items []string
+		for _, item := range fn.over.orderBy {
+			order := formatExpression(item.exp)
+			if item.desc {
+				order += " DESC"
+			}
+
+			items = append(items, order)
+		}
+
+		clauses = append(clauses, "ORDER BY "+strings.Join(items, ", "))
+	}
+
+	if frame := fn.over.frame; frame != nil {
+		mode := "ROWS"
+		if frame.mode == rangeFrame {
+			mode = "RANGE"
+		}
+
+		clauses = append(clauses, fmt.Sprintf("%s BETWEEN %s AND %s", mode, formatFrameBound(frame.start), formatFrameBound(frame.end)))
+	}
+
+	return s + " OVER (" + strings.Join(clauses, " ") + ")"
+}
+
+func formatFrameBound(bound frameBound) string {
+	switch bound.kind {
+	case unboundedPrecedingBound:
+		return "UNBOUNDED PRECEDING"
+	case precedingBound:
+		return formatExpression(*bound.offset) + " PRECEDING"
+	case currentRowBound:
+		return "CURRENT ROW"
+	case followingBound:
+		return formatExpression(*bound.offset) + " FOLLOWING"
+	}
+
+	return "UNBOUNDED FOLLOWING"
+}
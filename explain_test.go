@@ -0,0 +1,108 @@
+package jiesql
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// timings 匹配 EXPLAIN ANALYZE 里每次运行都不一样的耗时
+var timings = regexp.MustCompile(`(time=|Time: )[0-9.]+`)
+
+func expectPlan(t *testing.T, mb *MemoryBackend, source string, expected ...string) {
+	t.Helper()
+
+	var lines []string
+	for _, row := range formatRows(mustRun(t, mb, source)) {
+		lines = append(lines, timings.ReplaceAllString(row[0], "${1}X"))
+	}
+
+	if !reflect.DeepEqual(lines, expected) {
+		t.Errorf("%s:\n got:\n%s\nwant:\n%s", source, strings.Join(lines, "\n"), strings.Join(expected, "\n"))
+	}
+}
+
+func newExplainBackend(t *testing.T) *MemoryBackend {
+	t.Helper()
+
+	mb := NewMemoryBackend()
+	mustRun(t, mb, `CREATE TABLE a (id INT, name TEXT);
+		INSERT INTO a VALUES (1, 'x');
+		INSERT INTO a VALUES (2, 'y');
+		INSERT INTO a VALUES (3, null);
+		CREATE TABLE b (aid INT);
+		INSERT INTO b VALUES (1);
+		CREATE VIEW v AS SELECT id FROM a WHERE id > 1;`)
+	return mb
+}
+
+func TestExplain(t *testing.T) {
+	mb := newExplainBackend(t)
+
+	expectColumns(t, mb, "EXPLAIN SELECT 1", []column{{Name: "QUERY PLAN", Type: TextType}})
+	expectPlan(t, mb, "EXPLAIN SELECT 1", "Result  (rows=1)")
+	expectPlan(t, mb, "EXPLAIN SELECT id FROM a WHERE id > 1 ORDER BY name LIMIT 2",
+		"Limit  (rows=1)",
+		"  ->  Sort  (rows=1)",
+		"        Sort Key: name",
+		"        ->  Seq Scan on a  (rows=1)",
+		"              Filter: (id > 1)")
+	expectPlan(t, mb, "EXPLAIN SELECT a.id FROM a, b WHERE a.id = b.aid",
+		"Nested Loop  (rows=1)",
+		"  Join Filter: (a.id = b.aid)",
+		"  ->  Seq Scan on a  (rows=3)",
+		"  ->  Seq Scan on b  (rows=1)")
+	expectPlan(t, mb, "EXPLAIN SELECT id FROM v",
+		"Subquery Scan on v  (rows=1)",
+		"  ->  Seq Scan on a  (rows=1)",
+		"        Filter: (id > 1)")
+	expectPlan(t, mb, "EXPLAIN WITH c AS (SELECT id FROM a) SELECT id FROM c UNION SELECT 5",
+		"Unique  (rows=4)",
+		"  CTE c",
+		"    ->  Seq Scan on a  (rows=3)",
+		"  ->  Append  (rows=4)",
+		"        ->  CTE Scan on c  (rows=3)",
+		"        ->  Result  (rows=1)")
+	expectPlan(t, mb, "EXPLAIN SELECT row_number() OVER () FROM a",
+		"WindowAgg  (rows=3)",
+		"  Window: row_number() OVER ()",
+		"  ->  Seq Scan on a  (rows=3)")
+	expectPlan(t, mb, "EXPLAIN SELECT id FROM a WHERE name LIKE 'x%' AND id IN (1, 2) OR id BETWEEN 1 AND null",
+		"Seq Scan on a  (rows=1)",
+		"  Filter: (((name LIKE 'x%') AND (id IN (1, 2))) OR (id BETWEEN 1 AND NULL))")
+
+	// 没有 ANALYZE 时不执行查询
+	mustRun(t, mb, "CREATE TABLE z (n INT); INSERT INTO z VALUES (0)")
+	expectPlan(t, mb, "EXPLAIN SELECT 1 / n FROM z", "Seq Scan on z  (rows=1)")
+	expectError(t, mb, "EXPLAIN ANALYZE SELECT 1 / n FROM z", ErrDivisionByZero)
+
+	expectError(t, mb, "EXPLAIN SELECT id FROM nosuch", ErrTableDoesNotExist)
+	expectError(t, mb, "EXPLAIN INSERT INTO a VALUES (4, 'z')", nil)
+}
+
+func TestExplainAnalyze(t *testing.T) {
+	mb := newExplainBackend(t)
+
+	expectPlan(t, mb, "EXPLAIN ANALYZE SELECT id FROM a WHERE id > 1",
+		"Seq Scan on a  (rows=1) (actual time=X rows=2 loops=1)",
+		"  Filter: (id > 1)",
+		"  Rows Removed by Filter: 1",
+		"Planning Time: X ms",
+		"Execution Time: X ms")
+	// LIMIT 够了以后不再从子算子读取
+	expectPlan(t, mb, "EXPLAIN ANALYZE SELECT id FROM a LIMIT 1",
+		"Limit  (rows=1) (actual time=X rows=1 loops=1)",
+		"  ->  Seq Scan on a  (rows=3) (actual time=X rows=1 loops=1)",
+		"Planning Time: X ms",
+		"Execution Time: X ms")
+	// 内层每一行外层执行一次
+	expectPlan(t, mb, "EXPLAIN ANALYZE SELECT a.id FROM a, b WHERE a.id = b.aid",
+		"Nested Loop  (rows=1) (actual time=X rows=1 loops=1)",
+		"  Join Filter: (a.id = b.aid)",
+		"  Rows Removed by Filter: 2",
+		"  ->  Seq Scan on a  (rows=3) (actual time=X rows=3 loops=1)",
+		"  ->  Seq Scan on b  (rows=1) (actual time=X rows=1 loops=3)",
+		"Planning Time: X ms",
+		"Execution Time: X ms")
+}
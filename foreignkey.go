@@ -0,0 +1,280 @@
+package jiesql
+
+import (
+	"fmt"
+	"sort"
+)
+
+// 外键保存在引用方的表上, 被引用的表按名字查找. 和 PostgreSQL 的 MATCH SIMPLE 一样,
+// 外键列中有 NULL 时不检查. 还没有唯一约束, 所以被引用的列不要求唯一.
+type foreignKeyConstraint struct {
+	name       string
+	columns    []int
+	table      string
+	references []int
+	onDelete   foreignKeyAction
+}
+
+// addForeignKey 检查外键的列和类型, 引用自己的表时 t 还没有注册, 直接使用 t
+func (mb *MemoryBackend) addForeignKey(t *table, name string, fk *foreignKey) error {
+	parent, ok := mb.tables[fk.table.value]
+	if fk.table.value == t.name {
+		parent, ok = t, true
+	}
+	if !ok {
+		return ErrTableDoesNotExist
+	}
+
+	if _, ok := mb.materializedViews[parent.name]; ok {
+		return fmt.Errorf("%w: foreign key %s cannot reference %s", ErrMaterializedView, name, parent.name)
+	}
+
+	if len(fk.columns) != len(fk.references) {
+		return fmt.Errorf("%w: number of referencing and referenced columns for foreign key %s disagree", ErrInvalidDatatype, name)
+	}
+
+	c := foreignKeyConstraint{
+		name:     name,
+		table:    parent.name,
+		onDelete: fk.onDelete,
+	}
+	for i := range fk.columns {
+		column := t.columnIndex(fk.columns[i].value)
+		reference := parent.columnIndex(fk.references[i].value)
+		if column < 0 || reference < 0 {
+			return ErrColumnDoesNotExist
+		}
+
+		if t.columnTypes[column] != parent.columnTypes[reference] {
+			return fmt.Errorf("%w: foreign key %s compares %s with %s", ErrInvalidDatatype, name, t.columnTypes[column], parent.columnTypes[reference])
+		}
+
+		c.columns = append(c.columns, column)
+		c.references = append(c.references, reference)
+	}
+
+	t.foreignKeys = append(t.foreignKeys, c)
+	return nil
+}
+
+// keyOf 取出行上的键, 有 NULL 时返回 nil
+func keyOf(row []MemoryCell, columns []int) []MemoryCell {
+	var key []MemoryCell
+	for _, i := range columns {
+		if row[i] == nil {
+			return nil
+		}
+
+		key = append(key, row[i])
+	}
+
+	return key
+}
+
+func containsKey(t *table, rows [][]MemoryCell, columns []int, key []MemoryCell) bool {
+	for _, row := range rows {
+		other := keyOf(row, columns)
+		if other == nil {
+			continue
+		}
+
+		equal := true
+		for i, column := range columns {
+			if compareCells(key[i], other[i], t.columnTypes[column]) != 0 {
+				equal = false
+				break
+			}
+		}
+
+		if equal {
+			return true
+		}
+	}
+
+	return false
+}
+
+type referencingKey struct {
+	child *table
+	fk    foreignKeyConstraint
+}
+
+// referencingKeys 返回所有引用了表 name 的外键, 按表名排序让错误信息稳定
+func (mb *MemoryBackend) referencingKeys(name string) []referencingKey {
+	var names []string
+	for n := range mb.tables {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+
+	var keys []referencingKey
+	for _, n := range names {
+		for _, fk := range mb.tables[n].foreignKeys {
+			if fk.table == name {
+				keys = append(keys, referencingKey{child: mb.tables[n], fk: fk})
+			}
+		}
+	}
+
+	return keys
+}
+
+// tableChange 记录一条语句对一张表的修改
+type tableChange struct {
+	// 修改后表里的所有行
+	rows [][]MemoryCell
+	// 新插入或修改后的行, 它们引用的行必须存在
+	changed [][]MemoryCell
+	// 删除的行和修改前的行, 不能还有行引用它们的键
+	removed [][]MemoryCell
+}
+
+type tableChanges map[*table]*tableChange
+
+// checkReferentialIntegrity 在修改生效前检查外键, 看到的是所有修改完成后的状态
+func (mb *MemoryBackend) checkReferentialIntegrity(changes tableChanges) error {
+	rowsOf := func(t *table) [][]MemoryCell {
+		if change, ok := changes[t]; ok {
+			return change.rows
+		}
+
+		return t.rows
+	}
+
+	for t, change := range changes {
+		for _, fk := range t.foreignKeys {
+			parent := mb.tables[fk.table]
+			if fk.table == t.name {
+				parent = t
+			}
+
+			for _, row := range change.changed {
+				key := keyOf(row, fk.columns)
+				if key != nil && !containsKey(parent, rowsOf(parent), fk.references, key) {
+					return &ConstraintError{Constraint: fk.name, Err: ErrViolatesForeignKey}
+				}
+			}
+		}
+
+		if len(change.removed) == 0 {
+			continue
+		}
+
+		for _, ref := range mb.referencingKeys(t.name) {
+			for _, row := range change.removed {
+				key := keyOf(row, ref.fk.references)
+				// 还有别的行提供同样的键时不算违反
+				if key == nil || containsKey(t, rowsOf(t), ref.fk.references, key) {
+					continue
+				}
+
+				if containsKey(ref.child, rowsOf(ref.child), ref.fk.columns, key) {
+					return &ConstraintError{Constraint: ref.fk.name, Err: ErrViolatesForeignKey}
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// cascadeDelete 把 ON DELETE CASCADE 和 SET NULL 的影响记录到 deleted 和 nulled 里,
+// RESTRICT 和 NO ACTION 留给 checkReferentialIntegrity 检查
+func (mb *MemoryBackend) cascadeDelete(t *table, rows []int, deleted map[*table]map[int]bool, nulled map[*table]map[int][]int) {
+	if deleted[t] == nil {
+		deleted[t] = map[int]bool{}
+	}
+
+	var removed [][]MemoryCell
+	for _, r := range rows {
+		if !deleted[t][r] {
+			deleted[t][r] = true
+			removed = append(removed, t.rows[r])
+		}
+	}
+
+	if len(removed) == 0 {
+		return
+	}
+
+	// 被引用的列不要求唯一, 和 checkReferentialIntegrity 一样, 还有没删除的行提供同样的键时不影响引用它的行
+	var surviving [][]MemoryCell
+	for r, row := range t.rows {
+		if !deleted[t][r] {
+			surviving = append(surviving, row)
+		}
+	}
+
+	for _, ref := range mb.referencingKeys(t.name) {
+		if ref.fk.onDelete != cascadeAction && ref.fk.onDelete != setNullAction {
+			continue
+		}
+
+		var cascaded []int
+		for r, row := range ref.child.rows {
+			key := keyOf(row, ref.fk.columns)
+			if key == nil || deleted[ref.child][r] || !containsKey(t, removed, ref.fk.references, key) ||
+				containsKey(t, surviving, ref.fk.references, key) {
+				continue
+			}
+
+			if ref.fk.onDelete == cascadeAction {
+				cascaded = append(cascaded, r)
+				continue
+			}
+
+			if nulled[ref.child] == nil {
+				nulled[ref.child] = map[int][]int{}
+			}
+			nulled[ref.child][r] = append(nulled[ref.child][r], ref.fk.columns...)
+		}
+
+		mb.cascadeDelete(ref.child, cascaded, deleted, nulled)
+	}
+}
+
+// planDelete 算出删除表 t 的 rows 行以及级联删除和置空影响的所有行, 检查通过后再一起修改,
+// 这样中途出错时所有表保持不变
+func (mb *MemoryBackend) planDelete(t *table, rows []int) (tableChanges, error) {
+	deleted := map[*table]map[int]bool{}
+	nulled := map[*table]map[int][]int{}
+	mb.cascadeDelete(t, rows, deleted, nulled)
+
+	changes := tableChanges{}
+	for t := range deleted {
+		changes[t] = &tableChange{}
+	}
+	for t := range nulled {
+		changes[t] = &tableChange{}
+	}
+
+	for t, change := range changes {
+		for r, row := range t.rows {
+			if deleted[t][r] {
+				change.removed = append(change.removed, row)
+				continue
+			}
+
+			columns, ok := nulled[t][r]
+			if !ok {
+				change.rows = append(change.rows, row)
+				continue
+			}
+
+			newRow := append([]MemoryCell{}, row...)
+			for _, i := range columns {
+				newRow[i] = nil
+			}
+
+			if err := mb.checkConstraints(t, newRow); err != nil {
+				return nil, err
+			}
+
+			change.rows = append(change.rows, newRow)
+			change.changed = append(change.changed, newRow)
+			change.removed = append(change.removed, row)
+		}
+	}
+
+	return changes, nil
+}
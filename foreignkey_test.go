@@ -0,0 +1,126 @@
+package jiesql
+
+import (
+	"testing"
+)
+
+func TestForeignKeyDuplicateKeys(t *testing.T) {
+	mb := NewMemoryBackend()
+	mustRun(t, mb, `CREATE TABLE p (id INT, name TEXT);
+		CREATE TABLE c (pid INT REFERENCES p (id) ON DELETE CASCADE, note TEXT);
+		CREATE TABLE n (pid INT REFERENCES p (id) ON DELETE SET NULL, note TEXT);
+		CREATE TABLE r (pid INT REFERENCES p (id) ON DELETE RESTRICT, note TEXT);
+		CREATE TABLE a (pid INT REFERENCES p (id), note TEXT);
+		INSERT INTO p VALUES (1, 'a');
+		INSERT INTO p VALUES (2, 'b');
+		INSERT INTO p VALUES (2, 'dup');
+		INSERT INTO p VALUES (3, 'c');
+		INSERT INTO p VALUES (3, 'dup');
+		INSERT INTO c VALUES (1, 'one');
+		INSERT INTO c VALUES (2, 'two');
+		INSERT INTO n VALUES (2, 'two');
+		INSERT INTO r VALUES (3, 'three');
+		INSERT INTO a VALUES (3, 'three');`)
+
+	// 被引用的列不唯一, 还有一行 id = 2 时不级联也不置空
+	mustRun(t, mb, "DELETE FROM p WHERE id = 2 AND name = 'dup'")
+	expectRows(t, mb, "SELECT pid, note FROM c", [][]string{{"1", "one"}, {"2", "two"}})
+	expectRows(t, mb, "SELECT pid, note FROM n", [][]string{{"2", "two"}})
+
+	mustRun(t, mb, "DELETE FROM p WHERE id = 2")
+	expectRows(t, mb, "SELECT pid, note FROM c", [][]string{{"1", "one"}})
+	expectRows(t, mb, "SELECT pid, note FROM n", [][]string{{"null", "two"}})
+
+	// RESTRICT 和 NO ACTION 也一样, 还有一行 id = 3 时可以删除或修改另一行
+	mustRun(t, mb, "DELETE FROM p WHERE id = 3 AND name = 'dup'")
+	mustRun(t, mb, "INSERT INTO p VALUES (3, 'dup')")
+	mustRun(t, mb, "UPDATE p SET id = 4 WHERE id = 3 AND name = 'dup'")
+	expectRows(t, mb, "SELECT id, name FROM p", [][]string{{"1", "a"}, {"3", "c"}, {"4", "dup"}})
+
+	// 最后一行被删除时才报错
+	for _, source := range []string{"DELETE FROM p WHERE id = 3", "UPDATE p SET id = 5 WHERE id = 3"} {
+		_, err := runSQL(mb, source)
+		if cerr, ok := err.(*ConstraintError); !ok || cerr.Constraint != "a_pid_fkey" || cerr.Err != ErrViolatesForeignKey {
+			t.Errorf("%s: expected foreign key a_pid_fkey to fail, got %v", source, err)
+		}
+	}
+
+	mustRun(t, mb, "DELETE FROM a")
+	_, err := runSQL(mb, "DELETE FROM p WHERE id = 3")
+	if cerr, ok := err.(*ConstraintError); !ok || cerr.Constraint != "r_pid_fkey" || cerr.Err != ErrViolatesForeignKey {
+		t.Errorf("expected foreign key r_pid_fkey to fail, got %v", err)
+	}
+	expectRows(t, mb, "SELECT pid, note FROM r", [][]string{{"3", "three"}})
+}
+
+func TestForeignKeys(t *testing.T) {
+	mb := NewMemoryBackend()
+	mustRun(t, mb, `CREATE TABLE users (id INT, name TEXT);
+		CREATE TABLE posts (id INT, author INT REFERENCES users (id), title TEXT);
+		CREATE TABLE comments (post INT, body TEXT, FOREIGN KEY (post) REFERENCES posts (id) ON DELETE CASCADE);
+		CREATE TABLE likes (post INT REFERENCES posts (id) ON DELETE SET NULL);
+		CREATE TABLE pins (post INT REFERENCES posts (id) ON DELETE RESTRICT);
+		INSERT INTO users VALUES (1, 'ann');
+		INSERT INTO users VALUES (2, 'bo');
+		INSERT INTO posts VALUES (10, 1, 'hello');
+		INSERT INTO posts VALUES (11, 2, 'world');
+		INSERT INTO posts VALUES (12, null, 'anonymous');
+		INSERT INTO comments VALUES (10, 'nice');
+		INSERT INTO comments VALUES (11, 'ok');
+		INSERT INTO likes VALUES (10);
+		INSERT INTO likes VALUES (11);
+		INSERT INTO pins VALUES (11);`)
+
+	expectForeignKeyError := func(source, constraint string) {
+		t.Helper()
+
+		_, err := runSQL(mb, source)
+		cerr, ok := err.(*ConstraintError)
+		if !ok || cerr.Constraint != constraint || cerr.Err != ErrViolatesForeignKey {
+			t.Errorf("%s: expected foreign key %s to fail, got %v", source, constraint, err)
+		}
+	}
+
+	expectForeignKeyError("INSERT INTO posts VALUES (13, 3, 'ghost')", "posts_author_fkey")
+	expectForeignKeyError("UPDATE posts SET author = 3 WHERE id = 10", "posts_author_fkey")
+	// 被引用的行还被引用时不能删除或修改键
+	expectForeignKeyError("DELETE FROM users WHERE id = 1", "posts_author_fkey")
+	expectForeignKeyError("UPDATE users SET id = 5 WHERE id = 2", "posts_author_fkey")
+	expectForeignKeyError("DELETE FROM posts WHERE id = 11", "pins_post_fkey")
+
+	// 级联删除评论, 点赞的外键置为 NULL
+	mustRun(t, mb, "DELETE FROM posts WHERE id = 10")
+	expectRows(t, mb, "SELECT post, body FROM comments", [][]string{{"11", "ok"}})
+	expectRows(t, mb, "SELECT post FROM likes", [][]string{{"null"}, {"11"}})
+	mustRun(t, mb, "DELETE FROM users WHERE id = 1")
+
+	// RESTRICT 失败时级联的修改也不生效
+	expectRows(t, mb, "SELECT id FROM posts", [][]string{{"11"}, {"12"}})
+	expectRows(t, mb, "SELECT post FROM comments", [][]string{{"11"}})
+
+	// 外键列是 NULL 时不检查, 修改不涉及键时也不检查
+	mustRun(t, mb, "INSERT INTO likes VALUES (null)")
+	mustRun(t, mb, "UPDATE users SET name = 'bob' WHERE id = 2")
+
+	expectError(t, mb, "CREATE TABLE bad (a INT REFERENCES nosuch (id))", ErrTableDoesNotExist)
+	expectError(t, mb, "CREATE TABLE bad (a INT REFERENCES users (nosuch))", ErrColumnDoesNotExist)
+	expectError(t, mb, "CREATE TABLE bad (a TEXT REFERENCES users (id))", ErrInvalidDatatype)
+	expectError(t, mb, "CREATE TABLE bad (a INT, b INT, FOREIGN KEY (a, b) REFERENCES users (id))", ErrInvalidDatatype)
+}
+
+func TestSelfReferencingForeignKey(t *testing.T) {
+	mb := NewMemoryBackend()
+	mustRun(t, mb, `CREATE TABLE staff (id INT, boss INT REFERENCES staff (id) ON DELETE CASCADE);
+		INSERT INTO staff VALUES (1, null);
+		INSERT INTO staff VALUES (2, 1);
+		INSERT INTO staff VALUES (3, 2);
+		INSERT INTO staff VALUES (4, 1);`)
+
+	expectError(t, mb, "INSERT INTO staff VALUES (5, 9)", ErrViolatesForeignKey)
+
+	// 级联删除沿着引用链一直进行下去
+	mustRun(t, mb, "DELETE FROM staff WHERE id = 2")
+	expectRows(t, mb, "SELECT id FROM staff", [][]string{{"1"}, {"4"}})
+	mustRun(t, mb, "DELETE FROM staff WHERE id = 1")
+	expectRows(t, mb, "SELECT id FROM staff", [][]string{})
+}
@@ -0,0 +1,236 @@
+package jiesql
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// builtinFunction 接收已经求值的参数和它们的类型, 返回结果和结果类型.
+// 参数为 NULL 时 (包括推导列类型时的全 NULL 行) 也要返回正确的结果类型.
+type builtinFunction func(mb *MemoryBackend, args []MemoryCell, types []ColumnType) (MemoryCell, ColumnType, error)
+
+var builtinFunctions = map[string]builtinFunction{
+	"now":        fnNow,
+	"date_trunc": fnDateTrunc,
+	"extract":    fnExtract,
+	"date_part":  fnExtract,
+
+	"json_extract_path":      fnJsonExtractPath,
+	"json_extract_path_text": fnJsonExtractPathText,
+
+	"gen_random_uuid": fnGenRandomUUID,
+
+	"nextval": fnNextval,
+	"currval": fnCurrval,
+	"setval":  fnSetval,
+}
+
+// NOW() 返回 MemoryBackend.Clock 的当前时间, TIMESTAMP 不带时区, 所以取时钟所在时区的墙上时间
+func fnNow(mb *MemoryBackend, args []MemoryCell, types []ColumnType) (MemoryCell, ColumnType, error) {
+	if len(args) != 0 {
+		return nil, 0, ErrFunctionDoesNotExist
+	}
+
+	now := mb.Clock()
+	wall := time.Date(now.Year(), now.Month(), now.Day(), now.Hour(), now.Minute(), now.Second(), now.Nanosecond(), time.UTC)
+	return timestampCell(timeToTimestamp(wall)), TimestampType, nil
+}
+
+// DATE_TRUNC(field, source) 把 date 或 timestamp 截断到 field 指定的精度
+func fnDateTrunc(mb *MemoryBackend, args []MemoryCell, types []ColumnType) (MemoryCell, ColumnType, error) {
+	if len(args) != 2 || types[0] != TextType || (types[1] != TimestampType && types[1] != DateType) {
+		return nil, 0, ErrFunctionDoesNotExist
+	}
+
+	if args[0] == nil || args[1] == nil {
+		return nil, TimestampType, nil
+	}
+
+	t, err := dateTrunc(args[0].AsText(), args[1].AsTime())
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return timestampCell(timeToTimestamp(t)), TimestampType, nil
+}
+
+// EXTRACT(field FROM source) 从 date, timestamp, time 或 interval 中取出一个字段
+func fnExtract(mb *MemoryBackend, args []MemoryCell, types []ColumnType) (MemoryCell, ColumnType, error) {
+	if len(args) != 2 || types[0] != TextType || !isDatetimeType(types[1]) {
+		return nil, 0, ErrFunctionDoesNotExist
+	}
+
+	if args[0] == nil || args[1] == nil {
+		return nil, FloatType, nil
+	}
+
+	field := strings.ToLower(args[0].AsText())
+
+	var f float64
+	var err error
+	switch types[1] {
+	case IntervalType:
+		f, err = extractFromInterval(field, args[1].AsInterval())
+	case TimeType:
+		f, err = extractFromInterval(field, Interval{Microseconds: args[1].AsInt64()})
+	default:
+		f, err = extractFromTime(field, args[1].AsTime())
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return floatCell(f), FloatType, nil
+}
+
+// JSON_EXTRACT_PATH(json, key...) 等价于连续使用 ->, 路径不存在时返回 NULL
+func fnJsonExtractPath(mb *MemoryBackend, args []MemoryCell, types []ColumnType) (MemoryCell, ColumnType, error) {
+	v, err := jsonPathArgs(args, types)
+	if err != nil || v == nil {
+		return nil, JsonType, err
+	}
+
+	return MemoryCell(v), JsonType, nil
+}
+
+// JSON_EXTRACT_PATH_TEXT(json, key...) 等价于连续使用 -> 最后一步用 ->>
+func fnJsonExtractPathText(mb *MemoryBackend, args []MemoryCell, types []ColumnType) (MemoryCell, ColumnType, error) {
+	v, err := jsonPathArgs(args, types)
+	if err != nil || v == nil {
+		return nil, TextType, err
+	}
+
+	return jsonToText(v), TextType, nil
+}
+
+// jsonPathArgs 检查参数并按路径取值. 第一个参数是字面量时按 json 解析
+func jsonPathArgs(args []MemoryCell, types []ColumnType) (json.RawMessage, error) {
+	if len(args) < 1 || (types[0] != JsonType && types[0] != TextType) {
+		return nil, ErrFunctionDoesNotExist
+	}
+
+	var path []string
+	for i, arg := range args[1:] {
+		if types[i+1] != TextType {
+			return nil, ErrFunctionDoesNotExist
+		}
+
+		if arg == nil {
+			return nil, nil
+		}
+
+		path = append(path, arg.AsText())
+	}
+
+	if args[0] == nil {
+		return nil, nil
+	}
+
+	if types[0] == TextType {
+		if _, err := parseJSON(args[0].AsText()); err != nil {
+			return nil, err
+		}
+	}
+
+	v, ok := jsonExtractPath(args[0], path)
+	if !ok {
+		return nil, nil
+	}
+
+	return v, nil
+}
+
+// GEN_RANDOM_UUID() 返回随机生成的第 4 版 UUID
+func fnGenRandomUUID(mb *MemoryBackend, args []MemoryCell, types []ColumnType) (MemoryCell, ColumnType, error) {
+	if len(args) != 0 {
+		return nil, 0, ErrFunctionDoesNotExist
+	}
+
+	cell, err := randomUUID()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return cell, UUIDType, nil
+}
+
+// sequenceArgument 找到序列函数第一个参数指定的序列, 参数为 NULL 时返回 nil
+func (mb *MemoryBackend) sequenceArgument(args []MemoryCell, types []ColumnType) (*sequence, error) {
+	if len(args) == 0 || types[0] != TextType {
+		return nil, ErrFunctionDoesNotExist
+	}
+
+	if args[0] == nil {
+		return nil, nil
+	}
+
+	s, ok := mb.sequences[args[0].AsText()]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrSequenceDoesNotExist, args[0].AsText())
+	}
+
+	return s, nil
+}
+
+// NEXTVAL(sequence) 推进序列并返回新的值
+func fnNextval(mb *MemoryBackend, args []MemoryCell, types []ColumnType) (MemoryCell, ColumnType, error) {
+	if len(args) != 1 {
+		return nil, 0, ErrFunctionDoesNotExist
+	}
+
+	s, err := mb.sequenceArgument(args, types)
+	if err != nil || s == nil {
+		return nil, BigIntType, err
+	}
+
+	v, err := s.next()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return integerCell(v, BigIntType), BigIntType, nil
+}
+
+// CURRVAL(sequence) 返回本会话中 nextval 最后一次返回的值
+func fnCurrval(mb *MemoryBackend, args []MemoryCell, types []ColumnType) (MemoryCell, ColumnType, error) {
+	if len(args) != 1 {
+		return nil, 0, ErrFunctionDoesNotExist
+	}
+
+	s, err := mb.sequenceArgument(args, types)
+	if err != nil || s == nil {
+		return nil, BigIntType, err
+	}
+
+	if !s.hasCurrent {
+		return nil, 0, fmt.Errorf("%w: %s", ErrCurrvalNotDefined, s.name)
+	}
+
+	return integerCell(s.current, BigIntType), BigIntType, nil
+}
+
+// SETVAL(sequence, value [, is_called]) 设置序列的当前值并返回 value
+func fnSetval(mb *MemoryBackend, args []MemoryCell, types []ColumnType) (MemoryCell, ColumnType, error) {
+	if len(args) < 2 || len(args) > 3 || !isIntegerType(types[1]) || (len(args) == 3 && types[2] != BoolType) {
+		return nil, 0, ErrFunctionDoesNotExist
+	}
+
+	s, err := mb.sequenceArgument(args, types)
+	if err != nil || s == nil || args[1] == nil || (len(args) == 3 && args[2] == nil) {
+		return nil, BigIntType, err
+	}
+
+	called := true
+	if len(args) == 3 {
+		called = args[2].AsBool() == true
+	}
+
+	v := args[1].AsInt64()
+	if err := s.set(v, called); err != nil {
+		return nil, 0, err
+	}
+
+	return integerCell(v, BigIntType), BigIntType, nil
+}
@@ -0,0 +1,107 @@
+package jiesql
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// runSQL 依次执行 source 里的语句, 返回最后一条语句的结果, 末尾的分号可以省略
+func runSQL(mb *MemoryBackend, source string) (*Results, error) {
+	if !strings.HasSuffix(strings.TrimSpace(source), ";") {
+		source += ";"
+	}
+
+	ast, err := Parse(source)
+	if err != nil {
+		return nil, err
+	}
+
+	var results *Results
+	for _, stmt := range ast.Statements {
+		results, err = mb.execute(stmt)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return results, nil
+}
+
+func mustRun(t *testing.T, mb *MemoryBackend, source string) *Results {
+	t.Helper()
+
+	results, err := runSQL(mb, source)
+	if err != nil {
+		t.Fatalf("%s: %s", source, err)
+	}
+
+	return results
+}
+
+// formatRows 把结果的每一格转换成和 REPL 一样的文本
+func formatRows(results *Results) [][]string {
+	rows := [][]string{}
+	for i := range results.Rows {
+		row := []string{}
+		for j := range results.Rows[i] {
+			row = append(row, results.Format(i, j))
+		}
+
+		rows = append(rows, row)
+	}
+
+	return rows
+}
+
+func expectRows(t *testing.T, mb *MemoryBackend, source string, expected [][]string) {
+	t.Helper()
+
+	rows := formatRows(mustRun(t, mb, source))
+	if !reflect.DeepEqual(rows, expected) {
+		t.Errorf("%s:\n got %q\nwant %q", source, rows, expected)
+	}
+}
+
+// expectResults 检查已经得到的结果, 用于不经过 runSQL 的查询
+func expectResults(t *testing.T, results *Results, expected [][]string) {
+	t.Helper()
+
+	rows := formatRows(results)
+	if !reflect.DeepEqual(rows, expected) {
+		t.Errorf("got %q\nwant %q", rows, expected)
+	}
+}
+
+// expectValue 检查只返回一行一列的查询
+func expectValue(t *testing.T, mb *MemoryBackend, source string, expected string) {
+	t.Helper()
+
+	expectRows(t, mb, source, [][]string{{expected}})
+}
+
+// expectError 检查语句失败, target 为 nil 时只要求出错
+func expectError(t *testing.T, mb *MemoryBackend, source string, target error) {
+	t.Helper()
+
+	_, err := runSQL(mb, source)
+	if err == nil {
+		t.Errorf("%s: expected error %v, got none", source, target)
+		return
+	}
+
+	if target != nil && !errors.Is(err, target) {
+		t.Errorf("%s: expected error %v, got %v", source, target, err)
+	}
+}
+
+// expectColumns 检查结果的列名和类型
+func expectColumns(t *testing.T, mb *MemoryBackend, source string, expected []column) {
+	t.Helper()
+
+	results := mustRun(t, mb, source)
+	if !reflect.DeepEqual(results.Columns, expected) {
+		t.Errorf("%s:\n got %v\nwant %v", source, results.Columns, expected)
+	}
+}
@@ -0,0 +1,137 @@
+package jiesql
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+)
+
+// JSON 和 PostgreSQL 的 json 类型一样按原文保存, 只在写入时检查格式, 取字段时再解析
+
+// parseJSON 检查文本是合法的 JSON
+func parseJSON(s string) (MemoryCell, error) {
+	if !json.Valid([]byte(s)) {
+		return nil, ErrInvalidValue
+	}
+
+	return MemoryCell(s), nil
+}
+
+// jsonObjectField 取对象的字段, 不是对象或者字段不存在时返回 false
+func jsonObjectField(doc []byte, key string) (json.RawMessage, bool) {
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal(doc, &object); err != nil || object == nil {
+		return nil, false
+	}
+
+	v, ok := object[key]
+	return v, ok
+}
+
+// jsonArrayElement 取数组的元素, 和 PostgreSQL 一样负数下标从末尾数起
+func jsonArrayElement(doc []byte, index int64) (json.RawMessage, bool) {
+	var array []json.RawMessage
+	if err := json.Unmarshal(doc, &array); err != nil || array == nil {
+		return nil, false
+	}
+
+	if index < 0 {
+		index += int64(len(array))
+	}
+
+	if index < 0 || index >= int64(len(array)) {
+		return nil, false
+	}
+
+	return array[index], true
+}
+
+// jsonExtractPath 按路径逐层取值, 遇到数组时把路径元素当作下标
+func jsonExtractPath(doc []byte, path []string) (json.RawMessage, bool) {
+	v := json.RawMessage(doc)
+	for _, key := range path {
+		var ok bool
+		if bytes.HasPrefix(bytes.TrimSpace(v), []byte("[")) {
+			index, err := strconv.ParseInt(key, 10, 64)
+			if err != nil {
+				return nil, false
+			}
+
+			v, ok = jsonArrayElement(v, index)
+		} else {
+			v, ok = jsonObjectField(v, key)
+		}
+
+		if !ok {
+			return nil, false
+		}
+	}
+
+	return v, true
+}
+
+// jsonToText 是 ->> 的结果: 字符串去掉引号和转义, JSON null 变成 SQL NULL, 其余保持原文
+func jsonToText(v json.RawMessage) MemoryCell {
+	if string(v) == "null" {
+		return nil
+	}
+
+	var s string
+	if err := json.Unmarshal(v, &s); err == nil {
+		return MemoryCell(s)
+	}
+
+	return MemoryCell(v)
+}
+
+// evaluateJsonArrowCell 计算 json -> key 和 json ->> key, key 是文本时取对象字段, 是整数时取数组元素.
+// 左边的无类型字面量当作 json, 右边的当作 text, 所以不走 evaluateOperands 的类型推断
+func (mb *MemoryBackend) evaluateJsonArrowCell(t *table, row []MemoryCell, exp expression) (MemoryCell, string, ColumnType, error) {
+	bexp := exp.binary
+
+	l, _, lt, err := mb.evaluateCell(t, row, bexp.a)
+	if err != nil {
+		return nil, "", 0, err
+	}
+
+	l, lt, err = coerceUntyped(bexp.a, l, lt, JsonType, mb.enums)
+	if err != nil {
+		return nil, "", 0, err
+	}
+
+	r, _, rt, err := mb.evaluateCell(t, row, bexp.b)
+	if err != nil {
+		return nil, "", 0, err
+	}
+
+	if lt != JsonType || (rt != TextType && !isIntegerType(rt)) {
+		return nil, "", 0, ErrInvalidOperands
+	}
+
+	typ := JsonType
+	if symbol(bexp.op.value) == jsonArrowTextSymbol {
+		typ = TextType
+	}
+
+	if l == nil || r == nil {
+		return nil, "?column?", typ, nil
+	}
+
+	var v json.RawMessage
+	var ok bool
+	if rt == TextType {
+		v, ok = jsonObjectField(l, r.AsText())
+	} else {
+		v, ok = jsonArrayElement(l, r.AsInt64())
+	}
+
+	if !ok {
+		return nil, "?column?", typ, nil
+	}
+
+	if typ == TextType {
+		return jsonToText(v), "?column?", typ, nil
+	}
+
+	return MemoryCell(v), "?column?", typ, nil
+}
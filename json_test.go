@@ -0,0 +1,58 @@
+package jiesql
+
+import (
+	"testing"
+)
+
+func TestJSON(t *testing.T) {
+	mb := NewMemoryBackend()
+	mustRun(t, mb, `CREATE TABLE events (id INT, payload JSON);
+		INSERT INTO events VALUES (1, '{"type": "click", "user": {"id": 7, "tags": ["a", "b"]}}');
+		INSERT INTO events VALUES (2, '{"type": "view", "user": null}');
+		INSERT INTO events VALUES (3, '[1, "two", {"three": 3}]');
+		INSERT INTO events VALUES (4, null);`)
+
+	expectRows(t, mb, "SELECT id, payload ->> 'type' FROM events", [][]string{
+		{"1", "click"},
+		{"2", "view"},
+		{"3", "null"},
+		{"4", "null"},
+	})
+
+	tests := []struct {
+		source   string
+		expected string
+	}{
+		{`SELECT payload -> 'user' -> 'id' FROM events WHERE id = 1`, "7"},
+		{`SELECT payload -> 'user' -> 'tags' FROM events WHERE id = 1`, `["a", "b"]`},
+		{`SELECT payload -> 'user' -> 'tags' ->> 0 FROM events WHERE id = 1`, "a"},
+		{`SELECT payload -> 'type' FROM events WHERE id = 1`, `"click"`},
+		// JSON 的 null 用 -> 取出来还是 JSON, 用 ->> 取出来是 SQL NULL
+		{`SELECT payload -> 'user' FROM events WHERE id = 2`, "null"},
+		{`SELECT payload ->> 'user' = 'null' FROM events WHERE id = 2`, "null"},
+		{`SELECT payload -> 1 FROM events WHERE id = 3`, `"two"`},
+		{`SELECT payload ->> -1 FROM events WHERE id = 3`, `{"three": 3}`},
+		{`SELECT payload -> 5 FROM events WHERE id = 3`, "null"},
+		{`SELECT payload -> 'type' FROM events WHERE id = 3`, "null"},
+		{`SELECT '{"a": {"b": 1}}' -> 'a' ->> 'b'`, "1"},
+		{`SELECT json_extract_path(payload, 'user', 'tags', '1') FROM events WHERE id = 1`, `"b"`},
+		{`SELECT json_extract_path_text(payload, 'user', 'tags', '1') FROM events WHERE id = 1`, "b"},
+		{`SELECT json_extract_path(payload, 'missing') FROM events WHERE id = 1`, "null"},
+		{`SELECT json_extract_path_text('{"a": 1}', 'a')`, "1"},
+		{`SELECT json_extract_path_text('{"a": 1}', null)`, "null"},
+	}
+
+	for _, test := range tests {
+		expectValue(t, mb, test.source, test.expected)
+	}
+
+	expectRows(t, mb, "SELECT id FROM events WHERE payload ->> 'type' = 'click'", [][]string{{"1"}})
+	expectRows(t, mb, "SELECT id FROM events WHERE (payload -> 'user' ->> 'id')::int > 5", [][]string{{"1"}})
+
+	expectError(t, mb, "INSERT INTO events VALUES (5, '{bad')", ErrInvalidDatatype)
+	expectError(t, mb, "SELECT '{bad'::json", ErrInvalidValue)
+	expectError(t, mb, "SELECT json_extract_path_text('{bad', 'a')", ErrInvalidValue)
+	expectError(t, mb, "SELECT id -> 'a' FROM events", ErrInvalidOperands)
+	expectError(t, mb, "SELECT payload -> true FROM events", ErrInvalidOperands)
+	expectError(t, mb, "SELECT json_extract_path(1, 'a')", ErrFunctionDoesNotExist)
+}
@@ -2,6 +2,7 @@ package jiesql
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 )
 
@@ -37,26 +38,54 @@ const (
 	onKeyword         keyword = "on"
 	primarykeyKeyword keyword = "primary key"
 	nullKeyword       keyword = "null"
+	notKeyword        keyword = "not"
+	likeKeyword       keyword = "like"
+	ilikeKeyword      keyword = "ilike"
+	escapeKeyword     keyword = "escape"
+	inKeyword         keyword = "in"
+	betweenKeyword    keyword = "between"
+	castKeyword       keyword = "cast"
+	arrayKeyword      keyword = "array"
+	anyKeyword        keyword = "any"
+	allKeyword        keyword = "all"
+	defaultKeyword    keyword = "default"
+	updateKeyword     keyword = "update"
+	setKeyword        keyword = "set"
+	checkKeyword      keyword = "check"
+	constraintKeyword keyword = "constraint"
+	referencesKeyword keyword = "references"
+	foreignkeyKeyword keyword = "foreign key"
+	deleteKeyword     keyword = "delete"
+	alterKeyword      keyword = "alter"
+	withKeyword       keyword = "with"
+	unionKeyword      keyword = "union"
 )
 
 // for storing SQL syntax
 type symbol string
 
 const (
-	semicolonSymbol  symbol = ";"
-	asteriskSymbol   symbol = "*"
-	commaSymbol      symbol = ","
-	leftParenSymbol  symbol = "("
-	rightParenSymbol symbol = ")"
-	eqSymbol         symbol = "="
-	neqSymbol        symbol = "<>"
-	neqSymbol2       symbol = "!="
-	concatSymbol     symbol = "||"
-	plusSymbol       symbol = "+"
-	ltSymbol         symbol = "<"
-	lteSymbol        symbol = "<="
-	gtSymbol         symbol = ">"
-	gteSymbol        symbol = ">="
+	semicolonSymbol     symbol = ";"
+	asteriskSymbol      symbol = "*"
+	commaSymbol         symbol = ","
+	leftParenSymbol     symbol = "("
+	rightParenSymbol    symbol = ")"
+	eqSymbol            symbol = "="
+	neqSymbol           symbol = "<>"
+	neqSymbol2          symbol = "!="
+	concatSymbol        symbol = "||"
+	plusSymbol          symbol = "+"
+	minusSymbol         symbol = "-"
+	slashSymbol         symbol = "/"
+	ltSymbol            symbol = "<"
+	lteSymbol           symbol = "<="
+	gtSymbol            symbol = ">"
+	gteSymbol           symbol = ">="
+	castSymbol          symbol = "::"
+	jsonArrowSymbol     symbol = "->"
+	jsonArrowTextSymbol symbol = "->>"
+	leftBracketSymbol   symbol = "["
+	rightBracketSymbol  symbol = "]"
 )
 
 type tokenKind uint
@@ -69,6 +98,10 @@ const (
 	numericKind
 	boolKind
 	nullKind
+	// X'DEADBEEF' 形式的十六进制字节串, value 只保存引号里的十六进制数字
+	byteaKind
+	// 预处理语句的参数 $n 或 ?, value 是从 1 开始的编号, ? 按出现的顺序编号
+	parameterKind
 )
 
 type token struct {
@@ -81,39 +114,75 @@ func (t token) bindingPower() uint {
 	switch t.kind {
 	case keywordKind:
 		switch keyword(t.value) {
-		case andKeyword:
-			fallthrough
 		case orKeyword:
 			return 1
+
+		case andKeyword:
+			return 2
+
+		// 前缀 NOT 比 AND 结合得紧, 比比较运算符松
+		case notKeyword:
+			return 2
+
+		// LIKE, IN, BETWEEN 与比较运算符同级
+		case likeKeyword:
+			fallthrough
+		case ilikeKeyword:
+			fallthrough
+		case inKeyword:
+			fallthrough
+		case betweenKeyword:
+			return 3
 		}
 	case symbolKind:
 		switch symbol(t.value) {
 		case eqSymbol:
 			fallthrough
 		case neqSymbol:
-			return 2
+			return 3
 
 		case ltSymbol:
 			fallthrough
 		case gtSymbol:
-			return 3
+			return 4
 
 		// For some reason these are grouped separately
 		case lteSymbol:
 			fallthrough
 		case gteSymbol:
-			return 4
+			return 5
 
+		// 和 || 一样, -> 和 ->> 比比较运算符结合得紧
+		case jsonArrowSymbol:
+			fallthrough
+		case jsonArrowTextSymbol:
+			fallthrough
 		case concatSymbol:
 			fallthrough
 		case plusSymbol:
-			return 5
+			fallthrough
+		case minusSymbol:
+			return 6
+
+		case asteriskSymbol:
+			fallthrough
+		case slashSymbol:
+			return 7
+
+		// 后缀的 ::type 和下标 [i] 结合得最紧
+		case castSymbol:
+			fallthrough
+		case leftBracketSymbol:
+			return 10
 		}
 	}
 
 	return 0
 }
 
+// 前缀负号比所有二元算术运算符结合得紧
+const unaryMinusBindingPower uint = 8
+
 func (t *token) equals(other *token) bool {
 	return t.value == other.value && t.kind == other.kind
 }
@@ -202,11 +271,18 @@ func lexSymbol(source string, ic cursor) (*token, cursor, bool) {
 		gteSymbol,
 		concatSymbol,
 		plusSymbol,
+		minusSymbol,
+		slashSymbol,
 		commaSymbol,
 		leftParenSymbol,
 		rightParenSymbol,
 		semicolonSymbol,
 		asteriskSymbol,
+		castSymbol,
+		jsonArrowSymbol,
+		jsonArrowTextSymbol,
+		leftBracketSymbol,
+		rightBracketSymbol,
 	}
 
 	var options []string
@@ -261,6 +337,27 @@ func lexKeyword(source string, ic cursor) (*token, cursor, bool) {
 		onKeyword,
 		primarykeyKeyword,
 		nullKeyword,
+		notKeyword,
+		likeKeyword,
+		ilikeKeyword,
+		escapeKeyword,
+		inKeyword,
+		betweenKeyword,
+		castKeyword,
+		arrayKeyword,
+		anyKeyword,
+		allKeyword,
+		defaultKeyword,
+		updateKeyword,
+		setKeyword,
+		checkKeyword,
+		constraintKeyword,
+		referencesKeyword,
+		foreignkeyKeyword,
+		deleteKeyword,
+		alterKeyword,
+		withKeyword,
+		unionKeyword,
 	}
 
 	var options []string
@@ -273,7 +370,14 @@ func lexKeyword(source string, ic cursor) (*token, cursor, bool) {
 		return nil, ic, false
 	}
 
-	cur.pointer = ic.pointer + uint(len(match))
+	// Keywords must end at a word boundary, otherwise `internal` would
+	// be split into `int` and `ernal`
+	end := ic.pointer + uint(len(match))
+	if end < uint(len(source)) && isIdentifierChar(source[end]) {
+		return nil, ic, false
+	}
+
+	cur.pointer = end
 	cur.loc.col = ic.loc.col + uint(len(match))
 
 	kind := keywordKind
@@ -417,9 +521,7 @@ func lexIdentifier(source string, ic cursor) (*token, cursor, bool) {
 	cur := ic
 
 	c := source[cur.pointer]
-	// Other characters count too, big ignoring non-ascii for now
-	isAlphabetical := (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z')
-	if !isAlphabetical {
+	if !isAlphabetical(c) {
 		return nil, ic, false
 	}
 	cur.pointer++
@@ -429,10 +531,14 @@ func lexIdentifier(source string, ic cursor) (*token, cursor, bool) {
 	for ; cur.pointer < uint(len(source)); cur.pointer++ {
 		c = source[cur.pointer]
 
-		// Other characters count too, big ignoring non-ascii for now
-		isAlphabetical := (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z')
-		isNumeric := c >= '0' && c <= '9'
-		if isAlphabetical || isNumeric || c == '$' || c == '_' {
+		if isIdentifierChar(c) {
+			value = append(value, c)
+			cur.loc.col++
+			continue
+		}
+
+		// 带表名的列名 table.column 作为一个标识符
+		if c == '.' && cur.pointer+1 < uint(len(source)) && isAlphabetical(source[cur.pointer+1]) {
 			value = append(value, c)
 			cur.loc.col++
 			continue
@@ -453,10 +559,72 @@ func lexIdentifier(source string, ic cursor) (*token, cursor, bool) {
 	}, cur, true
 }
 
+// Other characters count too, big ignoring non-ascii for now
+func isAlphabetical(c byte) bool {
+	return (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z')
+}
+
+func isIdentifierChar(c byte) bool {
+	isNumeric := c >= '0' && c <= '9'
+	return isAlphabetical(c) || isNumeric || c == '$' || c == '_'
+}
+
 func lexString(source string, ic cursor) (*token, cursor, bool) {
 	return lexCharacterDelimited(source, ic, '\'')
 }
 
+// lexBinaryString 识别 X'DEADBEEF' 形式的字节串, 必须在 lexIdentifier 之前执行
+func lexBinaryString(source string, ic cursor) (*token, cursor, bool) {
+	if c := source[ic.pointer]; c != 'x' && c != 'X' {
+		return nil, ic, false
+	}
+
+	cur := ic
+	cur.pointer++
+	cur.loc.col++
+
+	t, newCursor, ok := lexCharacterDelimited(source, cur, '\'')
+	if !ok {
+		return nil, ic, false
+	}
+
+	t.kind = byteaKind
+	t.loc = ic.loc
+	return t, newCursor, true
+}
+
+// lexParameter 识别 $n 和 ?, ? 的编号在 lex 里按顺序补上
+func lexParameter(source string, ic cursor) (*token, cursor, bool) {
+	cur := ic
+	switch source[cur.pointer] {
+	case '?':
+		cur.pointer++
+		cur.loc.col++
+		return &token{kind: parameterKind, loc: ic.loc}, cur, true
+	case '$':
+	default:
+		return nil, ic, false
+	}
+
+	cur.pointer++
+	cur.loc.col++
+	start := cur.pointer
+	for cur.pointer < uint(len(source)) && source[cur.pointer] >= '0' && source[cur.pointer] <= '9' {
+		cur.pointer++
+		cur.loc.col++
+	}
+
+	if cur.pointer == start || cur.pointer < uint(len(source)) && isIdentifierChar(source[cur.pointer]) {
+		return nil, ic, false
+	}
+
+	return &token{
+		value: source[start:cur.pointer],
+		kind:  parameterKind,
+		loc:   ic.loc,
+	}, cur, true
+}
+
 type lexer func(string, cursor) (*token, cursor, bool)
 
 // lex splits an input string into a list of tokens. This process
@@ -474,8 +642,8 @@ func lex(source string) ([]*token, error) {
 	cur := cursor{}
 
 lex:
-	for cur.pointer < uint(len(source))-1 {
-		lexers := []lexer{lexKeyword, lexSymbol, lexString, lexNumeric, lexIdentifier}
+	for cur.pointer < uint(len(source)) {
+		lexers := []lexer{lexKeyword, lexSymbol, lexString, lexNumeric, lexBinaryString, lexParameter, lexIdentifier}
 		for _, l := range lexers {
 			if token, newCursor, ok := l(source, cur); ok {
 				cur = newCursor
@@ -499,5 +667,28 @@ lex:
 		return nil, fmt.Errorf("Unable to lex token%s, at %d:%d", hint, cur.loc.line, cur.loc.col)
 	}
 
+	// ? 按出现的顺序编号为 $1, $2, ..., 混用两种写法时编号会对不上
+	positional, numbered := 0, false
+	for _, t := range tokens {
+		if t.kind != parameterKind {
+			continue
+		}
+
+		if t.value != "" {
+			numbered = true
+			if n, err := strconv.Atoi(t.value); err != nil || n < 1 {
+				return nil, fmt.Errorf("%w: $%s at %d:%d", ErrInvalidParameter, t.value, t.loc.line, t.loc.col)
+			}
+			continue
+		}
+
+		positional++
+		t.value = strconv.Itoa(positional)
+	}
+
+	if numbered && positional > 0 {
+		return nil, fmt.Errorf("%w: cannot mix $n and ? parameters", ErrInvalidParameter)
+	}
+
 	return tokens, nil
 }
@@ -4,7 +4,10 @@ import (
 	"bytes"
 	"encoding/binary"
 	"fmt"
+	"math"
 	"strconv"
+	"strings"
+	"time"
 )
 
 type column struct {
@@ -16,12 +19,35 @@ type column struct {
 type Results struct {
 	Columns []column
 	Rows    [][]Cell
+	// 产生结果的 MemoryBackend 的枚举类型, 输出枚举值时用
+	enums enumTypes
+}
+
+// Format 返回第 row 行第 col 列的文本形式, 和 PostgreSQL 的输出格式一致, NULL 返回 "null"
+func (r *Results) Format(row, col int) string {
+	cell := r.Rows[row][col]
+	if cell.IsNull() {
+		return "null"
+	}
+
+	mc, ok := cell.(MemoryCell)
+	if !ok {
+		return cell.AsText()
+	}
+
+	return formatCell(mc, r.Columns[col].Type, r.enums)
 }
 
 type Cell interface {
 	AsText() string
 	AsInt() int32
+	AsInt64() int64
 	AsBool() interface{}
+	AsFloat() float64
+	AsTime() time.Time
+	AsInterval() Interval
+	AsBytes() []byte
+	IsNull() bool
 }
 
 type MemoryCell []byte
@@ -32,9 +58,28 @@ const (
 	TextType ColumnType = iota
 	IntType
 	BoolType
+	FloatType
+	BigIntType
+	SmallIntType
+	DateType
+	TimestampType
+	IntervalType
+	NumericType
+	ByteaType
+	JsonType
+	UUIDType
+	TimeType
 )
 
 func (c ColumnType) String() string {
+	if isArrayType(c) {
+		return strings.TrimSuffix(elementType(c).String(), "Type") + "ArrayType"
+	}
+
+	if isEnumType(c) {
+		return "EnumType"
+	}
+
 	switch c {
 	case TextType:
 		return "TextType"
@@ -42,6 +87,28 @@ func (c ColumnType) String() string {
 		return "IntType"
 	case BoolType:
 		return "BoolType"
+	case FloatType:
+		return "FloatType"
+	case BigIntType:
+		return "BigIntType"
+	case SmallIntType:
+		return "SmallIntType"
+	case DateType:
+		return "DateType"
+	case TimestampType:
+		return "TimestampType"
+	case IntervalType:
+		return "IntervalType"
+	case NumericType:
+		return "NumericType"
+	case ByteaType:
+		return "ByteaType"
+	case JsonType:
+		return "JsonType"
+	case UUIDType:
+		return "UUIDType"
+	case TimeType:
+		return "TimeType"
 	default:
 		return "Error"
 	}
@@ -66,161 +133,775 @@ func (mc MemoryCell) AsInt() int32 {
 	return i
 }
 
+// AsInt64 读取任意宽度的整数, 宽度由 SMALLINT, INT, BIGINT 的存储长度决定
+func (mc MemoryCell) AsInt64() int64 {
+	switch len(mc) {
+	case 2:
+		return int64(int16(binary.BigEndian.Uint16(mc)))
+	case 4:
+		return int64(int32(binary.BigEndian.Uint32(mc)))
+	case 8:
+		return int64(binary.BigEndian.Uint64(mc))
+	}
+
+	panic(ErrInvalidCell)
+}
+
+func (mc MemoryCell) AsFloat() float64 {
+	var f float64
+	err := binary.Read(bytes.NewBuffer(mc), binary.BigEndian, &f)
+	if err != nil {
+		panic(err)
+	}
+
+	return f
+}
+
 func (mc MemoryCell) AsText() string {
 	return string(mc)
 }
 
+// AsBytes 返回 bytea 的原始字节, 返回的是副本
+func (mc MemoryCell) AsBytes() []byte {
+	return append([]byte{}, mc...)
+}
+
+func (mc MemoryCell) IsNull() bool {
+	return mc == nil
+}
+
+func boolCell(b bool) MemoryCell {
+	if b {
+		return MemoryCell{1}
+	}
+
+	return MemoryCell{0}
+}
+
+func intCell(i int32) MemoryCell {
+	buf := new(bytes.Buffer)
+	err := binary.Write(buf, binary.BigEndian, i)
+	if err != nil {
+		panic(err)
+	}
+
+	return MemoryCell(buf.Bytes())
+}
+
+// integerCell 按整数类型的宽度编码, 调用方需要先检查范围
+func integerCell(i int64, typ ColumnType) MemoryCell {
+	var v interface{}
+	switch typ {
+	case SmallIntType:
+		v = int16(i)
+	case BigIntType:
+		v = i
+	default:
+		v = int32(i)
+	}
+
+	buf := new(bytes.Buffer)
+	err := binary.Write(buf, binary.BigEndian, v)
+	if err != nil {
+		panic(err)
+	}
+
+	return MemoryCell(buf.Bytes())
+}
+
+func floatCell(f float64) MemoryCell {
+	buf := new(bytes.Buffer)
+	err := binary.Write(buf, binary.BigEndian, f)
+	if err != nil {
+		panic(err)
+	}
+
+	return MemoryCell(buf.Bytes())
+}
+
 type table struct {
-	columns     []string
-	columnTypes []ColumnType
-	rows        [][]MemoryCell
+	name            string
+	columns         []string
+	columnTypes     []ColumnType
+	columnModifiers []typeModifier
+	// 列的默认值和生成列的表达式, 没有时为 nil
+	columnDefaults  []*expression
+	columnGenerated []*expression
+	// GENERATED ALWAYS AS IDENTITY 的列, 只能取默认值
+	columnIdentity []bool
+	// SERIAL 和标识列拥有的序列, 删除列时一起删除, 其他列为空字符串
+	columnSequences []string
+	checks         []checkConstraint
+	foreignKeys    []foreignKeyConstraint
+	rows           [][]MemoryCell
+	// SELECT 里窗口函数的结果保存在行尾的隐藏列里, 按函数调用找到对应的列
+	windowColumns map[*functionCall]int
+}
+
+type checkConstraint struct {
+	name  string
+	check expression
+}
+
+// columnIndex 返回列的位置, 不存在时返回 -1
+func (t *table) columnIndex(name string) int {
+	for i, column := range t.columns {
+		if column == name {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// resolveColumn 找到表达式里的列, 列名可以带表名或别名. 多表 FROM 的列名是 "别名.列",
+// 这时不带前缀的列名必须只对应一列
+func (t *table) resolveColumn(name string) (int, error) {
+	if i := t.columnIndex(name); i >= 0 {
+		return i, nil
+	}
+
+	if t.name != "" && strings.HasPrefix(name, t.name+".") {
+		if i := t.columnIndex(strings.TrimPrefix(name, t.name+".")); i >= 0 {
+			return i, nil
+		}
+	}
+
+	found := -1
+	if !strings.Contains(name, ".") {
+		for i, column := range t.columns {
+			if strings.HasSuffix(column, "."+name) {
+				if found >= 0 {
+					return -1, fmt.Errorf("%w: %s", ErrAmbiguousColumn, name)
+				}
+				found = i
+			}
+		}
+	}
+
+	if found < 0 {
+		return -1, ErrColumnDoesNotExist
+	}
+
+	return found, nil
+}
+
+// assign 把值转换成第 i 列的类型并检查类型参数. 无类型字面量按列类型解析, 其他值只允许赋值时的隐式转换
+func (t *table) assign(i int, cell MemoryCell, typ ColumnType, untyped bool, enums enumTypes) (MemoryCell, error) {
+	ctx := assignmentCoercion
+	if untyped {
+		ctx = explicitCoercion
+	}
+
+	cell, err := castCell(cell, typ, t.columnTypes[i], ctx, enums)
+	if err == ErrOutOfRange {
+		return nil, err
+	}
+	if err != nil {
+		return nil, ErrInvalidDatatype
+	}
+
+	cell, err = applyTypeModifier(cell, t.columnTypes[i], t.columnModifiers[i], assignmentCoercion)
+	if err == ErrValueTooLong {
+		return nil, fmt.Errorf("%w: column %s allows at most %d characters", err, t.columns[i], t.columnModifiers[i].length)
+	}
+
+	return cell, err
+}
+
+// columnDefault 计算第 i 列的默认值, 没有默认值时是 NULL
+func (mb *MemoryBackend) columnDefault(t *table, i int) (MemoryCell, error) {
+	exp := t.columnDefaults[i]
+	if exp == nil {
+		return nil, nil
+	}
+
+	cell, typ, err := mb.evaluateConstant(*exp)
+	if err != nil {
+		return nil, err
+	}
+
+	return t.assign(i, cell, typ, isUntypedLiteral(*exp), mb.enums)
+}
+
+// addConstraints 给表加上列约束和表约束. 和 PostgreSQL 一样, 没有名字的列约束叫 表名_列名_check,
+// 表约束叫 表名_check, 外键叫 表名_列名_fkey, 重名时在后面加数字
+func (mb *MemoryBackend) addConstraints(t *table, cols []*columnDefinition, constraints []*constraint) error {
+	type namedConstraint struct {
+		defaultName string
+		c           *constraint
+	}
+
+	var all []namedConstraint
+	for _, col := range cols {
+		for _, c := range col.constraints {
+			suffix := "_check"
+			if c.foreignKey != nil {
+				suffix = "_fkey"
+			}
+
+			all = append(all, namedConstraint{t.name + "_" + col.name.value + suffix, c})
+		}
+	}
+
+	for _, c := range constraints {
+		name := t.name + "_check"
+		if c.foreignKey != nil {
+			name = t.name
+			for _, column := range c.foreignKey.columns {
+				name += "_" + column.value
+			}
+			name += "_fkey"
+		}
+
+		all = append(all, namedConstraint{name, c})
+	}
+
+	// ALTER TABLE 加约束时不能和已有的约束重名
+	used := map[string]bool{}
+	for _, c := range t.checks {
+		used[c.name] = true
+	}
+	for _, fk := range t.foreignKeys {
+		used[fk.name] = true
+	}
+
+	for _, nc := range all {
+		if nc.c.name != nil {
+			if used[nc.c.name.value] {
+				return &ConstraintError{Constraint: nc.c.name.value, Err: ErrConstraintAlreadyExists}
+			}
+
+			used[nc.c.name.value] = true
+		}
+	}
+
+	for _, nc := range all {
+		name := ""
+		if nc.c.name != nil {
+			name = nc.c.name.value
+		} else {
+			name = nc.defaultName
+			for i := 1; used[name]; i++ {
+				name = fmt.Sprintf("%s%d", nc.defaultName, i)
+			}
+
+			used[name] = true
+		}
+
+		if nc.c.foreignKey != nil {
+			if err := mb.addForeignKey(t, name, nc.c.foreignKey); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		_, _, typ, err := mb.evaluateCell(t, nil, *nc.c.check)
+		if err != nil {
+			return err
+		}
+
+		if typ != BoolType {
+			return ErrInvalidCondition
+		}
+
+		t.checks = append(t.checks, checkConstraint{name: name, check: *nc.c.check})
+	}
+
+	return nil
+}
+
+// checkConstraints 检查一行是否满足所有 CHECK 约束, 和 PostgreSQL 一样结果为 NULL 时也算满足
+func (mb *MemoryBackend) checkConstraints(t *table, row []MemoryCell) error {
+	for _, c := range t.checks {
+		cell, _, _, err := mb.evaluateCell(t, row, c.check)
+		if err != nil {
+			return err
+		}
+
+		if cell != nil && cell.AsBool() == false {
+			return &ConstraintError{Constraint: c.name, Err: ErrViolatesCheckConstraint}
+		}
+	}
+
+	return nil
+}
+
+// computeGenerated 在插入或更新后的行上计算所有生成列
+func (mb *MemoryBackend) computeGenerated(t *table, row []MemoryCell) error {
+	for i, exp := range t.columnGenerated {
+		if exp == nil {
+			continue
+		}
+
+		cell, _, typ, err := mb.evaluateCell(t, row, *exp)
+		if err != nil {
+			return err
+		}
+
+		row[i], err = t.assign(i, cell, typ, isUntypedLiteral(*exp), mb.enums)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
 
 type MemoryBackend struct {
 	tables map[string]*table
+	// CREATE TYPE 创建的类型, 枚举类型的标签在 enums 里, nextEnumType 是下一个可用的类型号
+	types        map[string]ColumnType
+	enums        enumTypes
+	nextEnumType ColumnType
+	// CREATE SEQUENCE 创建的序列和 SERIAL, 标识列自动创建的序列
+	sequences map[string]*sequence
+	// 视图和表共用名字空间
+	views map[string]*view
+	// 物化视图的定义, 结果作为普通的表保存在 tables 里
+	materializedViews map[string]*view
+
+	// Clock 是 NOW() 使用的时钟, 测试时可以换成固定的时间
+	Clock func() time.Time
+	// MaxRecursion 限制 WITH RECURSIVE 的迭代次数, 防止递归查询不结束
+	MaxRecursion int
 }
 
 func NewMemoryBackend() *MemoryBackend {
 	return &MemoryBackend{
-		tables: map[string]*table{},
+		tables:            map[string]*table{},
+		types:             map[string]ColumnType{},
+		enums:             enumTypes{},
+		nextEnumType:      firstEnumType,
+		sequences:         map[string]*sequence{},
+		views:             map[string]*view{},
+		materializedViews: map[string]*view{},
+		Clock:             time.Now,
+		MaxRecursion:      1000,
 	}
 }
 
 func (mb *MemoryBackend) CreateTable(crt *CreateTableStatement) error {
-	t := table{}
-	mb.tables[crt.name.value] = &t
-	if crt.cols == nil {
+	if _, ok := mb.views[crt.name.value]; ok {
+		return ErrTableAlreadyExists
+	}
 
+	// 物化视图也存放在 mb.tables 里
+	if _, ok := mb.tables[crt.name.value]; ok {
+		return ErrTableAlreadyExists
+	}
+
+	t := table{name: crt.name.value}
+	if crt.cols == nil {
+		mb.tables[crt.name.value] = &t
 		return nil
 	}
 
+	sequences := map[string]*sequence{}
 	for _, col := range *crt.cols {
-		t.columns = append(t.columns, col.name.value)
+		if err := mb.addColumn(&t, col, sequences); err != nil {
+			return err
+		}
+	}
 
-		var dt ColumnType
-		switch col.datatype.value {
-		case "int":
-			dt = IntType
-		case "text":
-			dt = TextType
-		default:
-			return ErrInvalidDatatype
+	for i, col := range *crt.cols {
+		if err := mb.checkColumnExpression(&t, i, col); err != nil {
+			return err
 		}
+	}
 
-		t.columnTypes = append(t.columnTypes, dt)
+	if err := mb.addConstraints(&t, *crt.cols, crt.constraints); err != nil {
+		return err
 	}
 
+	// 所有列都合法后才注册, 避免留下建了一半的表
+	for name, s := range sequences {
+		mb.sequences[name] = s
+	}
+
+	mb.tables[crt.name.value] = &t
 	return nil
 }
 
-func (mb *MemoryBackend) Insert(inst *InsertStatement) error {
-	table, ok := mb.tables[inst.table.value]
-	if !ok {
-		return ErrTableDoesNotExist
+// addColumn 在表的最后加上一列, SERIAL 和标识列需要的序列放进 sequences, 由调用方在成功后注册
+func (mb *MemoryBackend) addColumn(t *table, col *columnDefinition, sequences map[string]*sequence) error {
+	if t.columnIndex(col.name.value) >= 0 {
+		return fmt.Errorf("%w: %s", ErrDuplicateColumn, col.name.value)
 	}
 
-	if inst.values == nil {
+	// SERIAL 是默认值为 nextval 的整数列的简写, 不能带参数, 也不能是数组
+	dt, serial := serialTypes[col.datatype.name.value]
+	serial = serial && !col.datatype.array && len(col.datatype.modifiers) == 0
+
+	mod := typeModifier{}
+	if !serial {
+		var err error
+		dt, mod, err = mb.resolveDataType(col.datatype)
+		if err != nil {
+			return err
+		}
+	}
+
+	defaultValue, owned := col.defaultValue, ""
+	if serial || col.identity != nil {
+		if col.defaultValue != nil || col.generated != nil || (serial && col.identity != nil) {
+			return fmt.Errorf("%w: column %s has both a default and an identity", ErrInvalidDatatype, col.name.value)
+		}
+
+		if !isIntegerType(dt) {
+			return fmt.Errorf("%w: identity column %s must be smallint, integer or bigint", ErrInvalidDatatype, col.name.value)
+		}
+
+		options := sequenceOptions{}
+		if col.identity != nil {
+			options = col.identity.options
+		}
+
+		// 和 PostgreSQL 一样, 序列叫 表名_列名_seq
+		name := t.name + "_" + col.name.value + "_seq"
+		if _, ok := mb.sequences[name]; ok {
+			return fmt.Errorf("%w: %s", ErrSequenceAlreadyExists, name)
+		}
+
+		s, err := newSequence(name, options, dt)
+		if err != nil {
+			return err
+		}
+
+		sequences[name] = s
+		defaultValue, owned = nextvalExpression(name), name
+	}
+
+	t.columns = append(t.columns, col.name.value)
+	t.columnTypes = append(t.columnTypes, dt)
+	t.columnModifiers = append(t.columnModifiers, mod)
+	t.columnDefaults = append(t.columnDefaults, defaultValue)
+	t.columnGenerated = append(t.columnGenerated, col.generated)
+	t.columnIdentity = append(t.columnIdentity, col.identity != nil && col.identity.always)
+	t.columnSequences = append(t.columnSequences, owned)
+	return nil
+}
+
+// checkColumnExpression 检查第 i 列的默认值或生成表达式能赋值给这一列
+func (mb *MemoryBackend) checkColumnExpression(t *table, i int, col *columnDefinition) error {
+	// 默认值不能引用任何列
+	exp, columns := col.defaultValue, &table{}
+	if col.generated != nil {
+		if exp != nil {
+			return fmt.Errorf("%w: column %s has both a default and a generation expression", ErrInvalidDatatype, col.name.value)
+		}
+
+		// 生成列只能引用普通列, 所以检查表达式时把生成列藏起来
+		plain := *t
+		plain.columns = append([]string{}, t.columns...)
+		for j, exp := range t.columnGenerated {
+			if exp != nil {
+				plain.columns[j] = ""
+			}
+		}
+
+		exp, columns = col.generated, &plain
+	}
+
+	if exp == nil {
 		return nil
 	}
 
-	row := []MemoryCell{}
+	_, _, typ, err := mb.evaluateCell(columns, nil, *exp)
+	if err != nil {
+		return err
+	}
 
-	if len(*inst.values) != len(table.columns) {
-		return ErrMissingValues
+	ctx := assignmentCoercion
+	if isUntypedLiteral(*exp) {
+		ctx = explicitCoercion
 	}
 
-	for _, value := range *inst.values {
-		if value.kind != literalKind {
-			fmt.Println("Skipping non-literal.")
-			continue
+	if !canCoerce(typ, t.columnTypes[i], ctx) {
+		return fmt.Errorf("%w: column %s is of type %s but expression is of type %s", ErrInvalidDatatype, col.name.value, t.columnTypes[i], typ)
+	}
+
+	// 字符串字面量要按列类型解析才知道是否合法, 例如 INT DEFAULT 'x', 和 PostgreSQL 一样建表时就报错
+	if isUntypedLiteral(*exp) {
+		cell, typ, err := mb.evaluateConstant(*exp)
+		if err != nil {
+			return err
 		}
 
-		row = append(row, mb.tokenToCell(value.literal))
+		if _, err := t.assign(i, cell, typ, true, mb.enums); err != nil {
+			return fmt.Errorf("%w: invalid default for column %s", err, col.name.value)
+		}
 	}
 
-	table.rows = append(table.rows, row)
 	return nil
 }
 
-// insert的辅助函数
-func (mb *MemoryBackend) tokenToCell(t *token) MemoryCell {
-	if t.kind == numericKind {
-		buf := new(bytes.Buffer)
-		i, err := strconv.Atoi(t.value)
+func (mb *MemoryBackend) Insert(inst *InsertStatement) error {
+	table, err := mb.writableTable(inst.table.value)
+	if err != nil {
+		return err
+	}
+
+	if inst.values == nil {
+		return nil
+	}
+
+	// 没有列名时按表的列顺序赋值, 值可以比列少
+	targets := []int{}
+	if inst.columns == nil {
+		if len(*inst.values) > len(table.columns) {
+			return ErrMissingValues
+		}
+
+		for i := range *inst.values {
+			targets = append(targets, i)
+		}
+	} else {
+		for _, column := range inst.columns {
+			i := table.columnIndex(column.value)
+			if i < 0 {
+				return ErrColumnDoesNotExist
+			}
+
+			for _, j := range targets {
+				if i == j {
+					return ErrDuplicateColumn
+				}
+			}
+
+			targets = append(targets, i)
+		}
+
+		if len(targets) != len(*inst.values) {
+			return ErrMissingValues
+		}
+	}
+
+	row := make([]MemoryCell, len(table.columns))
+	assigned := make([]bool, len(table.columns))
+	for k, value := range *inst.values {
+		i := targets[k]
+		if value.kind == defaultKind {
+			continue
+		}
+
+		if table.columnGenerated[i] != nil || table.columnIdentity[i] {
+			return fmt.Errorf("%w: %s", ErrGeneratedColumn, table.columns[i])
+		}
+
+		// VALUES 中不能引用列
+		cell, typ, err := mb.evaluateConstant(*value)
 		if err != nil {
-			panic(err)
+			return err
 		}
 
-		err = binary.Write(buf, binary.BigEndian, int32(i))
+		row[i], err = table.assign(i, cell, typ, isUntypedLiteral(*value), mb.enums)
 		if err != nil {
-			panic(err)
+			return err
 		}
-		return MemoryCell(buf.Bytes())
+
+		assigned[i] = true
 	}
 
-	if t.kind == stringKind {
-		return MemoryCell(t.value)
+	// 没有给出或者写了 DEFAULT 的列取默认值
+	for i := range row {
+		if assigned[i] || table.columnGenerated[i] != nil {
+			continue
+		}
+
+		cell, err := mb.columnDefault(table, i)
+		if err != nil {
+			return err
+		}
+
+		row[i] = cell
+	}
+
+	if err := mb.computeGenerated(table, row); err != nil {
+		return err
+	}
+
+	if err := mb.checkConstraints(table, row); err != nil {
+		return err
 	}
 
+	rows := append(table.rows[:len(table.rows):len(table.rows)], row)
+	err = mb.checkReferentialIntegrity(tableChanges{
+		table: {rows: rows, changed: [][]MemoryCell{row}},
+	})
+	if err != nil {
+		return err
+	}
+
+	table.rows = rows
 	return nil
 }
 
-func (mb *MemoryBackend) Select(slct *SelectStatement) (*Results, error) {
-	table, ok := mb.tables[slct.from.value]
-	if !ok {
-		return nil, ErrTableDoesNotExist
+func (mb *MemoryBackend) Update(upd *UpdateStatement) error {
+	table, err := mb.writableTable(upd.table.value)
+	if err != nil {
+		return err
 	}
 
-	results := [][]Cell{}
-	columns := []column{}
+	targets := []int{}
+	for _, a := range upd.set {
+		i := table.columnIndex(a.column.value)
+		if i < 0 {
+			return ErrColumnDoesNotExist
+		}
+
+		for _, j := range targets {
+			if i == j {
+				return ErrDuplicateColumn
+			}
+		}
+
+		if (table.columnGenerated[i] != nil || table.columnIdentity[i]) && a.value.kind != defaultKind {
+			return fmt.Errorf("%w: %s", ErrGeneratedColumn, table.columns[i])
+		}
 
-	for i, row := range table.rows {
-		result := []Cell{}
-		isFirstRow := i == 0
+		targets = append(targets, i)
+	}
+
+	// 先算出所有新行再一起替换, 中途出错时表保持不变
+	updated := map[int][]MemoryCell{}
+	for r, row := range table.rows {
+		if upd.where != nil {
+			ok, err := mb.evaluateCondition(table, row, *upd.where)
+			if err != nil {
+				return err
+			}
 
-		for _, exp := range slct.item {
-			if exp.kind != literalKind {
-				// Unsupported, doesn't currently exist, ignore.
-				fmt.Println("Skipping non-literal expression.")
+			if !ok {
 				continue
 			}
+		}
 
-			lit := exp.literal
-			if lit.kind == identifierKind {
-				found := false
-				for i, tableCol := range table.columns {
-					if tableCol == lit.value {
-						if isFirstRow {
-							columns = append(columns, struct {
-								Type ColumnType
-								Name string
-							}{
-								Type: table.columnTypes[i],
-								Name: lit.value,
-							})
-						}
-
-						result = append(result, row[i])
-						found = true
-						break
-					}
-				}
+		newRow := append([]MemoryCell{}, row...)
+		for k, a := range upd.set {
+			i := targets[k]
+			if table.columnGenerated[i] != nil {
+				continue
+			}
 
-				if !found {
-					return nil, ErrColumnDoesNotExist
+			if a.value.kind == defaultKind {
+				cell, err := mb.columnDefault(table, i)
+				if err != nil {
+					return err
 				}
 
+				newRow[i] = cell
+				continue
+			}
+
+			// SET 右边看到的是更新前的行
+			cell, _, typ, err := mb.evaluateCell(table, row, a.value)
+			if err != nil {
+				return err
+			}
+
+			newRow[i], err = table.assign(i, cell, typ, isUntypedLiteral(a.value), mb.enums)
+			if err != nil {
+				return err
+			}
+		}
+
+		if err := mb.computeGenerated(table, newRow); err != nil {
+			return err
+		}
+
+		if err := mb.checkConstraints(table, newRow); err != nil {
+			return err
+		}
+
+		updated[r] = newRow
+	}
+
+	change := &tableChange{rows: append([][]MemoryCell{}, table.rows...)}
+	for r, row := range updated {
+		change.rows[r] = row
+		change.changed = append(change.changed, row)
+		change.removed = append(change.removed, table.rows[r])
+	}
+
+	if err := mb.checkReferentialIntegrity(tableChanges{table: change}); err != nil {
+		return err
+	}
+
+	table.rows = change.rows
+	return nil
+}
+
+func (mb *MemoryBackend) Delete(del *DeleteStatement) error {
+	table, err := mb.writableTable(del.table.value)
+	if err != nil {
+		return err
+	}
+
+	var rows []int
+	for r, row := range table.rows {
+		if del.where != nil {
+			ok, err := mb.evaluateCondition(table, row, *del.where)
+			if err != nil {
+				return err
+			}
+
+			if !ok {
 				continue
 			}
+		}
+
+		rows = append(rows, r)
+	}
+
+	changes, err := mb.planDelete(table, rows)
+	if err != nil {
+		return err
+	}
 
-			return nil, ErrColumnDoesNotExist
+	if err := mb.checkReferentialIntegrity(changes); err != nil {
+		return err
+	}
+
+	for t, change := range changes {
+		t.rows = change.rows
+	}
+
+	return nil
+}
+
+// insert的辅助函数
+func (mb *MemoryBackend) tokenToCell(t *token) MemoryCell {
+	if t.kind == stringKind {
+		return MemoryCell(t.value)
+	}
+
+	if t.kind == boolKind {
+		return boolCell(t.value == string(trueKeyword))
+	}
+
+	return nil
+}
+
+// numericToCell 解析数字字面量. 和 PostgreSQL 一样, 能放进 int 的整数是 int, 放不下的是 bigint,
+// 带小数点或指数以及超出 bigint 范围的都是精确的 numeric
+func numericToCell(value string) (MemoryCell, ColumnType, error) {
+	if !strings.ContainsAny(value, ".e") {
+		i, err := strconv.ParseInt(value, 10, 64)
+		if err == nil {
+			if i >= math.MinInt32 && i <= math.MaxInt32 {
+				return intCell(int32(i)), IntType, nil
+			}
+
+			return integerCell(i, BigIntType), BigIntType, nil
 		}
+	}
 
-		results = append(results, result)
+	d, err := parseDecimal(value)
+	if err != nil {
+		return nil, 0, err
 	}
 
-	return &Results{
-		Columns: columns,
-		Rows:    results,
-	}, nil
+	return decimalCell(d), NumericType, nil
 }
@@ -0,0 +1,151 @@
+package jiesql
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDefaultValues(t *testing.T) {
+	mb := NewMemoryBackend()
+	mb.Clock = func() time.Time {
+		return time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	}
+	mustRun(t, mb, `CREATE TABLE orders (
+		id INT,
+		status TEXT DEFAULT 'new',
+		qty INT DEFAULT 1 + 1,
+		note TEXT,
+		created TIMESTAMP DEFAULT now()
+	)`)
+
+	mustRun(t, mb, "INSERT INTO orders (id) VALUES (1)")
+	mustRun(t, mb, "INSERT INTO orders VALUES (2, DEFAULT, 5)")
+	mustRun(t, mb, "INSERT INTO orders (id, status, created) VALUES (3, null, DEFAULT)")
+	mustRun(t, mb, "INSERT INTO orders (qty, id) VALUES (DEFAULT, 4)")
+	expectRows(t, mb, "SELECT id, status, qty, note, created FROM orders", [][]string{
+		{"1", "new", "2", "null", "2026-01-02 03:04:05"},
+		{"2", "new", "5", "null", "2026-01-02 03:04:05"},
+		{"3", "null", "2", "null", "2026-01-02 03:04:05"},
+		{"4", "new", "2", "null", "2026-01-02 03:04:05"},
+	})
+
+	mustRun(t, mb, "UPDATE orders SET status = DEFAULT, qty = 7 WHERE id = 3")
+	expectRows(t, mb, "SELECT status, qty FROM orders WHERE id = 3", [][]string{{"new", "7"}})
+	// 没有默认值的列取 NULL
+	mustRun(t, mb, "UPDATE orders SET note = DEFAULT")
+	expectRows(t, mb, "SELECT id FROM orders WHERE note = 'x'", [][]string{})
+
+	expectError(t, mb, "SELECT DEFAULT", ErrDefaultNotAllowed)
+	expectError(t, mb, "INSERT INTO orders (id) VALUES (DEFAULT + 1)", nil)
+	expectError(t, mb, "CREATE TABLE bad (a INT DEFAULT 'x'::text)", ErrInvalidDatatype)
+	expectError(t, mb, "CREATE TABLE bad (a INT, b INT DEFAULT a)", ErrColumnDoesNotExist)
+	// 无类型的默认值建表时就按列类型解析
+	expectError(t, mb, "CREATE TABLE bad (id INT, a INT DEFAULT 'abc')", ErrInvalidDatatype)
+	expectError(t, mb, "CREATE TABLE bad (a VARCHAR(2) DEFAULT 'abc')", ErrValueTooLong)
+	expectError(t, mb, "ALTER TABLE orders ADD COLUMN flag BOOLEAN DEFAULT 'maybe'", ErrInvalidDatatype)
+	mustRun(t, mb, "CREATE TABLE parsed (id INT, a INT DEFAULT ' 7', b CHAR(3) DEFAULT 'x')")
+	mustRun(t, mb, "INSERT INTO parsed (id) VALUES (1)")
+	expectRows(t, mb, "SELECT a, b FROM parsed", [][]string{{"7", "x  "}})
+}
+
+func TestGeneratedColumns(t *testing.T) {
+	mb := NewMemoryBackend()
+	mustRun(t, mb, `CREATE TABLE items (
+		price INT,
+		qty INT,
+		total INT GENERATED ALWAYS AS (price * qty) STORED,
+		label TEXT GENERATED ALWAYS AS ('item ' || price::text) STORED
+	)`)
+
+	mustRun(t, mb, "INSERT INTO items (price, qty) VALUES (3, 4)")
+	mustRun(t, mb, "INSERT INTO items VALUES (5, null, DEFAULT, DEFAULT)")
+	expectRows(t, mb, "SELECT price, qty, total, label FROM items", [][]string{
+		{"3", "4", "12", "item 3"},
+		{"5", "null", "null", "item 5"},
+	})
+
+	// 更新时重新计算
+	mustRun(t, mb, "UPDATE items SET qty = 2 WHERE price = 5")
+	expectRows(t, mb, "SELECT total FROM items WHERE price = 5", [][]string{{"10"}})
+	mustRun(t, mb, "UPDATE items SET total = DEFAULT")
+
+	expectError(t, mb, "INSERT INTO items VALUES (1, 1, 1)", ErrGeneratedColumn)
+	expectError(t, mb, "INSERT INTO items (price, total) VALUES (1, 1)", ErrGeneratedColumn)
+	expectError(t, mb, "UPDATE items SET total = 1", ErrGeneratedColumn)
+	// 计算出错时整行都不插入
+	expectError(t, mb, "INSERT INTO items (price, qty) VALUES (2147483647, 2)", ErrOutOfRange)
+	expectRows(t, mb, "SELECT price FROM items", [][]string{{"3"}, {"5"}})
+
+	// 生成列不能引用其他生成列, 也不能同时有默认值
+	expectError(t, mb, "CREATE TABLE bad (a INT, b INT GENERATED ALWAYS AS (a) STORED, c INT GENERATED ALWAYS AS (b) STORED)", ErrColumnDoesNotExist)
+	expectError(t, mb, "CREATE TABLE bad (a INT DEFAULT 1 GENERATED ALWAYS AS (2) STORED)", ErrInvalidDatatype)
+	expectError(t, mb, "CREATE TABLE bad (a INT, b BOOLEAN GENERATED ALWAYS AS (a + 1) STORED)", ErrInvalidDatatype)
+}
+
+func TestCheckConstraints(t *testing.T) {
+	mb := NewMemoryBackend()
+	mustRun(t, mb, `CREATE TABLE stock (
+		sku TEXT CHECK (sku <> ''),
+		qty INT CHECK (qty >= 0),
+		reserved INT,
+		CHECK (reserved <= qty),
+		CONSTRAINT small CHECK (qty < 1000)
+	)`)
+
+	mustRun(t, mb, "INSERT INTO stock VALUES ('a', 5, 2)")
+	// 结果是 NULL 时不算违反约束
+	mustRun(t, mb, "INSERT INTO stock VALUES ('b', null, 3)")
+	mustRun(t, mb, "INSERT INTO stock VALUES (null, 0, null)")
+
+	tests := []struct {
+		source     string
+		constraint string
+	}{
+		{"INSERT INTO stock VALUES ('c', -1, 0)", "stock_qty_check"},
+		{"INSERT INTO stock VALUES ('', 1, 0)", "stock_sku_check"},
+		{"INSERT INTO stock VALUES ('c', 1, 2)", "stock_check"},
+		{"INSERT INTO stock VALUES ('c', 1000, 0)", "small"},
+		{"UPDATE stock SET qty = qty - 10 WHERE sku = 'a'", "stock_qty_check"},
+		{"UPDATE stock SET reserved = 9 WHERE sku = 'a'", "stock_check"},
+	}
+
+	for _, test := range tests {
+		_, err := runSQL(mb, test.source)
+		cerr, ok := err.(*ConstraintError)
+		if !ok || cerr.Constraint != test.constraint || cerr.Err != ErrViolatesCheckConstraint {
+			t.Errorf("%s: expected check constraint %s to fail, got %v", test.source, test.constraint, err)
+		}
+	}
+
+	// 失败的 UPDATE 不改变任何行
+	expectRows(t, mb, "SELECT sku, qty, reserved FROM stock", [][]string{
+		{"a", "5", "2"},
+		{"b", "null", "3"},
+		{"null", "0", "null"},
+	})
+
+	expectError(t, mb, "CREATE TABLE bad (a INT CHECK (a + 1))", ErrInvalidCondition)
+	expectError(t, mb, "CREATE TABLE bad (a INT CHECK (b > 0))", ErrColumnDoesNotExist)
+	expectError(t, mb, "CREATE TABLE bad (a INT, CONSTRAINT c CHECK (a > 0), CONSTRAINT c CHECK (a < 9))", ErrConstraintAlreadyExists)
+}
+
+func TestCreateTableAlreadyExists(t *testing.T) {
+	mb := NewMemoryBackend()
+	mustRun(t, mb, `CREATE TABLE p (id INT);
+		CREATE TABLE c (p_id INT REFERENCES p (id));
+		INSERT INTO p VALUES (1);
+		INSERT INTO c VALUES (1);
+		CREATE MATERIALIZED VIEW m AS SELECT id FROM p;`)
+
+	// 被外键引用的表不能被悄悄替换掉
+	expectError(t, mb, "CREATE TABLE p (id INT)", ErrTableAlreadyExists)
+	expectError(t, mb, "CREATE TABLE c (a INT)", ErrTableAlreadyExists)
+	expectError(t, mb, "CREATE TABLE m (a INT)", ErrTableAlreadyExists)
+	expectRows(t, mb, "SELECT id FROM p", [][]string{{"1"}})
+	expectRows(t, mb, "SELECT id FROM m", [][]string{{"1"}})
+	expectError(t, mb, "INSERT INTO c VALUES (2)", ErrViolatesForeignKey)
+
+	mustRun(t, mb, "DROP MATERIALIZED VIEW m")
+	mustRun(t, mb, "CREATE TABLE m (a INT)")
+	expectColumns(t, mb, "SELECT a FROM m", []column{{Name: "a", Type: IntType}})
+}
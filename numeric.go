@@ -0,0 +1,281 @@
+package jiesql
+
+import (
+	"encoding/binary"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// NUMERIC 的值是 unscaled * 10^-scale, 在 MemoryCell 里依次存放 4 字节的 scale, 1 字节的符号
+// (0 为正, 1 为负) 和绝对值的大端字节序列, 所以精度没有上限.
+
+// maxNumericScale 限制小数位数, 避免 1e-100000 这样的输入占用过多内存
+const maxNumericScale = 1000
+
+// divisionMinScale 是除法结果至少保留的小数位数
+const divisionMinScale = 16
+
+type decimal struct {
+	unscaled *big.Int
+	scale    int32
+}
+
+var (
+	bigTen = big.NewInt(10)
+	bigOne = big.NewInt(1)
+)
+
+func pow10(n int32) *big.Int {
+	return new(big.Int).Exp(bigTen, big.NewInt(int64(n)), nil)
+}
+
+func decimalCell(d decimal) MemoryCell {
+	mag := d.unscaled.Bytes()
+	cell := make(MemoryCell, 5+len(mag))
+	binary.BigEndian.PutUint32(cell, uint32(d.scale))
+	if d.unscaled.Sign() < 0 {
+		cell[4] = 1
+	}
+	copy(cell[5:], mag)
+
+	return cell
+}
+
+func (mc MemoryCell) asDecimal() decimal {
+	if len(mc) < 5 {
+		panic(ErrInvalidCell)
+	}
+
+	unscaled := new(big.Int).SetBytes(mc[5:])
+	if mc[4] == 1 {
+		unscaled.Neg(unscaled)
+	}
+
+	return decimal{
+		unscaled: unscaled,
+		scale:    int32(binary.BigEndian.Uint32(mc)),
+	}
+}
+
+// parseDecimal 解析 123, -1.50, .5, 1.5e-3 这样的文本, 保留输入的小数位数
+func parseDecimal(s string) (decimal, error) {
+	s = strings.TrimSpace(s)
+
+	exponent := int64(0)
+	if i := strings.IndexAny(s, "eE"); i >= 0 {
+		e, err := strconv.ParseInt(s[i+1:], 10, 32)
+		if err != nil || e > maxNumericScale || e < -maxNumericScale {
+			return decimal{}, ErrInvalidValue
+		}
+
+		exponent = e
+		s = s[:i]
+	}
+
+	negative := false
+	if strings.HasPrefix(s, "-") || strings.HasPrefix(s, "+") {
+		negative = s[0] == '-'
+		s = s[1:]
+	}
+
+	intPart, fracPart := s, ""
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		intPart, fracPart = s[:i], s[i+1:]
+	}
+
+	digits := intPart + fracPart
+	if digits == "" || strings.Trim(digits, "0123456789") != "" {
+		return decimal{}, ErrInvalidValue
+	}
+
+	unscaled, _ := new(big.Int).SetString(digits, 10)
+	if negative {
+		unscaled.Neg(unscaled)
+	}
+
+	scale := int64(len(fracPart)) - exponent
+	if scale < 0 {
+		unscaled.Mul(unscaled, pow10(int32(-scale)))
+		scale = 0
+	}
+	if scale > maxNumericScale {
+		return decimal{}, ErrOutOfRange
+	}
+
+	return decimal{unscaled: unscaled, scale: int32(scale)}, nil
+}
+
+func decimalFromInt(i int64) decimal {
+	return decimal{unscaled: big.NewInt(i)}
+}
+
+func decimalFromFloat(f float64) (decimal, error) {
+	s := strconv.FormatFloat(f, 'f', -1, 64)
+	d, err := parseDecimal(s)
+	if err != nil {
+		// NaN 和 Infinity 不能表示成 NUMERIC
+		return decimal{}, ErrOutOfRange
+	}
+
+	return d, nil
+}
+
+// String 按 scale 输出, 保留末尾的 0, 例如 NUMERIC(10, 2) 的 1.5 输出为 1.50
+func (d decimal) String() string {
+	abs := new(big.Int).Abs(d.unscaled).String()
+	if d.scale > 0 {
+		if pad := int(d.scale) + 1 - len(abs); pad > 0 {
+			abs = strings.Repeat("0", pad) + abs
+		}
+
+		abs = abs[:len(abs)-int(d.scale)] + "." + abs[len(abs)-int(d.scale):]
+	}
+
+	if d.unscaled.Sign() < 0 {
+		return "-" + abs
+	}
+
+	return abs
+}
+
+func (d decimal) float64() float64 {
+	f, _ := new(big.Rat).SetFrac(d.unscaled, pow10(d.scale)).Float64()
+	return f
+}
+
+// divRound 计算 a / b 并四舍五入 (远离零的方向)
+func divRound(a, b *big.Int) *big.Int {
+	q, r := new(big.Int).QuoRem(a, b, new(big.Int))
+	if r.Sign() == 0 {
+		return q
+	}
+
+	// |2r| >= |b| 时进位
+	twice := new(big.Int).Abs(r)
+	twice.Lsh(twice, 1)
+	if twice.Cmp(new(big.Int).Abs(b)) >= 0 {
+		if (a.Sign() < 0) != (b.Sign() < 0) {
+			q.Sub(q, bigOne)
+		} else {
+			q.Add(q, bigOne)
+		}
+	}
+
+	return q
+}
+
+// rescale 调整小数位数, 位数变少时和 PostgreSQL 一样四舍五入 (远离零的方向)
+func (d decimal) rescale(scale int32) decimal {
+	switch {
+	case scale > d.scale:
+		return decimal{
+			unscaled: new(big.Int).Mul(d.unscaled, pow10(scale-d.scale)),
+			scale:    scale,
+		}
+	case scale < d.scale:
+		return decimal{
+			unscaled: divRound(d.unscaled, pow10(d.scale-scale)),
+			scale:    scale,
+		}
+	}
+
+	return d
+}
+
+// integerDigits 返回整数部分的位数, 用来检查 NUMERIC(p, s) 的精度
+func (d decimal) integerDigits() int {
+	n := len(new(big.Int).Abs(d.unscaled).String()) - int(d.scale)
+	if n < 0 || d.rescale(0).unscaled.Sign() == 0 {
+		return 0
+	}
+
+	return n
+}
+
+func (d decimal) cmp(o decimal) int {
+	scale := d.scale
+	if o.scale > scale {
+		scale = o.scale
+	}
+
+	return d.rescale(scale).unscaled.Cmp(o.rescale(scale).unscaled)
+}
+
+// toInt64 四舍五入到整数, 超出 int64 范围时返回 false
+func (d decimal) toInt64() (int64, bool) {
+	i := d.rescale(0).unscaled
+	if !i.IsInt64() {
+		return 0, false
+	}
+
+	return i.Int64(), true
+}
+
+// decimalArithmetic 精确计算 + - * /. 加减的小数位数取两边较大的, 乘法是两边之和,
+// 除法至少保留 divisionMinScale 位小数
+func decimalArithmetic(op symbol, a, b decimal) (decimal, error) {
+	scale := a.scale
+	if b.scale > scale {
+		scale = b.scale
+	}
+
+	switch op {
+	case plusSymbol:
+		return decimal{
+			unscaled: new(big.Int).Add(a.rescale(scale).unscaled, b.rescale(scale).unscaled),
+			scale:    scale,
+		}, nil
+	case minusSymbol:
+		return decimal{
+			unscaled: new(big.Int).Sub(a.rescale(scale).unscaled, b.rescale(scale).unscaled),
+			scale:    scale,
+		}, nil
+	case asteriskSymbol:
+		if a.scale+b.scale > maxNumericScale {
+			return decimal{}, ErrOutOfRange
+		}
+
+		return decimal{
+			unscaled: new(big.Int).Mul(a.unscaled, b.unscaled),
+			scale:    a.scale + b.scale,
+		}, nil
+	case slashSymbol:
+		if b.unscaled.Sign() == 0 {
+			return decimal{}, ErrDivisionByZero
+		}
+
+		if scale < divisionMinScale {
+			scale = divisionMinScale
+		}
+
+		// a / b * 10^scale = A * 10^(scale - sa + sb) / B
+		num, den := new(big.Int).Set(a.unscaled), new(big.Int).Set(b.unscaled)
+		if e := scale - a.scale + b.scale; e >= 0 {
+			num.Mul(num, pow10(e))
+		} else {
+			den.Mul(den, pow10(-e))
+		}
+
+		return decimal{
+			unscaled: divRound(num, den),
+			scale:    scale,
+		}, nil
+	}
+
+	return decimal{}, ErrInvalidOperands
+}
+
+// checkNumericModifier 按 NUMERIC(p, s) 调整小数位数并检查整数部分没有超出 p - s 位
+func checkNumericModifier(d decimal, mod typeModifier) (decimal, error) {
+	if mod.precision == 0 {
+		return d, nil
+	}
+
+	d = d.rescale(int32(mod.scale))
+	if d.integerDigits() > mod.precision-mod.scale {
+		return decimal{}, ErrOutOfRange
+	}
+
+	return d, nil
+}
@@ -0,0 +1,64 @@
+package jiesql
+
+import (
+	"testing"
+)
+
+func TestNumeric(t *testing.T) {
+	mb := NewMemoryBackend()
+	mustRun(t, mb, `CREATE TABLE accounts (id INT, balance NUMERIC(10, 2), rate NUMERIC);
+		INSERT INTO accounts VALUES (1, 100.1, 0.015);
+		INSERT INTO accounts VALUES (2, '0.005', 1);
+		INSERT INTO accounts VALUES (3, -2.345, null);
+		INSERT INTO accounts VALUES (4, null, 123456789012345678901234567890.5);`)
+
+	// NUMERIC(10, 2) 按 scale 输出并四舍五入, 不带参数时保留输入的小数位数
+	expectRows(t, mb, "SELECT balance, rate FROM accounts", [][]string{
+		{"100.10", "0.015"},
+		{"0.01", "1"},
+		{"-2.35", "null"},
+		{"null", "123456789012345678901234567890.5"},
+	})
+
+	tests := []struct {
+		source   string
+		expected string
+	}{
+		{"SELECT 0.1 + 0.2", "0.3"},
+		{"SELECT 0.1 + 0.2 = 0.3", "true"},
+		{"SELECT 1.50 - 0.5", "1.00"},
+		{"SELECT 1.5 * 1.25", "1.875"},
+		{"SELECT 1 / 3.0", "0.3333333333333333"},
+		{"SELECT 2 / 3.0", "0.6666666666666667"},
+		{"SELECT 10.0 / 4", "2.5000000000000000"},
+		{"SELECT 1.5e-3", "0.0015"},
+		{"SELECT .5", "0.5"},
+		{"SELECT -1.5", "-1.5"},
+		{"SELECT 1 + 1.5", "2.5"},
+		{"SELECT 99999999999999999999.5 + 0.5", "100000000000000000000.0"},
+		{"SELECT 1.5::numeric(3, 0)", "2"},
+		{"SELECT -2.5::numeric(3, 0)", "-3"},
+		{"SELECT '1.25'::numeric", "1.25"},
+		{"SELECT 1.5::int", "2"},
+		{"SELECT 1.5::float", "1.5"},
+		{"SELECT 2::numeric", "2"},
+		{"SELECT 1.50 = 1.5", "true"},
+		{"SELECT 1.5 > 1.49", "true"},
+		{"SELECT 1.5 + null", "null"},
+	}
+
+	for _, test := range tests {
+		expectValue(t, mb, test.source, test.expected)
+	}
+
+	expectRows(t, mb, "SELECT id FROM accounts WHERE balance > 0.01", [][]string{{"1"}})
+	expectRows(t, mb, "SELECT balance * rate FROM accounts WHERE id = 1", [][]string{{"1.50150"}})
+	expectColumns(t, mb, "SELECT balance + 1 FROM accounts WHERE id = 1", []column{{Name: "?column?", Type: NumericType}})
+
+	expectError(t, mb, "INSERT INTO accounts VALUES (5, 123456789.5, null)", ErrOutOfRange)
+	expectError(t, mb, "SELECT 1.5 / 0", ErrDivisionByZero)
+	expectError(t, mb, "SELECT 'abc'::numeric", ErrInvalidValue)
+	expectError(t, mb, "SELECT 'NaN'::float::numeric", ErrOutOfRange)
+	expectError(t, mb, "SELECT 1e100::numeric::int", ErrOutOfRange)
+	expectError(t, mb, "CREATE TABLE bad (n NUMERIC(2, 3))", ErrInvalidDatatype)
+}
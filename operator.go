@@ -0,0 +1,676 @@
+package jiesql
+
+import (
+	"fmt"
+	"sort"
+)
+
+// operator 是执行计划里的算子. Open 之后反复调用 Next 一次取出一行, 直到 ok 为 false,
+// 最后调用 Close. Close 之后可以再次 Open 从头开始, 嵌套循环连接就是这样重新扫描内表的.
+// 大部分算子只在需要时向子算子要一行, 只有排序和窗口函数要先读完所有的行
+type operator interface {
+	// Open 返回结果的列, 返回的表里的 rows 没有意义
+	Open() (*table, error)
+	Next() ([]MemoryCell, bool, error)
+	Close() error
+}
+
+// drain 执行算子并取出所有的行
+func drain(op operator) (*table, error) {
+	schema, err := op.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer op.Close()
+
+	t := *schema
+	t.rows = nil
+	for {
+		row, ok, err := op.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		if !ok {
+			break
+		}
+
+		t.rows = append(t.rows, row)
+	}
+
+	return &t, nil
+}
+
+// scanOp 逐行读取一张已经存在的表, source 在 Open 时调用, 这样可以读到 WITH 临时表最新的内容
+type scanOp struct {
+	source func() *table
+	t      *table
+	i      int
+}
+
+func (s *scanOp) Open() (*table, error) {
+	s.t = s.source()
+	s.i = 0
+	return s.t, nil
+}
+
+func (s *scanOp) Next() ([]MemoryCell, bool, error) {
+	if s.i >= len(s.t.rows) {
+		return nil, false, nil
+	}
+
+	s.i++
+	return s.t.rows[s.i-1], true, nil
+}
+
+func (s *scanOp) Close() error {
+	s.t = nil
+	return nil
+}
+
+// resultOp 只产生一个空行, 用于没有 FROM 的 SELECT
+type resultOp struct {
+	done bool
+}
+
+func (r *resultOp) Open() (*table, error) {
+	r.done = false
+	return &table{}, nil
+}
+
+func (r *resultOp) Next() ([]MemoryCell, bool, error) {
+	if r.done {
+		return nil, false, nil
+	}
+
+	r.done = true
+	return []MemoryCell{}, true, nil
+}
+
+func (r *resultOp) Close() error {
+	return nil
+}
+
+// qualifyOp 让列名带上表名或别名, 例如 e.id, 行本身不变
+type qualifyOp struct {
+	child     operator
+	qualifier string
+}
+
+func (q *qualifyOp) Open() (*table, error) {
+	t, err := q.child.Open()
+	if err != nil {
+		return nil, err
+	}
+
+	schema := &table{}
+	for i, column := range t.columns {
+		schema.columns = append(schema.columns, q.qualifier+"."+column)
+		schema.columnTypes = append(schema.columnTypes, t.columnTypes[i])
+		schema.columnModifiers = append(schema.columnModifiers, t.columnModifiers[i])
+	}
+
+	return schema, nil
+}
+
+func (q *qualifyOp) Next() ([]MemoryCell, bool, error) {
+	return q.child.Next()
+}
+
+func (q *qualifyOp) Close() error {
+	return q.child.Close()
+}
+
+// renameOp 把结果当作一张名为 name 的表, columns 是给出的列名
+type renameOp struct {
+	child   operator
+	name    string
+	columns []string
+}
+
+func (r *renameOp) Open() (*table, error) {
+	t, err := r.child.Open()
+	if err != nil {
+		return nil, err
+	}
+
+	renamed, err := renameTable(r.name, r.columns, t)
+	if err != nil {
+		r.child.Close()
+		return nil, err
+	}
+
+	return renamed, nil
+}
+
+func (r *renameOp) Next() ([]MemoryCell, bool, error) {
+	return r.child.Next()
+}
+
+func (r *renameOp) Close() error {
+	return r.child.Close()
+}
+
+// nestedLoopOp 把外表的每一行和内表的每一行拼在一起, 外表每换一行就重新打开内表
+type nestedLoopOp struct {
+	left, right operator
+	outer       []MemoryCell
+	rescan      bool
+}
+
+func (n *nestedLoopOp) Open() (*table, error) {
+	l, err := n.left.Open()
+	if err != nil {
+		return nil, err
+	}
+
+	// 内表打开失败时调用方不会再 Close, 要在这里关掉已经打开的外表
+	r, err := n.right.Open()
+	if err != nil {
+		n.left.Close()
+		return nil, err
+	}
+
+	n.outer = nil
+	n.rescan = false
+	return &table{
+		columns:         append(append([]string{}, l.columns...), r.columns...),
+		columnTypes:     append(append([]ColumnType{}, l.columnTypes...), r.columnTypes...),
+		columnModifiers: append(append([]typeModifier{}, l.columnModifiers...), r.columnModifiers...),
+	}, nil
+}
+
+func (n *nestedLoopOp) Next() ([]MemoryCell, bool, error) {
+	for {
+		if n.outer == nil {
+			row, ok, err := n.left.Next()
+			if err != nil || !ok {
+				return nil, false, err
+			}
+			n.outer = row
+
+			// 第一行用 Open 时打开的内表
+			if n.rescan {
+				if err := n.right.Close(); err != nil {
+					return nil, false, err
+				}
+
+				if _, err := n.right.Open(); err != nil {
+					return nil, false, err
+				}
+			}
+			n.rescan = true
+		}
+
+		inner, ok, err := n.right.Next()
+		if err != nil {
+			return nil, false, err
+		}
+
+		if ok {
+			return append(append([]MemoryCell{}, n.outer...), inner...), true, nil
+		}
+
+		n.outer = nil
+	}
+}
+
+func (n *nestedLoopOp) Close() error {
+	err := n.right.Close()
+	if e := n.left.Close(); err == nil {
+		err = e
+	}
+
+	return err
+}
+
+// filterOp 跳过不满足条件的行, removed 统计跳过的行数
+type filterOp struct {
+	mb      *MemoryBackend
+	child   operator
+	where   expression
+	removed *int
+	schema  *table
+}
+
+func (f *filterOp) Open() (*table, error) {
+	var err error
+	f.schema, err = f.child.Open()
+	return f.schema, err
+}
+
+func (f *filterOp) Next() ([]MemoryCell, bool, error) {
+	for {
+		row, ok, err := f.child.Next()
+		if err != nil || !ok {
+			return nil, false, err
+		}
+
+		ok, err = f.mb.evaluateCondition(f.schema, row, f.where)
+		if err != nil {
+			return nil, false, err
+		}
+
+		if ok {
+			return row, true, nil
+		}
+
+		*f.removed++
+	}
+}
+
+func (f *filterOp) Close() error {
+	return f.child.Close()
+}
+
+// windowOp 读完所有的行后计算窗口函数, 结果放在隐藏的列里, 由后面的投影读取
+type windowOp struct {
+	mb    *MemoryBackend
+	child operator
+	items []*expression
+	scanOp
+}
+
+func (w *windowOp) Open() (*table, error) {
+	t, err := drain(w.child)
+	if err != nil {
+		return nil, err
+	}
+
+	t, err = w.mb.windowTable(t, t.rows, w.items)
+	if err != nil {
+		return nil, err
+	}
+
+	w.source = func() *table { return t }
+	return w.scanOp.Open()
+}
+
+// sortOp 读完所有的行后按 ORDER BY 排序, 相等的行保持原来的顺序, NULL 排在最大的位置
+type sortOp struct {
+	mb      *MemoryBackend
+	child   operator
+	orderBy []*orderItem
+	// 为 true 时 orderBy 里的整数常量表示子算子结果的第几列, 见 orderPosition
+	positional bool
+	scanOp
+}
+
+func (s *sortOp) Open() (*table, error) {
+	t, err := drain(s.child)
+	if err != nil {
+		return nil, err
+	}
+
+	var exps []expression
+	for _, item := range s.orderBy {
+		exps = append(exps, item.exp)
+	}
+
+	_, types, err := s.mb.evaluateList(t, nil, exps)
+	if err != nil {
+		return nil, err
+	}
+
+	// 按列位置排序的项直接取那一列, 位置在规划时已经检查过
+	columns := make([]int, len(exps))
+	for i, item := range s.orderBy {
+		columns[i] = -1
+		if n, ok := orderPosition(item); ok && s.positional {
+			columns[i] = n - 1
+			types[i] = t.columnTypes[n-1]
+		}
+	}
+
+	keys := make([][]MemoryCell, len(t.rows))
+	for i, row := range t.rows {
+		keys[i], _, err = s.mb.evaluateList(t, row, exps)
+		if err != nil {
+			return nil, err
+		}
+
+		for j, c := range columns {
+			if c >= 0 {
+				keys[i][j] = row[c]
+			}
+		}
+	}
+
+	order := make([]int, len(t.rows))
+	for i := range order {
+		order[i] = i
+	}
+
+	sort.SliceStable(order, func(i, j int) bool {
+		return compareOrderKeys(keys[order[i]], keys[order[j]], s.orderBy, types) < 0
+	})
+
+	sorted := *t
+	sorted.rows = make([][]MemoryCell, len(order))
+	for i, k := range order {
+		sorted.rows[i] = t.rows[k]
+	}
+
+	s.source = func() *table { return &sorted }
+	return s.scanOp.Open()
+}
+
+// limitOp 跳过前 offset 行, 最多返回 limit 行, 够了以后不再从子算子取行
+type limitOp struct {
+	mb            *MemoryBackend
+	child         operator
+	limit, offset *expression
+	remaining     int64
+	skip          int64
+}
+
+func (l *limitOp) Open() (*table, error) {
+	var err error
+	l.remaining, err = l.mb.evaluateLimit(l.limit, "LIMIT")
+	if err != nil {
+		return nil, err
+	}
+
+	l.skip, err = l.mb.evaluateLimit(l.offset, "OFFSET")
+	if err != nil {
+		return nil, err
+	}
+
+	return l.child.Open()
+}
+
+// evaluateLimit 计算 LIMIT 或 OFFSET 的值, 没写或者是 NULL 时返回 -1
+func (mb *MemoryBackend) evaluateLimit(exp *expression, clause string) (int64, error) {
+	if exp == nil {
+		return -1, nil
+	}
+
+	cell, _, typ, err := mb.evaluateCell(&table{}, []MemoryCell{}, *exp)
+	if err != nil {
+		return 0, err
+	}
+
+	if cell == nil {
+		return -1, nil
+	}
+
+	if !isIntegerType(typ) {
+		return 0, fmt.Errorf("%w: argument of %s must be an integer, got %s", ErrInvalidLimit, clause, typ)
+	}
+
+	n := cell.AsInt64()
+	if n < 0 {
+		return 0, fmt.Errorf("%w: %s must not be negative", ErrInvalidLimit, clause)
+	}
+
+	return n, nil
+}
+
+func (l *limitOp) Next() ([]MemoryCell, bool, error) {
+	for {
+		if l.remaining == 0 {
+			return nil, false, nil
+		}
+
+		row, ok, err := l.child.Next()
+		if err != nil || !ok {
+			return nil, false, err
+		}
+
+		if l.skip > 0 {
+			l.skip--
+			continue
+		}
+
+		if l.remaining > 0 {
+			l.remaining--
+		}
+
+		return row, true, nil
+	}
+}
+
+func (l *limitOp) Close() error {
+	return l.child.Close()
+}
+
+// projectOp 计算 SELECT 的每一项, unnest 的每个元素占一行
+type projectOp struct {
+	mb      *MemoryBackend
+	child   operator
+	items   []*expression
+	schema  *table
+	pending [][]MemoryCell
+}
+
+func (p *projectOp) Open() (*table, error) {
+	t, err := p.child.Open()
+	if err != nil {
+		return nil, err
+	}
+	p.schema = t
+	p.pending = nil
+
+	// 失败时调用方不会再 Close, 要在这里关掉子算子
+	fail := func(err error) (*table, error) {
+		p.child.Close()
+		return nil, err
+	}
+
+	// 在全为 NULL 的行上求值来确定结果的列名和类型, 这样空结果也有列信息
+	columns := []column{}
+	for _, exp := range p.items {
+		if isSetReturning(*exp) {
+			_, typ, err := p.mb.evaluateUnnestCell(t, nil, *exp)
+			if err != nil {
+				return fail(err)
+			}
+
+			columns = append(columns, column{
+				Type: typ,
+				Name: exp.function.name.value,
+			})
+			continue
+		}
+
+		_, name, typ, err := p.mb.evaluateCell(t, nil, *exp)
+		if err != nil {
+			return fail(err)
+		}
+
+		columns = append(columns, column{
+			Type: typ,
+			Name: name,
+		})
+	}
+
+	return newResultTable(columns), nil
+}
+
+func (p *projectOp) Next() ([]MemoryCell, bool, error) {
+	for len(p.pending) == 0 {
+		row, ok, err := p.child.Next()
+		if err != nil || !ok {
+			return nil, false, err
+		}
+
+		p.pending, err = p.projectRow(row)
+		if err != nil {
+			return nil, false, err
+		}
+	}
+
+	row := p.pending[0]
+	p.pending = p.pending[1:]
+	return row, true, nil
+}
+
+// projectRow 计算一行的结果, 有多个 unnest 时按位置对齐, 短的用 NULL 补齐
+func (p *projectOp) projectRow(row []MemoryCell) ([][]MemoryCell, error) {
+	result := []MemoryCell{}
+	sets := map[int][]MemoryCell{}
+	for i, exp := range p.items {
+		if isSetReturning(*exp) {
+			elems, _, err := p.mb.evaluateUnnestCell(p.schema, row, *exp)
+			if err != nil {
+				return nil, err
+			}
+
+			sets[i] = elems
+			result = append(result, nil)
+			continue
+		}
+
+		cell, _, _, err := p.mb.evaluateCell(p.schema, row, *exp)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, cell)
+	}
+
+	if len(sets) == 0 {
+		return [][]MemoryCell{result}, nil
+	}
+
+	n := 0
+	for _, elems := range sets {
+		if len(elems) > n {
+			n = len(elems)
+		}
+	}
+
+	var rows [][]MemoryCell
+	for j := 0; j < n; j++ {
+		expanded := append([]MemoryCell{}, result...)
+		for i, elems := range sets {
+			var cell MemoryCell
+			if j < len(elems) {
+				cell = elems[j]
+			}
+
+			expanded[i] = cell
+		}
+
+		rows = append(rows, expanded)
+	}
+
+	return rows, nil
+}
+
+func (p *projectOp) Close() error {
+	p.pending = nil
+	return p.child.Close()
+}
+
+// appendOp 先返回左边的所有行再返回右边的, 都转换成 UNION 合并后的类型
+type appendOp struct {
+	left, right  operator
+	lTypes       []ColumnType
+	rTypes       []ColumnType
+	types        []ColumnType
+	leftFinished bool
+}
+
+func (a *appendOp) Open() (*table, error) {
+	l, err := a.left.Open()
+	if err != nil {
+		return nil, err
+	}
+
+	// 失败时调用方不会再 Close, 要在这里关掉已经打开的子算子
+	r, err := a.right.Open()
+	if err != nil {
+		a.left.Close()
+		return nil, err
+	}
+
+	fail := func(err error) (*table, error) {
+		a.right.Close()
+		a.left.Close()
+		return nil, err
+	}
+
+	if len(l.columns) != len(r.columns) {
+		return fail(fmt.Errorf("%w: each UNION query must have the same number of columns", ErrInvalidSelectItem))
+	}
+
+	var columns []column
+	a.types = nil
+	for i, name := range l.columns {
+		typ, err := unionType(l.columnTypes[i], r.columnTypes[i])
+		if err != nil {
+			return fail(err)
+		}
+
+		columns = append(columns, column{Name: name, Type: typ})
+		a.types = append(a.types, typ)
+	}
+
+	a.lTypes, a.rTypes = l.columnTypes, r.columnTypes
+	a.leftFinished = false
+	return newResultTable(columns), nil
+}
+
+func (a *appendOp) Next() ([]MemoryCell, bool, error) {
+	if !a.leftFinished {
+		row, ok, err := a.left.Next()
+		if err != nil {
+			return nil, false, err
+		}
+
+		if ok {
+			row, err = castRow(row, a.lTypes, a.types)
+			return row, err == nil, err
+		}
+
+		a.leftFinished = true
+	}
+
+	row, ok, err := a.right.Next()
+	if err != nil || !ok {
+		return nil, false, err
+	}
+
+	row, err = castRow(row, a.rTypes, a.types)
+	return row, err == nil, err
+}
+
+func (a *appendOp) Close() error {
+	a.right.Close()
+	return a.left.Close()
+}
+
+// uniqueOp 跳过已经返回过的行
+type uniqueOp struct {
+	child operator
+	seen  *rowSet
+}
+
+func (u *uniqueOp) Open() (*table, error) {
+	t, err := u.child.Open()
+	if err != nil {
+		return nil, err
+	}
+
+	u.seen = newRowSet(t.columnTypes)
+	return t, nil
+}
+
+func (u *uniqueOp) Next() ([]MemoryCell, bool, error) {
+	for {
+		row, ok, err := u.child.Next()
+		if err != nil || !ok {
+			return nil, false, err
+		}
+
+		if u.seen.add(row) {
+			return row, true, nil
+		}
+	}
+}
+
+func (u *uniqueOp) Close() error {
+	u.seen = nil
+	return u.child.Close()
+}
@@ -0,0 +1,214 @@
+package jiesql
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// testOp 逐行返回 rows, 记录打开的状态和次数, openErr 不为 nil 时 Open 失败.
+// columns 为空时只有一个 int 列 n
+type testOp struct {
+	rows    [][]MemoryCell
+	columns []column
+	openErr error
+	open    bool
+	opens   int
+	i       int
+}
+
+func (o *testOp) Open() (*table, error) {
+	if o.openErr != nil {
+		return nil, o.openErr
+	}
+
+	o.open = true
+	o.opens++
+	o.i = 0
+	if len(o.columns) > 0 {
+		return newResultTable(o.columns), nil
+	}
+
+	return newResultTable([]column{{Name: "n", Type: IntType}}), nil
+}
+
+func (o *testOp) Next() ([]MemoryCell, bool, error) {
+	if o.i >= len(o.rows) {
+		return nil, false, nil
+	}
+
+	o.i++
+	return o.rows[o.i-1], true, nil
+}
+
+func (o *testOp) Close() error {
+	o.open = false
+	return nil
+}
+
+func intRows(values ...int64) [][]MemoryCell {
+	var rows [][]MemoryCell
+	for _, v := range values {
+		rows = append(rows, []MemoryCell{integerCell(v, IntType)})
+	}
+
+	return rows
+}
+
+func TestNestedLoop(t *testing.T) {
+	left := &testOp{rows: intRows(1, 2)}
+	right := &testOp{rows: intRows(10, 20, 30)}
+	loop := &nestedLoopOp{left: left, right: right}
+
+	result, err := drain(loop)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var pairs [][2]int64
+	for _, row := range result.rows {
+		pairs = append(pairs, [2]int64{row[0].AsInt64(), row[1].AsInt64()})
+	}
+
+	expected := [][2]int64{{1, 10}, {1, 20}, {1, 30}, {2, 10}, {2, 20}, {2, 30}}
+	if !reflect.DeepEqual(pairs, expected) {
+		t.Errorf("got %v, want %v", pairs, expected)
+	}
+
+	// 外表每一行打开一次内表
+	if right.opens != 2 {
+		t.Errorf("expected inner to be opened 2 times, got %d", right.opens)
+	}
+
+	if left.open || right.open {
+		t.Errorf("expected both children to be closed")
+	}
+
+	// 外表为空时结果为空
+	result, err = drain(&nestedLoopOp{left: &testOp{}, right: &testOp{rows: intRows(1)}})
+	if err != nil || len(result.rows) != 0 {
+		t.Errorf("expected no rows, got %v %v", result, err)
+	}
+}
+
+func TestNestedLoopOpenFailure(t *testing.T) {
+	openErr := errors.New("open failed")
+	left := &testOp{rows: intRows(1)}
+	loop := &nestedLoopOp{left: left, right: &testOp{openErr: openErr}}
+
+	if _, err := loop.Open(); err != openErr {
+		t.Fatalf("expected %v, got %v", openErr, err)
+	}
+
+	if left.open {
+		t.Errorf("expected outer to be closed after inner failed to open")
+	}
+}
+
+func TestAppendOpenFailure(t *testing.T) {
+	openErr := errors.New("open failed")
+	tests := []struct {
+		right  *testOp
+		target error
+	}{
+		{&testOp{openErr: openErr}, openErr},
+		{&testOp{columns: []column{{Name: "a", Type: IntType}, {Name: "b", Type: IntType}}}, ErrInvalidSelectItem},
+		{&testOp{columns: []column{{Name: "a", Type: TextType}}}, ErrInvalidDatatype},
+	}
+
+	for _, test := range tests {
+		left := &testOp{rows: intRows(1)}
+		_, err := (&appendOp{left: left, right: test.right}).Open()
+		if !errors.Is(err, test.target) {
+			t.Errorf("expected %v, got %v", test.target, err)
+		}
+
+		if left.open || test.right.open {
+			t.Errorf("%v: expected both children to be closed", test.target)
+		}
+	}
+}
+
+func TestOpenFailureClosesChild(t *testing.T) {
+	mb := NewMemoryBackend()
+	ast, err := Parse("SELECT nosuch, unnest(1);")
+	if err != nil {
+		t.Fatal(err)
+	}
+	items := ast.Statements[0].SelectStatement.item
+
+	tests := []struct {
+		name   string
+		op     func(child operator) operator
+		target error
+	}{
+		{"project", func(child operator) operator {
+			return &projectOp{mb: mb, child: child, items: items[:1]}
+		}, ErrColumnDoesNotExist},
+		{"project unnest", func(child operator) operator {
+			return &projectOp{mb: mb, child: child, items: items[1:]}
+		}, ErrFunctionDoesNotExist},
+		{"rename", func(child operator) operator {
+			return &renameOp{child: child, name: "v", columns: []string{"a", "b"}}
+		}, ErrInvalidSelectItem},
+		{"recursive union", func(child operator) operator {
+			return &recursiveUnionOp{mb: mb, ct: &commonTable{name: "n"}, columns: []string{"a", "b"}, anchor: &planNode{op: child}}
+		}, ErrInvalidSelectItem},
+	}
+
+	for _, test := range tests {
+		child := &testOp{rows: intRows(1)}
+		if _, err := test.op(child).Open(); !errors.Is(err, test.target) {
+			t.Errorf("%s: expected %v, got %v", test.name, test.target, err)
+		}
+
+		if child.open {
+			t.Errorf("%s: expected child to be closed", test.name)
+		}
+	}
+
+	// WITH 临时表在主查询打开失败之前已经开始执行时也要关掉
+	openErr := errors.New("open failed")
+	cte := &testOp{rows: intRows(1)}
+	ct := &commonTable{name: "c", plan: &planNode{op: cte}}
+	with := &withOp{
+		child: &nestedLoopOp{left: &cteScanOp{ct: ct}, right: &testOp{openErr: openErr}},
+		ctes:  []*commonTable{ct},
+	}
+
+	if _, err := with.Open(); err != openErr {
+		t.Errorf("expected %v, got %v", openErr, err)
+	}
+
+	if cte.open {
+		t.Errorf("expected WITH query to be closed")
+	}
+}
+
+func TestUnique(t *testing.T) {
+	var values []int64
+	for i := int64(0); i < 20000; i++ {
+		values = append(values, i%5000)
+	}
+
+	child := &testOp{rows: intRows(values...)}
+	result, err := drain(&uniqueOp{child: child})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(result.rows) != 5000 {
+		t.Fatalf("expected 5000 rows, got %d", len(result.rows))
+	}
+
+	// 保持第一次出现的顺序
+	for i, row := range result.rows {
+		if row[0].AsInt64() != int64(i) {
+			t.Fatalf("row %d: got %d", i, row[0].AsInt64())
+		}
+	}
+
+	if child.open {
+		t.Errorf("expected child to be closed")
+	}
+}
@@ -3,6 +3,7 @@ package jiesql
 import (
 	"errors"
 	"fmt"
+	"strconv"
 )
 
 func tokenFromKeyword(k keyword) token {
@@ -48,6 +49,10 @@ func Parse(source string) (*Ast, error) {
 		return nil, err
 	}
 
+	return parseTokens(tokens)
+}
+
+func parseTokens(tokens []*token) (*Ast, error) {
 	a := Ast{}
 	cursor := uint(0)
 	for cursor < uint(len(tokens)) {
@@ -97,6 +102,22 @@ func parseStatement(tokens []*token, initialCursor uint, delimiter token) (*Stat
 		}, newCursor, true
 	}
 
+	upd, newCursor, ok := parseUpdateStatement(tokens, cursor, semicolonToken)
+	if ok {
+		return &Statement{
+			Kind:            UpdateKind,
+			UpdateStatement: upd,
+		}, newCursor, true
+	}
+
+	del, newCursor, ok := parseDeleteStatement(tokens, cursor, semicolonToken)
+	if ok {
+		return &Statement{
+			Kind:            DeleteKind,
+			DeleteStatement: del,
+		}, newCursor, true
+	}
+
 	// Look for a CREATE statement
 	crtTbl, newCursor, ok := parseCreateTableStatement(tokens, cursor, semicolonToken)
 	if ok {
@@ -106,18 +127,195 @@ func parseStatement(tokens []*token, initialCursor uint, delimiter token) (*Stat
 		}, newCursor, true
 	}
 
+	crtType, newCursor, ok := parseCreateTypeStatement(tokens, cursor, semicolonToken)
+	if ok {
+		return &Statement{
+			Kind:                CreateTypeKind,
+			CreateTypeStatement: crtType,
+		}, newCursor, true
+	}
+
+	crtSeq, newCursor, ok := parseCreateSequenceStatement(tokens, cursor, semicolonToken)
+	if ok {
+		return &Statement{
+			Kind:                    CreateSequenceKind,
+			CreateSequenceStatement: crtSeq,
+		}, newCursor, true
+	}
+
+	alt, newCursor, ok := parseAlterTableStatement(tokens, cursor, semicolonToken)
+	if ok {
+		return &Statement{
+			Kind:                AlterTableKind,
+			AlterTableStatement: alt,
+		}, newCursor, true
+	}
+
+	crtView, newCursor, ok := parseCreateViewStatement(tokens, cursor, semicolonToken)
+	if ok {
+		return &Statement{
+			Kind:                CreateViewKind,
+			CreateViewStatement: crtView,
+		}, newCursor, true
+	}
+
+	dropView, newCursor, ok := parseDropViewStatement(tokens, cursor, semicolonToken)
+	if ok {
+		return &Statement{
+			Kind:              DropViewKind,
+			DropViewStatement: dropView,
+		}, newCursor, true
+	}
+
+	refresh, newCursor, ok := parseRefreshMaterializedViewStatement(tokens, cursor, semicolonToken)
+	if ok {
+		return &Statement{
+			Kind:                             RefreshMaterializedViewKind,
+			RefreshMaterializedViewStatement: refresh,
+		}, newCursor, true
+	}
+
+	explain, newCursor, ok := parseExplainStatement(tokens, cursor, semicolonToken)
+	if ok {
+		return &Statement{
+			Kind:             ExplainKind,
+			ExplainStatement: explain,
+		}, newCursor, true
+	}
+
 	return nil, initialCursor, false
 }
 
 /* select mode
-1. SELECT
-2. $expression [, ...]
-3. FROM
-4. $table-name
+1. [WITH [RECURSIVE] $common-table-expression [, ...]]
+2. SELECT
+3. $expression [, ...]
+4. [FROM $table-reference [, ...]]
+5. [WHERE $expression]
+6. [UNION [ALL] SELECT ... [...]]
+7. [ORDER BY $expression [ASC | DESC] [, ...]]
+8. [LIMIT $expression] [OFFSET $expression]
 */
 // 切记辅助函数是需要返回新的 cursor来让parser（parse函数）进行定位
 func parseSelectStatement(tokens []*token, initialCursor uint, delimiter token) (*SelectStatement, uint, bool) {
 	cursor := initialCursor
+
+	var with []*commonTableExpression
+	recursive := false
+	if expectToken(tokens, cursor, tokenFromKeyword(withKeyword)) {
+		cursor++
+
+		if expectIdentifier(tokens, cursor, "recursive") {
+			recursive = true
+			cursor++
+		}
+
+		for {
+			cte, newCursor, ok := parseCommonTableExpression(tokens, cursor)
+			if !ok {
+				return nil, initialCursor, false
+			}
+			cursor = newCursor
+
+			with = append(with, cte)
+
+			if !expectToken(tokens, cursor, tokenFromSymbol(commaSymbol)) {
+				break
+			}
+			cursor++
+		}
+	}
+
+	slct, newCursor, ok := parseSimpleSelect(tokens, cursor, delimiter)
+	if !ok {
+		if with != nil {
+			helpMessage(tokens, cursor, "Expected SELECT after WITH")
+		}
+
+		return nil, initialCursor, false
+	}
+	cursor = newCursor
+
+	slct.with = with
+	slct.recursive = recursive
+
+	for expectToken(tokens, cursor, tokenFromKeyword(unionKeyword)) {
+		cursor++
+
+		op := setOperation{}
+		if expectToken(tokens, cursor, tokenFromKeyword(allKeyword)) {
+			op.all = true
+			cursor++
+		}
+
+		query, newCursor, ok := parseSimpleSelect(tokens, cursor, delimiter)
+		if !ok {
+			helpMessage(tokens, cursor, "Expected SELECT after UNION")
+			return nil, initialCursor, false
+		}
+		cursor = newCursor
+
+		op.query = query
+		slct.union = append(slct.union, &op)
+	}
+
+	limitToken := token{kind: identifierKind, value: "limit"}
+	offsetToken := token{kind: identifierKind, value: "offset"}
+	if expectIdentifier(tokens, cursor, "order") && expectIdentifier(tokens, cursor+1, "by") {
+		cursor += 2
+
+		orderBy, newCursor, ok := parseOrderBy(tokens, cursor, []token{limitToken, offsetToken, delimiter})
+		if !ok {
+			return nil, initialCursor, false
+		}
+		cursor = newCursor
+
+		slct.orderBy = orderBy
+	}
+
+	if expectToken(tokens, cursor, limitToken) {
+		cursor++
+
+		limit, newCursor, ok := parseExpression(tokens, cursor, []token{offsetToken, delimiter}, 0)
+		if !ok {
+			helpMessage(tokens, cursor, "Expected LIMIT expression")
+			return nil, initialCursor, false
+		}
+		cursor = newCursor
+
+		slct.limit = limit
+	}
+
+	if expectToken(tokens, cursor, offsetToken) {
+		cursor++
+
+		offset, newCursor, ok := parseExpression(tokens, cursor, []token{delimiter}, 0)
+		if !ok {
+			helpMessage(tokens, cursor, "Expected OFFSET expression")
+			return nil, initialCursor, false
+		}
+		cursor = newCursor
+
+		slct.offset = offset
+	}
+
+	return slct, cursor, true
+}
+
+// selectClauses 是可以跟在 WHERE 条件后面的子句, ORDER, LIMIT 和 OFFSET 不是保留字
+func selectClauses(delimiter token) []token {
+	return []token{
+		tokenFromKeyword(unionKeyword),
+		{kind: identifierKind, value: "order"},
+		{kind: identifierKind, value: "limit"},
+		{kind: identifierKind, value: "offset"},
+		delimiter,
+	}
+}
+
+// SELECT $expression [, ...] [FROM ...] [WHERE ...], 不带 WITH 和 UNION 的部分
+func parseSimpleSelect(tokens []*token, initialCursor uint, delimiter token) (*SelectStatement, uint, bool) {
+	cursor := initialCursor
 	if !expectToken(tokens, cursor, tokenFromKeyword(selectKeyword)) {
 		return nil, initialCursor, false
 	}
@@ -125,7 +323,7 @@ func parseSelectStatement(tokens []*token, initialCursor uint, delimiter token)
 
 	slct := SelectStatement{}
 
-	exps, newCursor, ok := parseExpressions(tokens, cursor, []token{tokenFromKeyword(fromKeyword), delimiter})
+	exps, newCursor, ok := parseExpressions(tokens, cursor, append([]token{tokenFromKeyword(fromKeyword), tokenFromKeyword(whereKeyword)}, selectClauses(delimiter)...))
 	if !ok {
 		return nil, initialCursor, false
 	}
@@ -136,20 +334,117 @@ func parseSelectStatement(tokens []*token, initialCursor uint, delimiter token)
 	if expectToken(tokens, cursor, tokenFromKeyword(fromKeyword)) {
 		cursor++
 
-		from, newCursor, ok := parseToken(tokens, cursor, identifierKind)
+		for {
+			ref, newCursor, ok := parseTableReference(tokens, cursor)
+			if !ok {
+				helpMessage(tokens, cursor, "Expected FROM token")
+				return nil, initialCursor, false
+			}
+			cursor = newCursor
+
+			slct.from = append(slct.from, ref)
+
+			if !expectToken(tokens, cursor, tokenFromSymbol(commaSymbol)) {
+				break
+			}
+			cursor++
+		}
+	}
+
+	if expectToken(tokens, cursor, tokenFromKeyword(whereKeyword)) {
+		cursor++
+
+		where, newCursor, ok := parseExpression(tokens, cursor, selectClauses(delimiter), 0)
 		if !ok {
-			helpMessage(tokens, cursor, "Expected FROM token")
+			helpMessage(tokens, cursor, "Expected WHERE conditionals")
 			return nil, initialCursor, false
 		}
 
-		// 注意这里是取实体的值
-		slct.from = *from
+		slct.where = where
 		cursor = newCursor
 	}
 
 	return &slct, cursor, true
 }
 
+// $table-name [[AS] $alias]
+func parseTableReference(tokens []*token, initialCursor uint) (*tableReference, uint, bool) {
+	cursor := initialCursor
+
+	name, newCursor, ok := parseToken(tokens, cursor, identifierKind)
+	if !ok {
+		return nil, initialCursor, false
+	}
+	cursor = newCursor
+
+	ref := tableReference{name: *name}
+
+	hasAs := expectToken(tokens, cursor, tokenFromKeyword(asKeyword))
+	if hasAs {
+		cursor++
+	} else if expectIdentifier(tokens, cursor, "order") || expectIdentifier(tokens, cursor, "limit") || expectIdentifier(tokens, cursor, "offset") {
+		// 和 PostgreSQL 一样, 不写 AS 时这几个词是后面的子句而不是别名
+		return &ref, cursor, true
+	}
+
+	alias, newCursor, ok := parseToken(tokens, cursor, identifierKind)
+	if ok {
+		ref.alias = alias
+		cursor = newCursor
+	} else if hasAs {
+		helpMessage(tokens, cursor, "Expected alias")
+		return nil, initialCursor, false
+	}
+
+	return &ref, cursor, true
+}
+
+// $name [($column-name [, ...])] AS ($select-statement)
+func parseCommonTableExpression(tokens []*token, initialCursor uint) (*commonTableExpression, uint, bool) {
+	cursor := initialCursor
+
+	name, newCursor, ok := parseToken(tokens, cursor, identifierKind)
+	if !ok {
+		helpMessage(tokens, cursor, "Expected WITH query name")
+		return nil, initialCursor, false
+	}
+	cursor = newCursor
+
+	cte := commonTableExpression{name: *name}
+	if expectToken(tokens, cursor, tokenFromSymbol(leftParenSymbol)) {
+		columns, newCursor, ok := parseColumnList(tokens, cursor)
+		if !ok {
+			return nil, initialCursor, false
+		}
+		cursor = newCursor
+
+		cte.columns = columns
+	}
+
+	if !expectToken(tokens, cursor, tokenFromKeyword(asKeyword)) || !expectToken(tokens, cursor+1, tokenFromSymbol(leftParenSymbol)) {
+		helpMessage(tokens, cursor, "Expected AS (")
+		return nil, initialCursor, false
+	}
+	cursor += 2
+
+	rightParenToken := tokenFromSymbol(rightParenSymbol)
+	query, newCursor, ok := parseSelectStatement(tokens, cursor, rightParenToken)
+	if !ok {
+		helpMessage(tokens, cursor, "Expected SELECT statement")
+		return nil, initialCursor, false
+	}
+	cursor = newCursor
+
+	if !expectToken(tokens, cursor, rightParenToken) {
+		helpMessage(tokens, cursor, "Expected right parenthesis")
+		return nil, initialCursor, false
+	}
+	cursor++
+
+	cte.query = query
+	return &cte, cursor, true
+}
+
 // 解析一个token
 func parseToken(tokens []*token, initialCursor uint, kind tokenKind) (*token, uint, bool) {
 	cursor := initialCursor
@@ -197,7 +492,7 @@ outer:
 		}
 
 		// Look for expression
-		exp, newCursor, ok := parseExpression(tokens, cursor, tokenFromSymbol(commaSymbol))
+		exp, newCursor, ok := parseExpression(tokens, cursor, append(delimiters, tokenFromSymbol(commaSymbol)), 0)
 		if !ok {
 			helpMessage(tokens, cursor, "Expected expression")
 			return nil, initialCursor, false
@@ -210,150 +505,1644 @@ outer:
 	return &exps, cursor, true
 }
 
-func parseExpression(tokens []*token, initialCursor uint, _ token) (*expression, uint, bool) {
+// parseExpression 用 Pratt parsing 解析表达式, minBp 是当前允许的最小绑定力,
+// 绑定力不超过 minBp 的运算符留给上一层处理, 这样同级运算符是左结合的
+func parseExpression(tokens []*token, initialCursor uint, delimiters []token, minBp uint) (*expression, uint, bool) {
 	cursor := initialCursor
 
-	kinds := []tokenKind{identifierKind, numericKind, stringKind}
-	for _, kind := range kinds {
-		t, newCursor, ok := parseToken(tokens, cursor, kind)
-		if ok {
-			return &expression{
-				literal: t,
-				kind:    literalKind,
-			}, newCursor, true
+	var exp *expression
+	if expectToken(tokens, cursor, tokenFromSymbol(leftParenSymbol)) {
+		cursor++
+		rightParenToken := tokenFromSymbol(rightParenSymbol)
+
+		inner, newCursor, ok := parseExpression(tokens, cursor, append(delimiters, rightParenToken), 0)
+		if !ok {
+			helpMessage(tokens, cursor, "Expected expression after opening paren")
+			return nil, initialCursor, false
 		}
-	}
+		cursor = newCursor
 
-	return nil, initialCursor, false
-}
+		if !expectToken(tokens, cursor, rightParenToken) {
+			helpMessage(tokens, cursor, "Expected closing paren")
+			return nil, initialCursor, false
+		}
+		cursor++
 
-/*
-Insert mode
-1. INSERT
-2. INTO
-3. $table-name
-4. VALUES
-5. (
-6. $expression [, ...]
-7. )
-*/
-func parseInsertStatement(tokens []*token, initialCursor uint, delimiter token) (*InsertStatement, uint, bool) {
-	cursor := initialCursor
+		exp = inner
+	} else if expectToken(tokens, cursor, tokenFromKeyword(notKeyword)) {
+		op := tokens[cursor]
+		cursor++
 
-	// Look for INSERT
-	if !expectToken(tokens, cursor, tokenFromKeyword(insertKeyword)) {
-		return nil, initialCursor, false
-	}
-	cursor++
+		operand, newCursor, ok := parseExpression(tokens, cursor, delimiters, op.bindingPower())
+		if !ok {
+			helpMessage(tokens, cursor, "Expected expression after NOT")
+			return nil, initialCursor, false
+		}
+		cursor = newCursor
 
-	// Look for INTO
-	if !expectToken(tokens, cursor, tokenFromKeyword(intoKeyword)) {
-		helpMessage(tokens, cursor, "Expected into")
-		return nil, initialCursor, false
-	}
-	cursor++
+		exp = &expression{
+			unary: &unaryExpression{
+				operand: *operand,
+				op:      *op,
+			},
+			kind: unaryKind,
+		}
+	} else if expectToken(tokens, cursor, tokenFromSymbol(minusSymbol)) {
+		op := tokens[cursor]
+		cursor++
 
-	// Look for table name
-	table, newCursor, ok := parseToken(tokens, cursor, identifierKind)
-	if !ok {
-		helpMessage(tokens, cursor, "Expected table name")
-		return nil, initialCursor, false
-	}
-	cursor = newCursor
+		operand, newCursor, ok := parseExpression(tokens, cursor, delimiters, unaryMinusBindingPower)
+		if !ok {
+			helpMessage(tokens, cursor, "Expected expression after -")
+			return nil, initialCursor, false
+		}
+		cursor = newCursor
 
-	// Look for VALUES
-	if !expectToken(tokens, cursor, tokenFromKeyword(valuesKeyword)) {
-		helpMessage(tokens, cursor, "Expected VALUES")
-		return nil, initialCursor, false
-	}
-	cursor++
+		exp = &expression{
+			unary: &unaryExpression{
+				operand: *operand,
+				op:      *op,
+			},
+			kind: unaryKind,
+		}
+	} else if expectToken(tokens, cursor, tokenFromKeyword(arrayKeyword)) {
+		array, newCursor, ok := parseArrayExpression(tokens, cursor)
+		if !ok {
+			return nil, initialCursor, false
+		}
+		cursor = newCursor
 
-	// Look for left paren
-	if !expectToken(tokens, cursor, tokenFromSymbol(leftParenSymbol)) {
-		helpMessage(tokens, cursor, "Expected left paren")
-		return nil, initialCursor, false
-	}
-	cursor++
+		exp = array
+	} else if expectToken(tokens, cursor, tokenFromKeyword(anyKeyword)) || expectToken(tokens, cursor, tokenFromKeyword(allKeyword)) {
+		quantified, newCursor, ok := parseQuantifiedExpression(tokens, cursor)
+		if !ok {
+			return nil, initialCursor, false
+		}
+		cursor = newCursor
 
-	// Look for expression list
-	values, newCursor, ok := parseExpressions(tokens, cursor, []token{tokenFromSymbol(rightParenSymbol)})
-	if !ok {
-		return nil, initialCursor, false
+		exp = quantified
+	} else if expectToken(tokens, cursor, tokenFromKeyword(castKeyword)) {
+		cast, newCursor, ok := parseCastExpression(tokens, cursor)
+		if !ok {
+			return nil, initialCursor, false
+		}
+		cursor = newCursor
+
+		exp = cast
+	} else if isFunctionCall(tokens, cursor) {
+		function, newCursor, ok := parseFunctionCall(tokens, cursor)
+		if !ok {
+			return nil, initialCursor, false
+		}
+		cursor = newCursor
+
+		exp = function
+	} else if expectToken(tokens, cursor, tokenFromKeyword(defaultKeyword)) {
+		cursor++
+
+		exp = &expression{kind: defaultKind}
+	} else if isTypedLiteral(tokens, cursor) {
+		// DATE '2026-01-01' 这样的写法等价于 '2026-01-01'::date
+		exp = &expression{
+			cast: &castExpression{
+				value: expression{
+					literal: tokens[cursor+1],
+					kind:    literalKind,
+				},
+				datatype: dataType{name: *tokens[cursor]},
+			},
+			kind: castKind,
+		}
+		cursor += 2
+	} else {
+		literal, newCursor, ok := parseLiteralExpression(tokens, cursor)
+		if !ok {
+			return nil, initialCursor, false
+		}
+		cursor = newCursor
+
+		exp = literal
+	}
+
+outer:
+	for cursor < uint(len(tokens)) {
+		current := tokens[cursor]
+		for _, d := range delimiters {
+			if d.equals(current) {
+				break outer
+			}
+		}
+
+		// [NOT] LIKE | ILIKE | IN | BETWEEN
+		not := expectToken(tokens, cursor, tokenFromKeyword(notKeyword))
+		opCursor := cursor
+		if not {
+			opCursor++
+		}
+		if opCursor < uint(len(tokens)) && isPredicateKeyword(tokens[opCursor]) {
+			op := tokens[opCursor]
+			bp := op.bindingPower()
+			if bp <= minBp {
+				break
+			}
+
+			var predicate *expression
+			var newCursor uint
+			var ok bool
+			switch keyword(op.value) {
+			case likeKeyword, ilikeKeyword:
+				predicate, newCursor, ok = parseLikeExpression(tokens, opCursor+1, delimiters, *exp, bp)
+				if ok {
+					predicate.like.not = not
+					predicate.like.caseInsensitive = keyword(op.value) == ilikeKeyword
+				}
+			case inKeyword:
+				predicate, newCursor, ok = parseInExpression(tokens, opCursor+1, delimiters, *exp)
+				if ok {
+					predicate.in.not = not
+				}
+			case betweenKeyword:
+				predicate, newCursor, ok = parseBetweenExpression(tokens, opCursor+1, delimiters, *exp, bp)
+				if ok {
+					predicate.between.not = not
+				}
+			}
+			if !ok {
+				return nil, initialCursor, false
+			}
+
+			exp = predicate
+			cursor = newCursor
+			continue
+		}
+
+		// 单独出现的 NOT 只能作为前缀
+		if not {
+			break
+		}
+
+		// 后缀 ::type
+		if expectToken(tokens, cursor, tokenFromSymbol(castSymbol)) {
+			if tokens[cursor].bindingPower() <= minBp {
+				break
+			}
+
+			datatype, newCursor, ok := parseDataType(tokens, cursor+1)
+			if !ok {
+				helpMessage(tokens, cursor+1, "Expected type name after ::")
+				return nil, initialCursor, false
+			}
+
+			exp = &expression{
+				cast: &castExpression{
+					value:    *exp,
+					datatype: *datatype,
+				},
+				kind: castKind,
+			}
+			cursor = newCursor
+			continue
+		}
+
+		// 后缀下标 [index]
+		if expectToken(tokens, cursor, tokenFromSymbol(leftBracketSymbol)) {
+			if tokens[cursor].bindingPower() <= minBp {
+				break
+			}
+
+			rightBracketToken := tokenFromSymbol(rightBracketSymbol)
+			index, newCursor, ok := parseExpression(tokens, cursor+1, []token{rightBracketToken}, 0)
+			if !ok {
+				helpMessage(tokens, cursor+1, "Expected subscript")
+				return nil, initialCursor, false
+			}
+			cursor = newCursor
+
+			if !expectToken(tokens, cursor, rightBracketToken) {
+				helpMessage(tokens, cursor, "Expected closing bracket")
+				return nil, initialCursor, false
+			}
+			cursor++
+
+			exp = &expression{
+				subscript: &subscriptExpression{
+					value: *exp,
+					index: *index,
+				},
+				kind: subscriptKind,
+			}
+			continue
+		}
+
+		op := tokens[cursor]
+		bp := op.bindingPower()
+		// 不是二元运算符, 表达式到此结束, 交给调用方检查后续 token
+		if bp == 0 || bp <= minBp {
+			break
+		}
+
+		b, newCursor, ok := parseExpression(tokens, cursor+1, delimiters, bp)
+		if !ok {
+			helpMessage(tokens, cursor+1, "Expected right operand")
+			return nil, initialCursor, false
+		}
+
+		exp = &expression{
+			binary: &binaryExpression{
+				a:  *exp,
+				b:  *b,
+				op: *op,
+			},
+			kind: binaryKind,
+		}
+		cursor = newCursor
+	}
+
+	return exp, cursor, true
+}
+
+func parseLiteralExpression(tokens []*token, initialCursor uint) (*expression, uint, bool) {
+	cursor := initialCursor
+
+	kinds := []tokenKind{identifierKind, numericKind, stringKind, boolKind, nullKind, byteaKind, parameterKind}
+	for _, kind := range kinds {
+		t, newCursor, ok := parseToken(tokens, cursor, kind)
+		if ok {
+			return &expression{
+				literal: t,
+				kind:    literalKind,
+			}, newCursor, true
+		}
+	}
+
+	return nil, initialCursor, false
+}
+
+// 标识符后面紧跟左括号就是函数调用
+func isFunctionCall(tokens []*token, cursor uint) bool {
+	return cursor+1 < uint(len(tokens)) &&
+		tokens[cursor].kind == identifierKind &&
+		expectToken(tokens, cursor+1, tokenFromSymbol(leftParenSymbol))
+}
+
+// 类型名后面紧跟字符串, 例如 TIMESTAMP '2026-01-01 10:00'
+func isTypedLiteral(tokens []*token, cursor uint) bool {
+	if cursor+1 >= uint(len(tokens)) || tokens[cursor+1].kind != stringKind {
+		return false
+	}
+
+	_, ok := columnTypeFromName(tokens[cursor].value)
+	return ok && (tokens[cursor].kind == identifierKind || tokens[cursor].kind == keywordKind)
+}
+
+/*
+Function call mode
+1. $function-name
+2. (
+3. [$expression [, ...]] | *
+4. )
+5. [OVER $window-definition]
+EXTRACT($field FROM $expression) 会被转换成 extract('$field', $expression)
+*/
+func parseFunctionCall(tokens []*token, initialCursor uint) (*expression, uint, bool) {
+	cursor := initialCursor
+
+	name, newCursor, ok := parseToken(tokens, cursor, identifierKind)
+	if !ok {
+		return nil, initialCursor, false
+	}
+	cursor = newCursor
+
+	if !expectToken(tokens, cursor, tokenFromSymbol(leftParenSymbol)) {
+		helpMessage(tokens, cursor, "Expected left paren")
+		return nil, initialCursor, false
+	}
+	cursor++
+
+	var args []*expression
+	star := false
+	if name.value == "extract" {
+		field, newCursor, ok := parseToken(tokens, cursor, identifierKind)
+		if !ok {
+			helpMessage(tokens, cursor, "Expected field name in EXTRACT")
+			return nil, initialCursor, false
+		}
+		cursor = newCursor
+
+		if !expectToken(tokens, cursor, tokenFromKeyword(fromKeyword)) {
+			helpMessage(tokens, cursor, "Expected FROM in EXTRACT")
+			return nil, initialCursor, false
+		}
+		cursor++
+
+		source, newCursor, ok := parseExpression(tokens, cursor, []token{tokenFromSymbol(rightParenSymbol)}, 0)
+		if !ok {
+			helpMessage(tokens, cursor, "Expected expression in EXTRACT")
+			return nil, initialCursor, false
+		}
+		cursor = newCursor
+
+		args = []*expression{
+			{
+				literal: &token{value: field.value, kind: stringKind, loc: field.loc},
+				kind:    literalKind,
+			},
+			source,
+		}
+	} else if expectToken(tokens, cursor, tokenFromSymbol(asteriskSymbol)) && expectToken(tokens, cursor+1, tokenFromSymbol(rightParenSymbol)) {
+		cursor++
+		star = true
+	} else {
+		exps, newCursor, ok := parseExpressions(tokens, cursor, []token{tokenFromSymbol(rightParenSymbol)})
+		if !ok {
+			return nil, initialCursor, false
+		}
+		cursor = newCursor
+
+		args = *exps
+	}
+
+	if !expectToken(tokens, cursor, tokenFromSymbol(rightParenSymbol)) {
+		helpMessage(tokens, cursor, "Expected right paren")
+		return nil, initialCursor, false
+	}
+	cursor++
+
+	var over *windowDefinition
+	if expectIdentifier(tokens, cursor, "over") {
+		over, newCursor, ok = parseWindowDefinition(tokens, cursor+1)
+		if !ok {
+			return nil, initialCursor, false
+		}
+		cursor = newCursor
+	}
+
+	return &expression{
+		function: &functionCall{
+			name: *name,
+			args: args,
+			star: star,
+			over: over,
+		},
+		kind: functionKind,
+	}, cursor, true
+}
+
+// $expression [ASC | DESC] [, ...], ORDER BY 后面的部分
+func parseOrderBy(tokens []*token, initialCursor uint, delimiters []token) ([]*orderItem, uint, bool) {
+	cursor := initialCursor
+
+	delimiters = append([]token{tokenFromSymbol(commaSymbol)}, delimiters...)
+	var orderBy []*orderItem
+	for {
+		exp, newCursor, ok := parseExpression(tokens, cursor, delimiters, 0)
+		if !ok {
+			helpMessage(tokens, cursor, "Expected ORDER BY expression")
+			return nil, initialCursor, false
+		}
+		cursor = newCursor
+
+		item := orderItem{exp: *exp, positional: isIntegerLiteral(*exp)}
+		if expectIdentifier(tokens, cursor, "asc") {
+			cursor++
+		} else if expectIdentifier(tokens, cursor, "desc") {
+			cursor++
+			item.desc = true
+		}
+		orderBy = append(orderBy, &item)
+
+		if !expectToken(tokens, cursor, tokenFromSymbol(commaSymbol)) {
+			break
+		}
+		cursor++
+	}
+
+	return orderBy, cursor, true
+}
+
+/*
+Window definition
+1. (
+2. [PARTITION BY $expression [, ...]]
+3. [ORDER BY $expression [ASC | DESC] [, ...]]
+4. [{ROWS | RANGE} {$frame-start | BETWEEN $frame-start AND $frame-end}]
+5. )
+*/
+func parseWindowDefinition(tokens []*token, initialCursor uint) (*windowDefinition, uint, bool) {
+	cursor := initialCursor
+
+	if !expectToken(tokens, cursor, tokenFromSymbol(leftParenSymbol)) {
+		helpMessage(tokens, cursor, "Expected left paren after OVER")
+		return nil, initialCursor, false
+	}
+	cursor++
+
+	rightParenToken := tokenFromSymbol(rightParenSymbol)
+	orderToken := token{kind: identifierKind, value: "order"}
+	rowsToken := token{kind: identifierKind, value: "rows"}
+	rangeToken := token{kind: identifierKind, value: "range"}
+
+	window := windowDefinition{}
+	if expectIdentifier(tokens, cursor, "partition") && expectIdentifier(tokens, cursor+1, "by") {
+		cursor += 2
+
+		exps, newCursor, ok := parseExpressions(tokens, cursor, []token{rightParenToken, orderToken, rowsToken, rangeToken})
+		if !ok || len(*exps) == 0 {
+			helpMessage(tokens, cursor, "Expected PARTITION BY expressions")
+			return nil, initialCursor, false
+		}
+		cursor = newCursor
+
+		window.partitionBy = *exps
+	}
+
+	if expectIdentifier(tokens, cursor, "order") && expectIdentifier(tokens, cursor+1, "by") {
+		cursor += 2
+
+		orderBy, newCursor, ok := parseOrderBy(tokens, cursor, []token{rightParenToken, rowsToken, rangeToken})
+		if !ok {
+			return nil, initialCursor, false
+		}
+		cursor = newCursor
+
+		window.orderBy = orderBy
+	}
+
+	if expectToken(tokens, cursor, rowsToken) || expectToken(tokens, cursor, rangeToken) {
+		frame := windowFrame{mode: rowsFrame}
+		if expectToken(tokens, cursor, rangeToken) {
+			frame.mode = rangeFrame
+		}
+		cursor++
+
+		between := expectToken(tokens, cursor, tokenFromKeyword(betweenKeyword))
+		if between {
+			cursor++
+		}
+
+		start, newCursor, ok := parseFrameBound(tokens, cursor)
+		if !ok {
+			return nil, initialCursor, false
+		}
+		cursor = newCursor
+		frame.start = *start
+		frame.end = frameBound{kind: currentRowBound}
+
+		if between {
+			if !expectToken(tokens, cursor, tokenFromKeyword(andKeyword)) {
+				helpMessage(tokens, cursor, "Expected AND in frame clause")
+				return nil, initialCursor, false
+			}
+			cursor++
+
+			end, newCursor, ok := parseFrameBound(tokens, cursor)
+			if !ok {
+				return nil, initialCursor, false
+			}
+			cursor = newCursor
+			frame.end = *end
+		}
+
+		// 和 PostgreSQL 一样, 起点不能在终点的后面
+		if frame.start.kind == unboundedFollowingBound || frame.end.kind == unboundedPrecedingBound || frame.start.kind > frame.end.kind {
+			helpMessage(tokens, cursor, "Frame starting point cannot be after its ending point")
+			return nil, initialCursor, false
+		}
+
+		window.frame = &frame
+	}
+
+	if !expectToken(tokens, cursor, rightParenToken) {
+		helpMessage(tokens, cursor, "Expected right paren after window definition")
+		return nil, initialCursor, false
+	}
+	cursor++
+
+	return &window, cursor, true
+}
+
+// UNBOUNDED {PRECEDING | FOLLOWING} | CURRENT ROW | $offset {PRECEDING | FOLLOWING}
+func parseFrameBound(tokens []*token, initialCursor uint) (*frameBound, uint, bool) {
+	cursor := initialCursor
+
+	if expectIdentifier(tokens, cursor, "current") && expectIdentifier(tokens, cursor+1, "row") {
+		return &frameBound{kind: currentRowBound}, cursor + 2, true
+	}
+
+	bound := frameBound{}
+	if expectIdentifier(tokens, cursor, "unbounded") {
+		cursor++
+	} else {
+		preceding := token{kind: identifierKind, value: "preceding"}
+		following := token{kind: identifierKind, value: "following"}
+		offset, newCursor, ok := parseExpression(tokens, cursor, []token{preceding, following}, 0)
+		if !ok {
+			helpMessage(tokens, cursor, "Expected frame offset")
+			return nil, initialCursor, false
+		}
+		cursor = newCursor
+		bound.offset = offset
+	}
+
+	switch {
+	case expectIdentifier(tokens, cursor, "preceding") && bound.offset == nil:
+		bound.kind = unboundedPrecedingBound
+	case expectIdentifier(tokens, cursor, "preceding"):
+		bound.kind = precedingBound
+	case expectIdentifier(tokens, cursor, "following") && bound.offset == nil:
+		bound.kind = unboundedFollowingBound
+	case expectIdentifier(tokens, cursor, "following"):
+		bound.kind = followingBound
+	default:
+		helpMessage(tokens, cursor, "Expected PRECEDING or FOLLOWING")
+		return nil, initialCursor, false
+	}
+	cursor++
+
+	return &bound, cursor, true
+}
+
+// ARRAY[$expression [, ...]]
+func parseArrayExpression(tokens []*token, initialCursor uint) (*expression, uint, bool) {
+	cursor := initialCursor
+
+	if !expectToken(tokens, cursor, tokenFromKeyword(arrayKeyword)) {
+		return nil, initialCursor, false
+	}
+	cursor++
+
+	if !expectToken(tokens, cursor, tokenFromSymbol(leftBracketSymbol)) {
+		helpMessage(tokens, cursor, "Expected left bracket after ARRAY")
+		return nil, initialCursor, false
+	}
+	cursor++
+
+	rightBracketToken := tokenFromSymbol(rightBracketSymbol)
+	elements, newCursor, ok := parseExpressions(tokens, cursor, []token{rightBracketToken})
+	if !ok {
+		return nil, initialCursor, false
+	}
+	cursor = newCursor
+
+	if !expectToken(tokens, cursor, rightBracketToken) {
+		helpMessage(tokens, cursor, "Expected right bracket")
+		return nil, initialCursor, false
+	}
+	cursor++
+
+	return &expression{
+		array: &arrayExpression{
+			elements: *elements,
+		},
+		kind: arrayKind,
+	}, cursor, true
+}
+
+// ANY($expression) 或 ALL($expression)
+func parseQuantifiedExpression(tokens []*token, initialCursor uint) (*expression, uint, bool) {
+	cursor := initialCursor
+	all := expectToken(tokens, cursor, tokenFromKeyword(allKeyword))
+	cursor++
+
+	if !expectToken(tokens, cursor, tokenFromSymbol(leftParenSymbol)) {
+		helpMessage(tokens, cursor, "Expected left paren after ANY or ALL")
+		return nil, initialCursor, false
+	}
+	cursor++
+
+	rightParenToken := tokenFromSymbol(rightParenSymbol)
+	array, newCursor, ok := parseExpression(tokens, cursor, []token{rightParenToken}, 0)
+	if !ok {
+		helpMessage(tokens, cursor, "Expected array expression")
+		return nil, initialCursor, false
+	}
+	cursor = newCursor
+
+	if !expectToken(tokens, cursor, rightParenToken) {
+		helpMessage(tokens, cursor, "Expected right paren")
+		return nil, initialCursor, false
+	}
+	cursor++
+
+	return &expression{
+		quantified: &quantifiedExpression{
+			array: *array,
+			all:   all,
+		},
+		kind: quantifiedKind,
+	}, cursor, true
+}
+
+// CAST($expression AS $type)
+func parseCastExpression(tokens []*token, initialCursor uint) (*expression, uint, bool) {
+	cursor := initialCursor
+
+	if !expectToken(tokens, cursor, tokenFromKeyword(castKeyword)) {
+		return nil, initialCursor, false
+	}
+	cursor++
+
+	if !expectToken(tokens, cursor, tokenFromSymbol(leftParenSymbol)) {
+		helpMessage(tokens, cursor, "Expected left paren after CAST")
+		return nil, initialCursor, false
+	}
+	cursor++
+
+	value, newCursor, ok := parseExpression(tokens, cursor, []token{tokenFromKeyword(asKeyword)}, 0)
+	if !ok {
+		helpMessage(tokens, cursor, "Expected expression to cast")
+		return nil, initialCursor, false
+	}
+	cursor = newCursor
+
+	if !expectToken(tokens, cursor, tokenFromKeyword(asKeyword)) {
+		helpMessage(tokens, cursor, "Expected AS in CAST")
+		return nil, initialCursor, false
+	}
+	cursor++
+
+	datatype, newCursor, ok := parseDataType(tokens, cursor)
+	if !ok {
+		helpMessage(tokens, cursor, "Expected type name")
+		return nil, initialCursor, false
+	}
+	cursor = newCursor
+
+	if !expectToken(tokens, cursor, tokenFromSymbol(rightParenSymbol)) {
+		helpMessage(tokens, cursor, "Expected right paren")
+		return nil, initialCursor, false
+	}
+	cursor++
+
+	return &expression{
+		cast: &castExpression{
+			value:    *value,
+			datatype: *datatype,
+		},
+		kind: castKind,
+	}, cursor, true
+}
+
+// 类型名既可能是关键字 (int, text), 也可能是普通标识符 (bool, integer)
+func parseTypeName(tokens []*token, initialCursor uint) (*token, uint, bool) {
+	if t, newCursor, ok := parseToken(tokens, initialCursor, keywordKind); ok {
+		return t, newCursor, true
+	}
+
+	t, cursor, ok := parseToken(tokens, initialCursor, identifierKind)
+	if !ok {
+		return nil, initialCursor, false
+	}
+
+	// 由两个单词组成的类型名
+	twoWordTypes := map[string]string{
+		"double":    "precision",
+		"character": "varying",
+	}
+	if second, ok := twoWordTypes[t.value]; ok && cursor < uint(len(tokens)) && tokens[cursor].kind == identifierKind && tokens[cursor].value == second {
+		return &token{
+			value: t.value + " " + second,
+			kind:  identifierKind,
+			loc:   t.loc,
+		}, cursor + 1, true
+	}
+
+	return t, cursor, true
+}
+
+// 类型名后面可以跟括号括起来的数字参数, 例如 varchar(64), numeric(10, 2)
+func parseDataType(tokens []*token, initialCursor uint) (*dataType, uint, bool) {
+	cursor := initialCursor
+
+	name, newCursor, ok := parseTypeName(tokens, cursor)
+	if !ok {
+		return nil, initialCursor, false
+	}
+	cursor = newCursor
+
+	dt := dataType{name: *name}
+	if !expectToken(tokens, cursor, tokenFromSymbol(leftParenSymbol)) {
+		return parseArraySuffix(tokens, cursor, &dt)
+	}
+	cursor++
+
+	for {
+		if len(dt.modifiers) > 0 {
+			if !expectToken(tokens, cursor, tokenFromSymbol(commaSymbol)) {
+				break
+			}
+			cursor++
+		}
+
+		modifier, newCursor, ok := parseToken(tokens, cursor, numericKind)
+		if !ok {
+			helpMessage(tokens, cursor, "Expected type modifier")
+			return nil, initialCursor, false
+		}
+		cursor = newCursor
+
+		dt.modifiers = append(dt.modifiers, modifier)
+	}
+
+	if !expectToken(tokens, cursor, tokenFromSymbol(rightParenSymbol)) {
+		helpMessage(tokens, cursor, "Expected right paren after type modifiers")
+		return nil, initialCursor, false
+	}
+	cursor++
+
+	return parseArraySuffix(tokens, cursor, &dt)
+}
+
+// 类型名后面的 [] 表示数组, 和 PostgreSQL 一样方括号里的长度会被忽略
+func parseArraySuffix(tokens []*token, initialCursor uint, dt *dataType) (*dataType, uint, bool) {
+	cursor := initialCursor
+
+	if !expectToken(tokens, cursor, tokenFromSymbol(leftBracketSymbol)) {
+		return dt, cursor, true
+	}
+	cursor++
+
+	if _, newCursor, ok := parseToken(tokens, cursor, numericKind); ok {
+		cursor = newCursor
+	}
+
+	if !expectToken(tokens, cursor, tokenFromSymbol(rightBracketSymbol)) {
+		helpMessage(tokens, cursor, "Expected right bracket after array type")
+		return nil, initialCursor, false
+	}
+	cursor++
+
+	dt.array = true
+	return dt, cursor, true
+}
+
+func isPredicateKeyword(t *token) bool {
+	if t.kind != keywordKind {
+		return false
+	}
+
+	switch keyword(t.value) {
+	case likeKeyword, ilikeKeyword, inKeyword, betweenKeyword:
+		return true
+	}
+
+	return false
+}
+
+// LIKE 右侧: $pattern [ESCAPE $escape]
+func parseLikeExpression(tokens []*token, initialCursor uint, delimiters []token, value expression, bp uint) (*expression, uint, bool) {
+	cursor := initialCursor
+
+	pattern, newCursor, ok := parseExpression(tokens, cursor, delimiters, bp)
+	if !ok {
+		helpMessage(tokens, cursor, "Expected LIKE pattern")
+		return nil, initialCursor, false
+	}
+	cursor = newCursor
+
+	like := likeExpression{
+		value:   value,
+		pattern: *pattern,
+	}
+
+	if expectToken(tokens, cursor, tokenFromKeyword(escapeKeyword)) {
+		cursor++
+
+		escape, newCursor, ok := parseExpression(tokens, cursor, delimiters, bp)
+		if !ok {
+			helpMessage(tokens, cursor, "Expected ESCAPE character")
+			return nil, initialCursor, false
+		}
+		cursor = newCursor
+
+		like.escape = escape
+	}
+
+	return &expression{
+		like: &like,
+		kind: likeKind,
+	}, cursor, true
+}
+
+// IN 右侧: ($expression [, ...])
+func parseInExpression(tokens []*token, initialCursor uint, delimiters []token, value expression) (*expression, uint, bool) {
+	cursor := initialCursor
+
+	if !expectToken(tokens, cursor, tokenFromSymbol(leftParenSymbol)) {
+		helpMessage(tokens, cursor, "Expected left paren after IN")
+		return nil, initialCursor, false
+	}
+	cursor++
+
+	list, newCursor, ok := parseExpressions(tokens, cursor, []token{tokenFromSymbol(rightParenSymbol)})
+	if !ok {
+		return nil, initialCursor, false
+	}
+	cursor = newCursor
+
+	if len(*list) == 0 {
+		helpMessage(tokens, cursor, "Expected at least one value in IN list")
+		return nil, initialCursor, false
+	}
+
+	if !expectToken(tokens, cursor, tokenFromSymbol(rightParenSymbol)) {
+		helpMessage(tokens, cursor, "Expected right paren")
+		return nil, initialCursor, false
+	}
+	cursor++
+
+	return &expression{
+		in: &inExpression{
+			value: value,
+			list:  *list,
+		},
+		kind: inKind,
+	}, cursor, true
+}
+
+// BETWEEN 右侧: $low AND $high, 两边的绑定力都高于 AND, 所以这里的 AND 不会被当成逻辑与
+func parseBetweenExpression(tokens []*token, initialCursor uint, delimiters []token, value expression, bp uint) (*expression, uint, bool) {
+	cursor := initialCursor
+
+	low, newCursor, ok := parseExpression(tokens, cursor, delimiters, bp)
+	if !ok {
+		helpMessage(tokens, cursor, "Expected lower bound of BETWEEN")
+		return nil, initialCursor, false
+	}
+	cursor = newCursor
+
+	if !expectToken(tokens, cursor, tokenFromKeyword(andKeyword)) {
+		helpMessage(tokens, cursor, "Expected AND in BETWEEN")
+		return nil, initialCursor, false
+	}
+	cursor++
+
+	high, newCursor, ok := parseExpression(tokens, cursor, delimiters, bp)
+	if !ok {
+		helpMessage(tokens, cursor, "Expected upper bound of BETWEEN")
+		return nil, initialCursor, false
+	}
+	cursor = newCursor
+
+	return &expression{
+		between: &betweenExpression{
+			value: value,
+			low:   *low,
+			high:  *high,
+		},
+		kind: betweenKind,
+	}, cursor, true
+}
+
+/*
+Insert mode
+1. INSERT
+2. INTO
+3. $table-name
+4. [($column-name [, ...])]
+5. VALUES
+6. (
+7. $expression [, ...]
+8. )
+*/
+func parseInsertStatement(tokens []*token, initialCursor uint, delimiter token) (*InsertStatement, uint, bool) {
+	cursor := initialCursor
+
+	// Look for INSERT
+	if !expectToken(tokens, cursor, tokenFromKeyword(insertKeyword)) {
+		return nil, initialCursor, false
+	}
+	cursor++
+
+	// Look for INTO
+	if !expectToken(tokens, cursor, tokenFromKeyword(intoKeyword)) {
+		helpMessage(tokens, cursor, "Expected into")
+		return nil, initialCursor, false
+	}
+	cursor++
+
+	// Look for table name
+	table, newCursor, ok := parseToken(tokens, cursor, identifierKind)
+	if !ok {
+		helpMessage(tokens, cursor, "Expected table name")
+		return nil, initialCursor, false
+	}
+	cursor = newCursor
+
+	// Look for column names
+	var columns []*token
+	if expectToken(tokens, cursor, tokenFromSymbol(leftParenSymbol)) {
+		cursor++
+
+		for !expectToken(tokens, cursor, tokenFromSymbol(rightParenSymbol)) {
+			if len(columns) > 0 {
+				if !expectToken(tokens, cursor, tokenFromSymbol(commaSymbol)) {
+					helpMessage(tokens, cursor, "Expected comma")
+					return nil, initialCursor, false
+				}
+				cursor++
+			}
+
+			column, newCursor, ok := parseToken(tokens, cursor, identifierKind)
+			if !ok {
+				helpMessage(tokens, cursor, "Expected column name")
+				return nil, initialCursor, false
+			}
+			cursor = newCursor
+
+			columns = append(columns, column)
+		}
+		cursor++
+	}
+
+	// Look for VALUES
+	if !expectToken(tokens, cursor, tokenFromKeyword(valuesKeyword)) {
+		helpMessage(tokens, cursor, "Expected VALUES")
+		return nil, initialCursor, false
+	}
+	cursor++
+
+	// Look for left paren
+	if !expectToken(tokens, cursor, tokenFromSymbol(leftParenSymbol)) {
+		helpMessage(tokens, cursor, "Expected left paren")
+		return nil, initialCursor, false
+	}
+	cursor++
+
+	// Look for expression list
+	values, newCursor, ok := parseExpressions(tokens, cursor, []token{tokenFromSymbol(rightParenSymbol)})
+	if !ok {
+		return nil, initialCursor, false
+	}
+	cursor = newCursor
+
+	// Look for right paren
+	if !expectToken(tokens, cursor, tokenFromSymbol(rightParenSymbol)) {
+		helpMessage(tokens, cursor, "Expected right paren")
+		return nil, initialCursor, false
+	}
+	cursor++
+
+	return &InsertStatement{
+		table:   *table,
+		columns: columns,
+		values:  values,
+	}, cursor, true
+}
+
+/*
+update mode
+1. UPDATE $table-name
+2. SET $column-name = $expression [, ...]
+3. [WHERE $expression]
+*/
+func parseUpdateStatement(tokens []*token, initialCursor uint, delimiter token) (*UpdateStatement, uint, bool) {
+	cursor := initialCursor
+
+	if !expectToken(tokens, cursor, tokenFromKeyword(updateKeyword)) {
+		return nil, initialCursor, false
+	}
+	cursor++
+
+	table, newCursor, ok := parseToken(tokens, cursor, identifierKind)
+	if !ok {
+		helpMessage(tokens, cursor, "Expected table name")
+		return nil, initialCursor, false
+	}
+	cursor = newCursor
+
+	if !expectToken(tokens, cursor, tokenFromKeyword(setKeyword)) {
+		helpMessage(tokens, cursor, "Expected SET")
+		return nil, initialCursor, false
+	}
+	cursor++
+
+	upd := UpdateStatement{table: *table}
+	whereToken := tokenFromKeyword(whereKeyword)
+	for {
+		column, newCursor, ok := parseToken(tokens, cursor, identifierKind)
+		if !ok {
+			helpMessage(tokens, cursor, "Expected column name")
+			return nil, initialCursor, false
+		}
+		cursor = newCursor
+
+		if !expectToken(tokens, cursor, tokenFromSymbol(eqSymbol)) {
+			helpMessage(tokens, cursor, "Expected =")
+			return nil, initialCursor, false
+		}
+		cursor++
+
+		value, newCursor, ok := parseExpression(tokens, cursor, []token{tokenFromSymbol(commaSymbol), whereToken, delimiter}, 0)
+		if !ok {
+			helpMessage(tokens, cursor, "Expected expression")
+			return nil, initialCursor, false
+		}
+		cursor = newCursor
+
+		upd.set = append(upd.set, &assignment{
+			column: *column,
+			value:  *value,
+		})
+
+		if !expectToken(tokens, cursor, tokenFromSymbol(commaSymbol)) {
+			break
+		}
+		cursor++
+	}
+
+	if expectToken(tokens, cursor, whereToken) {
+		cursor++
+
+		where, newCursor, ok := parseExpression(tokens, cursor, []token{delimiter}, 0)
+		if !ok {
+			helpMessage(tokens, cursor, "Expected WHERE conditionals")
+			return nil, initialCursor, false
+		}
+
+		upd.where = where
+		cursor = newCursor
+	}
+
+	return &upd, cursor, true
+}
+
+/*
+delete mode
+1. DELETE FROM $table-name
+2. [WHERE $expression]
+*/
+func parseDeleteStatement(tokens []*token, initialCursor uint, delimiter token) (*DeleteStatement, uint, bool) {
+	cursor := initialCursor
+
+	if !expectToken(tokens, cursor, tokenFromKeyword(deleteKeyword)) {
+		return nil, initialCursor, false
+	}
+	cursor++
+
+	if !expectToken(tokens, cursor, tokenFromKeyword(fromKeyword)) {
+		helpMessage(tokens, cursor, "Expected FROM")
+		return nil, initialCursor, false
+	}
+	cursor++
+
+	table, newCursor, ok := parseToken(tokens, cursor, identifierKind)
+	if !ok {
+		helpMessage(tokens, cursor, "Expected table name")
+		return nil, initialCursor, false
+	}
+	cursor = newCursor
+
+	del := DeleteStatement{table: *table}
+	if expectToken(tokens, cursor, tokenFromKeyword(whereKeyword)) {
+		cursor++
+
+		where, newCursor, ok := parseExpression(tokens, cursor, []token{delimiter}, 0)
+		if !ok {
+			helpMessage(tokens, cursor, "Expected WHERE conditionals")
+			return nil, initialCursor, false
+		}
+
+		del.where = where
+		cursor = newCursor
+	}
+
+	return &del, cursor, true
+}
+
+/*
+Create mode
+1. CREATE
+2. $table-name
+3. (
+4. [$column-name $column-type [, ...]]
+5. )
+*/
+func parseCreateTableStatement(tokens []*token, initialCursor uint, delimiter token) (*CreateTableStatement, uint, bool) {
+	cursor := initialCursor
+
+	if !expectToken(tokens, cursor, tokenFromKeyword(createKeyword)) {
+		return nil, initialCursor, false
+	}
+	cursor++
+
+	if !expectToken(tokens, cursor, tokenFromKeyword(tableKeyword)) {
+		return nil, initialCursor, false
+	}
+	cursor++
+
+	name, newCursor, ok := parseToken(tokens, cursor, identifierKind)
+	if !ok {
+		helpMessage(tokens, cursor, "Expected table name")
+		return nil, initialCursor, false
+	}
+	cursor = newCursor
+
+	if !expectToken(tokens, cursor, tokenFromSymbol(leftParenSymbol)) {
+		helpMessage(tokens, cursor, "Expected left parenthesis")
+		return nil, initialCursor, false
+	}
+	cursor++
+
+	cols, constraints, newCursor, ok := parseColumnDefinitions(tokens, cursor, tokenFromSymbol(rightParenSymbol))
+	if !ok {
+		return nil, initialCursor, false
+	}
+	cursor = newCursor
+
+	if !expectToken(tokens, cursor, tokenFromSymbol(rightParenSymbol)) {
+		helpMessage(tokens, cursor, "Expected right parenthesis")
+		return nil, initialCursor, false
+	}
+	cursor++
+
+	return &CreateTableStatement{
+		name:        *name,
+		cols:        cols,
+		constraints: constraints,
+	}, cursor, true
+}
+
+/*
+create view mode
+1. CREATE [OR REPLACE | MATERIALIZED] VIEW $view-name
+2. [($column-name [, ...])]
+3. AS $select-statement
+*/
+func parseCreateViewStatement(tokens []*token, initialCursor uint, delimiter token) (*CreateViewStatement, uint, bool) {
+	cursor := initialCursor
+
+	if !expectToken(tokens, cursor, tokenFromKeyword(createKeyword)) {
+		return nil, initialCursor, false
+	}
+	cursor++
+
+	crt := CreateViewStatement{}
+	if expectToken(tokens, cursor, tokenFromKeyword(orKeyword)) && expectIdentifier(tokens, cursor+1, "replace") {
+		crt.replace = true
+		cursor += 2
+	} else if expectIdentifier(tokens, cursor, "materialized") {
+		crt.materialized = true
+		cursor++
+	}
+
+	if !expectIdentifier(tokens, cursor, "view") {
+		return nil, initialCursor, false
+	}
+	cursor++
+
+	name, newCursor, ok := parseToken(tokens, cursor, identifierKind)
+	if !ok {
+		helpMessage(tokens, cursor, "Expected view name")
+		return nil, initialCursor, false
+	}
+	cursor = newCursor
+	crt.name = *name
+
+	if expectToken(tokens, cursor, tokenFromSymbol(leftParenSymbol)) {
+		columns, newCursor, ok := parseColumnList(tokens, cursor)
+		if !ok {
+			return nil, initialCursor, false
+		}
+		cursor = newCursor
+
+		crt.columns = columns
+	}
+
+	if !expectToken(tokens, cursor, tokenFromKeyword(asKeyword)) {
+		helpMessage(tokens, cursor, "Expected AS")
+		return nil, initialCursor, false
+	}
+	cursor++
+
+	query, newCursor, ok := parseSelectStatement(tokens, cursor, delimiter)
+	if !ok {
+		helpMessage(tokens, cursor, "Expected SELECT statement")
+		return nil, initialCursor, false
+	}
+	cursor = newCursor
+	crt.query = query
+
+	return &crt, cursor, true
+}
+
+// DROP [MATERIALIZED] VIEW [IF EXISTS] $view-name
+func parseDropViewStatement(tokens []*token, initialCursor uint, delimiter token) (*DropViewStatement, uint, bool) {
+	cursor := initialCursor
+
+	if !expectToken(tokens, cursor, tokenFromKeyword(dropKeyword)) {
+		return nil, initialCursor, false
+	}
+	cursor++
+
+	drop := DropViewStatement{}
+	if expectIdentifier(tokens, cursor, "materialized") {
+		drop.materialized = true
+		cursor++
+	}
+
+	if !expectIdentifier(tokens, cursor, "view") {
+		return nil, initialCursor, false
+	}
+	cursor++
+	if expectIdentifier(tokens, cursor, "if") && expectIdentifier(tokens, cursor+1, "exists") {
+		drop.ifExists = true
+		cursor += 2
+	}
+
+	name, newCursor, ok := parseToken(tokens, cursor, identifierKind)
+	if !ok {
+		helpMessage(tokens, cursor, "Expected view name")
+		return nil, initialCursor, false
+	}
+	cursor = newCursor
+	drop.name = *name
+
+	return &drop, cursor, true
+}
+
+// REFRESH MATERIALIZED VIEW $view-name
+func parseRefreshMaterializedViewStatement(tokens []*token, initialCursor uint, delimiter token) (*RefreshMaterializedViewStatement, uint, bool) {
+	cursor := initialCursor
+
+	if !expectIdentifier(tokens, cursor, "refresh") || !expectIdentifier(tokens, cursor+1, "materialized") ||
+		!expectIdentifier(tokens, cursor+2, "view") {
+		return nil, initialCursor, false
+	}
+	cursor += 3
+
+	name, newCursor, ok := parseToken(tokens, cursor, identifierKind)
+	if !ok {
+		helpMessage(tokens, cursor, "Expected view name")
+		return nil, initialCursor, false
+	}
+	cursor = newCursor
+
+	return &RefreshMaterializedViewStatement{name: *name}, cursor, true
+}
+
+// EXPLAIN [ANALYZE] $select
+func parseExplainStatement(tokens []*token, initialCursor uint, delimiter token) (*ExplainStatement, uint, bool) {
+	cursor := initialCursor
+
+	if !expectIdentifier(tokens, cursor, "explain") {
+		return nil, initialCursor, false
+	}
+	cursor++
+
+	analyze := false
+	if expectIdentifier(tokens, cursor, "analyze") {
+		analyze = true
+		cursor++
+	}
+
+	slct, newCursor, ok := parseSelectStatement(tokens, cursor, delimiter)
+	if !ok {
+		helpMessage(tokens, cursor, "Expected SELECT after EXPLAIN")
+		return nil, initialCursor, false
+	}
+	cursor = newCursor
+
+	return &ExplainStatement{analyze: analyze, query: slct}, cursor, true
+}
+
+/*
+alter table mode
+1. ALTER TABLE $table-name
+2. $action [, ...]
+*/
+func parseAlterTableStatement(tokens []*token, initialCursor uint, delimiter token) (*AlterTableStatement, uint, bool) {
+	cursor := initialCursor
+
+	if !expectToken(tokens, cursor, tokenFromKeyword(alterKeyword)) || !expectToken(tokens, cursor+1, tokenFromKeyword(tableKeyword)) {
+		return nil, initialCursor, false
+	}
+	cursor += 2
+
+	name, newCursor, ok := parseToken(tokens, cursor, identifierKind)
+	if !ok {
+		helpMessage(tokens, cursor, "Expected table name")
+		return nil, initialCursor, false
 	}
 	cursor = newCursor
 
-	// Look for right paren
-	if !expectToken(tokens, cursor, tokenFromSymbol(rightParenSymbol)) {
-		helpMessage(tokens, cursor, "Expected right paren")
-		return nil, initialCursor, false
+	alt := AlterTableStatement{table: *name}
+	for {
+		action, newCursor, ok := parseAlterTableAction(tokens, cursor, delimiter)
+		if !ok {
+			return nil, initialCursor, false
+		}
+		cursor = newCursor
+
+		alt.actions = append(alt.actions, action)
+
+		if !expectToken(tokens, cursor, tokenFromSymbol(commaSymbol)) {
+			break
+		}
+		cursor++
+	}
+
+	return &alt, cursor, true
+}
+
+/*
+alter table action mode
+1. ADD [COLUMN] $column-definition | ADD $table-constraint
+2. DROP [COLUMN] $column-name | DROP CONSTRAINT $constraint-name
+3. RENAME [COLUMN] $column-name TO $new-name | RENAME TO $new-name
+4. ALTER [COLUMN] $column-name [SET DATA] TYPE $type [USING $expression]
+*/
+func parseAlterTableAction(tokens []*token, initialCursor uint, delimiter token) (*alterTableAction, uint, bool) {
+	cursor := initialCursor
+	action := alterTableAction{}
+
+	switch {
+	case expectIdentifier(tokens, cursor, "add"):
+		cursor++
+
+		if c, newCursor, ok := parseConstraint(tokens, cursor, nil); ok {
+			action.kind = addConstraintKind
+			action.constraint = c
+			return &action, newCursor, true
+		}
+
+		if expectIdentifier(tokens, cursor, "column") {
+			cursor++
+		}
+
+		cd, newCursor, ok := parseColumnDefinition(tokens, cursor)
+		if !ok {
+			return nil, initialCursor, false
+		}
+		cursor = newCursor
+
+		action.kind = addColumnKind
+		action.column = cd
+		return &action, cursor, true
+	case expectToken(tokens, cursor, tokenFromKeyword(dropKeyword)):
+		cursor++
+
+		action.kind = dropColumnKind
+		if expectToken(tokens, cursor, tokenFromKeyword(constraintKeyword)) {
+			action.kind = dropConstraintKind
+			cursor++
+		} else if expectIdentifier(tokens, cursor, "column") {
+			cursor++
+		}
+
+		name, newCursor, ok := parseToken(tokens, cursor, identifierKind)
+		if !ok {
+			helpMessage(tokens, cursor, "Expected column or constraint name")
+			return nil, initialCursor, false
+		}
+		cursor = newCursor
+
+		action.name = *name
+		return &action, cursor, true
+	case expectIdentifier(tokens, cursor, "rename"):
+		cursor++
+
+		action.kind = renameColumnKind
+		if expectIdentifier(tokens, cursor, "to") {
+			action.kind = renameTableKind
+		} else {
+			if expectIdentifier(tokens, cursor, "column") {
+				cursor++
+			}
+
+			name, newCursor, ok := parseToken(tokens, cursor, identifierKind)
+			if !ok {
+				helpMessage(tokens, cursor, "Expected column name")
+				return nil, initialCursor, false
+			}
+			cursor = newCursor
+
+			action.name = *name
+		}
+
+		if !expectIdentifier(tokens, cursor, "to") {
+			helpMessage(tokens, cursor, "Expected TO")
+			return nil, initialCursor, false
+		}
+		cursor++
+
+		newName, newCursor, ok := parseToken(tokens, cursor, identifierKind)
+		if !ok {
+			helpMessage(tokens, cursor, "Expected new name")
+			return nil, initialCursor, false
+		}
+		cursor = newCursor
+
+		action.newName = *newName
+		return &action, cursor, true
+	case expectToken(tokens, cursor, tokenFromKeyword(alterKeyword)):
+		cursor++
+
+		if expectIdentifier(tokens, cursor, "column") {
+			cursor++
+		}
+
+		name, newCursor, ok := parseToken(tokens, cursor, identifierKind)
+		if !ok {
+			helpMessage(tokens, cursor, "Expected column name")
+			return nil, initialCursor, false
+		}
+		cursor = newCursor
+
+		if expectToken(tokens, cursor, tokenFromKeyword(setKeyword)) && expectIdentifier(tokens, cursor+1, "data") {
+			cursor += 2
+		}
+
+		if !expectIdentifier(tokens, cursor, "type") {
+			helpMessage(tokens, cursor, "Expected TYPE")
+			return nil, initialCursor, false
+		}
+		cursor++
+
+		dt, newCursor, ok := parseDataType(tokens, cursor)
+		if !ok {
+			helpMessage(tokens, cursor, "Expected column type")
+			return nil, initialCursor, false
+		}
+		cursor = newCursor
+
+		action.kind = alterColumnTypeKind
+		action.name = *name
+		action.datatype = *dt
+
+		if expectIdentifier(tokens, cursor, "using") {
+			cursor++
+
+			using, newCursor, ok := parseExpression(tokens, cursor, []token{tokenFromSymbol(commaSymbol), delimiter}, 0)
+			if !ok {
+				helpMessage(tokens, cursor, "Expected USING expression")
+				return nil, initialCursor, false
+			}
+			cursor = newCursor
+
+			action.using = using
+		}
+
+		return &action, cursor, true
 	}
-	cursor++
 
-	return &InsertStatement{
-		table:  *table,
-		values: values,
-	}, cursor, true
+	helpMessage(tokens, cursor, "Expected ADD, DROP, RENAME or ALTER")
+	return nil, initialCursor, false
 }
 
-/*
-Create mode
-1. CREATE
-2. $table-name
-3. (
-4. [$column-name $column-type [, ...]]
-5. )
-*/
-func parseCreateTableStatement(tokens []*token, initialCursor uint, delimiter token) (*CreateTableStatement, uint, bool) {
-	cursor := initialCursor
+// expectIdentifier 匹配不是保留字的关键字, 例如 CREATE TYPE 里的 type 和 enum
+func expectIdentifier(tokens []*token, cursor uint, value string) bool {
+	return expectToken(tokens, cursor, token{kind: identifierKind, value: value})
+}
 
-	if !expectToken(tokens, cursor, tokenFromKeyword(createKeyword)) {
-		return nil, initialCursor, false
-	}
-	cursor++
+// CREATE TYPE $type-name AS ENUM ($string [, ...])
+func parseCreateTypeStatement(tokens []*token, initialCursor uint, delimiter token) (*CreateTypeStatement, uint, bool) {
+	cursor := initialCursor
 
-	if !expectToken(tokens, cursor, tokenFromKeyword(tableKeyword)) {
+	if !expectToken(tokens, cursor, tokenFromKeyword(createKeyword)) || !expectIdentifier(tokens, cursor+1, "type") {
 		return nil, initialCursor, false
 	}
-	cursor++
+	cursor += 2
 
 	name, newCursor, ok := parseToken(tokens, cursor, identifierKind)
 	if !ok {
-		helpMessage(tokens, cursor, "Expected table name")
+		helpMessage(tokens, cursor, "Expected type name")
 		return nil, initialCursor, false
 	}
 	cursor = newCursor
 
+	if !expectToken(tokens, cursor, tokenFromKeyword(asKeyword)) || !expectIdentifier(tokens, cursor+1, "enum") {
+		helpMessage(tokens, cursor, "Expected AS ENUM")
+		return nil, initialCursor, false
+	}
+	cursor += 2
+
 	if !expectToken(tokens, cursor, tokenFromSymbol(leftParenSymbol)) {
 		helpMessage(tokens, cursor, "Expected left parenthesis")
 		return nil, initialCursor, false
 	}
 	cursor++
 
-	cols, newCursor, ok := parseColumnDefinitions(tokens, cursor, tokenFromSymbol(rightParenSymbol))
+	crt := CreateTypeStatement{name: *name}
+	for !expectToken(tokens, cursor, tokenFromSymbol(rightParenSymbol)) {
+		if len(crt.labels) > 0 {
+			if !expectToken(tokens, cursor, tokenFromSymbol(commaSymbol)) {
+				helpMessage(tokens, cursor, "Expected comma")
+				return nil, initialCursor, false
+			}
+			cursor++
+		}
+
+		label, newCursor, ok := parseToken(tokens, cursor, stringKind)
+		if !ok {
+			helpMessage(tokens, cursor, "Expected enum label")
+			return nil, initialCursor, false
+		}
+		cursor = newCursor
+
+		crt.labels = append(crt.labels, label)
+	}
+	cursor++
+
+	return &crt, cursor, true
+}
+
+// CREATE SEQUENCE $sequence-name [$option ...]
+func parseCreateSequenceStatement(tokens []*token, initialCursor uint, delimiter token) (*CreateSequenceStatement, uint, bool) {
+	cursor := initialCursor
+
+	if !expectToken(tokens, cursor, tokenFromKeyword(createKeyword)) || !expectIdentifier(tokens, cursor+1, "sequence") {
+		return nil, initialCursor, false
+	}
+	cursor += 2
+
+	name, newCursor, ok := parseToken(tokens, cursor, identifierKind)
 	if !ok {
+		helpMessage(tokens, cursor, "Expected sequence name")
 		return nil, initialCursor, false
 	}
 	cursor = newCursor
 
-	if !expectToken(tokens, cursor, tokenFromSymbol(rightParenSymbol)) {
-		helpMessage(tokens, cursor, "Expected right parenthesis")
+	options, newCursor, ok := parseSequenceOptions(tokens, cursor)
+	if !ok {
 		return nil, initialCursor, false
 	}
-	cursor++
+	cursor = newCursor
 
-	return &CreateTableStatement{
-		name: *name,
-		cols: cols,
-	}, cursor, true
+	return &CreateSequenceStatement{name: *name, options: options}, cursor, true
+}
+
+// parseSequenceOptions 解析 CREATE SEQUENCE 和 AS IDENTITY (...) 里的选项, 遇到不认识的 token 就停下
+func parseSequenceOptions(tokens []*token, initialCursor uint) (sequenceOptions, uint, bool) {
+	cursor := initialCursor
+	options := sequenceOptions{}
+
+	for {
+		var target **int64
+		switch {
+		case expectIdentifier(tokens, cursor, "increment"):
+			target = &options.increment
+			cursor++
+			if expectIdentifier(tokens, cursor, "by") {
+				cursor++
+			}
+		case expectIdentifier(tokens, cursor, "start"):
+			target = &options.start
+			cursor++
+			if expectToken(tokens, cursor, tokenFromKeyword(withKeyword)) {
+				cursor++
+			}
+		case expectIdentifier(tokens, cursor, "minvalue"):
+			target = &options.minValue
+			cursor++
+		case expectIdentifier(tokens, cursor, "maxvalue"):
+			target = &options.maxValue
+			cursor++
+		default:
+			return options, cursor, true
+		}
+
+		if *target != nil {
+			helpMessage(tokens, cursor, "Conflicting or redundant sequence options")
+			return sequenceOptions{}, initialCursor, false
+		}
+
+		negative := expectToken(tokens, cursor, tokenFromSymbol(minusSymbol))
+		if negative {
+			cursor++
+		}
+
+		value, newCursor, ok := parseToken(tokens, cursor, numericKind)
+		if !ok {
+			helpMessage(tokens, cursor, "Expected integer")
+			return sequenceOptions{}, initialCursor, false
+		}
+
+		digits := value.value
+		if negative {
+			digits = "-" + digits
+		}
+
+		i, err := strconv.ParseInt(digits, 10, 64)
+		if err != nil {
+			helpMessage(tokens, cursor, "Expected integer")
+			return sequenceOptions{}, initialCursor, false
+		}
+		cursor = newCursor
+
+		*target = &i
+	}
 }
 
-func parseColumnDefinitions(tokens []*token, initialCursor uint, delimiter token) (*[]*columnDefinition, uint, bool) {
+// parseColumnDefinitions 解析 CREATE TABLE 括号里的列定义和表级约束
+func parseColumnDefinitions(tokens []*token, initialCursor uint, delimiter token) (*[]*columnDefinition, []*constraint, uint, bool) {
 	cursor := initialCursor
 
 	cds := []*columnDefinition{}
+	var constraints []*constraint
 	for {
 		if cursor >= uint(len(tokens)) {
-			return nil, initialCursor, false
+			return nil, nil, initialCursor, false
 		}
 
 		// Look for a delimiter
@@ -363,36 +2152,341 @@ func parseColumnDefinitions(tokens []*token, initialCursor uint, delimiter token
 		}
 
 		// Look for a comma
-		if len(cds) > 0 {
+		if len(cds) > 0 || len(constraints) > 0 {
 			if !expectToken(tokens, cursor, tokenFromSymbol(commaSymbol)) {
 				helpMessage(tokens, cursor, "Expected comma")
-				return nil, initialCursor, false
+				return nil, nil, initialCursor, false
 			}
 
 			cursor++
 		}
 
-		// Look for a column name
-		id, newCursor, ok := parseToken(tokens, cursor, identifierKind)
+		// Look for a table constraint
+		if c, newCursor, ok := parseConstraint(tokens, cursor, nil); ok {
+			cursor = newCursor
+
+			constraints = append(constraints, c)
+			continue
+		}
+
+		cd, newCursor, ok := parseColumnDefinition(tokens, cursor)
 		if !ok {
-			helpMessage(tokens, cursor, "Expected column name")
+			return nil, nil, initialCursor, false
+		}
+		cursor = newCursor
+
+		cds = append(cds, cd)
+	}
+
+	return &cds, constraints, cursor, true
+}
+
+// $column-name $type [$column-constraint ...]
+func parseColumnDefinition(tokens []*token, initialCursor uint) (*columnDefinition, uint, bool) {
+	cursor := initialCursor
+
+	// Look for a column name
+	id, newCursor, ok := parseToken(tokens, cursor, identifierKind)
+	if !ok {
+		helpMessage(tokens, cursor, "Expected column name")
+		return nil, initialCursor, false
+	}
+	cursor = newCursor
+
+	// Look for a column type
+	ty, newCursor, ok := parseDataType(tokens, cursor)
+	if !ok {
+		helpMessage(tokens, cursor, "Expected column type")
+		return nil, initialCursor, false
+	}
+	cursor = newCursor
+
+	cd := &columnDefinition{
+		name:     *id,
+		datatype: *ty,
+	}
+
+	newCursor, ok = parseColumnConstraints(tokens, cursor, cd)
+	if !ok {
+		return nil, initialCursor, false
+	}
+	cursor = newCursor
+
+	return cd, cursor, true
+}
+
+/*
+constraint mode
+1. [CONSTRAINT $name]
+2. CHECK ($expression)
+   | FOREIGN KEY ($column-name [, ...]) REFERENCES ... (表约束)
+   | REFERENCES $table-name ($column-name [, ...]) [ON DELETE $action] (列约束)
+*/
+// column 是列约束所在的列, 解析表约束时为 nil
+func parseConstraint(tokens []*token, initialCursor uint, column *token) (*constraint, uint, bool) {
+	cursor := initialCursor
+	c := constraint{}
+
+	if expectToken(tokens, cursor, tokenFromKeyword(constraintKeyword)) {
+		cursor++
+
+		name, newCursor, ok := parseToken(tokens, cursor, identifierKind)
+		if !ok {
+			helpMessage(tokens, cursor, "Expected constraint name")
+			return nil, initialCursor, false
+		}
+		cursor = newCursor
+
+		c.name = name
+	}
+
+	switch {
+	case expectToken(tokens, cursor, tokenFromKeyword(checkKeyword)):
+		cursor++
+
+		if !expectToken(tokens, cursor, tokenFromSymbol(leftParenSymbol)) {
+			helpMessage(tokens, cursor, "Expected left paren after CHECK")
+			return nil, initialCursor, false
+		}
+		cursor++
+
+		rightParenToken := tokenFromSymbol(rightParenSymbol)
+		check, newCursor, ok := parseExpression(tokens, cursor, []token{rightParenToken}, 0)
+		if !ok {
+			helpMessage(tokens, cursor, "Expected CHECK expression")
 			return nil, initialCursor, false
 		}
 		cursor = newCursor
 
-		// Look for a column type
-		ty, newCursor, ok := parseToken(tokens, cursor, keywordKind)
+		if !expectToken(tokens, cursor, rightParenToken) {
+			helpMessage(tokens, cursor, "Expected right paren")
+			return nil, initialCursor, false
+		}
+		cursor++
+
+		c.check = check
+	case column == nil && expectToken(tokens, cursor, tokenFromKeyword(foreignkeyKeyword)):
+		cursor++
+
+		columns, newCursor, ok := parseColumnList(tokens, cursor)
 		if !ok {
-			helpMessage(tokens, cursor, "Expected column type")
 			return nil, initialCursor, false
 		}
 		cursor = newCursor
 
-		cds = append(cds, &columnDefinition{
-			name:     *id,
-			datatype: *ty,
-		})
+		fk, newCursor, ok := parseReferences(tokens, cursor)
+		if !ok {
+			return nil, initialCursor, false
+		}
+		cursor = newCursor
+
+		fk.columns = columns
+		c.foreignKey = fk
+	case column != nil && expectToken(tokens, cursor, tokenFromKeyword(referencesKeyword)):
+		fk, newCursor, ok := parseReferences(tokens, cursor)
+		if !ok {
+			return nil, initialCursor, false
+		}
+		cursor = newCursor
+
+		fk.columns = []*token{column}
+		c.foreignKey = fk
+	default:
+		if c.name != nil {
+			helpMessage(tokens, cursor, "Expected constraint")
+		}
+		return nil, initialCursor, false
+	}
+
+	return &c, cursor, true
+}
+
+// REFERENCES $table-name ($column-name [, ...]) [ON DELETE CASCADE | SET NULL | RESTRICT | NO ACTION]
+func parseReferences(tokens []*token, initialCursor uint) (*foreignKey, uint, bool) {
+	cursor := initialCursor
+
+	if !expectToken(tokens, cursor, tokenFromKeyword(referencesKeyword)) {
+		helpMessage(tokens, cursor, "Expected REFERENCES")
+		return nil, initialCursor, false
+	}
+	cursor++
+
+	table, newCursor, ok := parseToken(tokens, cursor, identifierKind)
+	if !ok {
+		helpMessage(tokens, cursor, "Expected referenced table name")
+		return nil, initialCursor, false
+	}
+	cursor = newCursor
+
+	// 还没有主键, 所以必须写出被引用的列
+	references, newCursor, ok := parseColumnList(tokens, cursor)
+	if !ok {
+		return nil, initialCursor, false
+	}
+	cursor = newCursor
+
+	fk := foreignKey{
+		table:      *table,
+		references: references,
+	}
+
+	if expectToken(tokens, cursor, tokenFromKeyword(onKeyword)) && expectToken(tokens, cursor+1, tokenFromKeyword(deleteKeyword)) {
+		cursor += 2
+
+		switch {
+		case expectIdentifier(tokens, cursor, "cascade"):
+			fk.onDelete = cascadeAction
+			cursor++
+		case expectIdentifier(tokens, cursor, "restrict"):
+			fk.onDelete = restrictAction
+			cursor++
+		case expectToken(tokens, cursor, tokenFromKeyword(setKeyword)) && expectToken(tokens, cursor+1, token{kind: nullKind, value: string(nullKeyword)}):
+			fk.onDelete = setNullAction
+			cursor += 2
+		case expectIdentifier(tokens, cursor, "no") && expectIdentifier(tokens, cursor+1, "action"):
+			fk.onDelete = noAction
+			cursor += 2
+		default:
+			helpMessage(tokens, cursor, "Expected CASCADE, SET NULL, RESTRICT or NO ACTION")
+			return nil, initialCursor, false
+		}
+	}
+
+	return &fk, cursor, true
+}
+
+// ($column-name [, ...])
+func parseColumnList(tokens []*token, initialCursor uint) ([]*token, uint, bool) {
+	cursor := initialCursor
+
+	if !expectToken(tokens, cursor, tokenFromSymbol(leftParenSymbol)) {
+		helpMessage(tokens, cursor, "Expected left paren")
+		return nil, initialCursor, false
+	}
+	cursor++
+
+	var columns []*token
+	for !expectToken(tokens, cursor, tokenFromSymbol(rightParenSymbol)) {
+		if len(columns) > 0 {
+			if !expectToken(tokens, cursor, tokenFromSymbol(commaSymbol)) {
+				helpMessage(tokens, cursor, "Expected comma")
+				return nil, initialCursor, false
+			}
+			cursor++
+		}
+
+		column, newCursor, ok := parseToken(tokens, cursor, identifierKind)
+		if !ok {
+			helpMessage(tokens, cursor, "Expected column name")
+			return nil, initialCursor, false
+		}
+		cursor = newCursor
+
+		columns = append(columns, column)
+	}
+	cursor++
+
+	if len(columns) == 0 {
+		helpMessage(tokens, cursor-1, "Expected column name")
+		return nil, initialCursor, false
 	}
 
-	return &cds, cursor, true
+	return columns, cursor, true
+}
+
+// 列定义在类型之后的部分: DEFAULT $expression, GENERATED ALWAYS AS ($expression) STORED 和列约束
+func parseColumnConstraints(tokens []*token, initialCursor uint, cd *columnDefinition) (uint, bool) {
+	cursor := initialCursor
+	delimiters := []token{tokenFromSymbol(commaSymbol), tokenFromSymbol(rightParenSymbol)}
+
+	for {
+		switch {
+		case expectToken(tokens, cursor, tokenFromKeyword(defaultKeyword)):
+			cursor++
+
+			value, newCursor, ok := parseExpression(tokens, cursor, delimiters, 0)
+			if !ok {
+				helpMessage(tokens, cursor, "Expected default value")
+				return initialCursor, false
+			}
+			cursor = newCursor
+
+			cd.defaultValue = value
+		case expectIdentifier(tokens, cursor, "generated"):
+			always := expectIdentifier(tokens, cursor+1, "always")
+			byDefault := expectIdentifier(tokens, cursor+1, "by") && expectToken(tokens, cursor+2, tokenFromKeyword(defaultKeyword))
+			switch {
+			case always:
+				cursor += 2
+			case byDefault:
+				cursor += 3
+			default:
+				helpMessage(tokens, cursor, "Expected GENERATED ALWAYS or GENERATED BY DEFAULT")
+				return initialCursor, false
+			}
+
+			if !expectToken(tokens, cursor, tokenFromKeyword(asKeyword)) {
+				helpMessage(tokens, cursor, "Expected AS")
+				return initialCursor, false
+			}
+			cursor++
+
+			if expectIdentifier(tokens, cursor, "identity") {
+				cursor++
+
+				identity := identityColumn{always: always}
+				if expectToken(tokens, cursor, tokenFromSymbol(leftParenSymbol)) {
+					options, newCursor, ok := parseSequenceOptions(tokens, cursor+1)
+					if !ok {
+						return initialCursor, false
+					}
+					cursor = newCursor
+
+					if !expectToken(tokens, cursor, tokenFromSymbol(rightParenSymbol)) {
+						helpMessage(tokens, cursor, "Expected right parenthesis")
+						return initialCursor, false
+					}
+					cursor++
+
+					identity.options = options
+				}
+
+				cd.identity = &identity
+				continue
+			}
+
+			if !always || !expectToken(tokens, cursor, tokenFromSymbol(leftParenSymbol)) {
+				helpMessage(tokens, cursor, "Expected GENERATED ALWAYS AS ( or AS IDENTITY")
+				return initialCursor, false
+			}
+			cursor++
+
+			rightParenToken := tokenFromSymbol(rightParenSymbol)
+			value, newCursor, ok := parseExpression(tokens, cursor, []token{rightParenToken}, 0)
+			if !ok {
+				helpMessage(tokens, cursor, "Expected generation expression")
+				return initialCursor, false
+			}
+			cursor = newCursor
+
+			if !expectToken(tokens, cursor, rightParenToken) || !expectIdentifier(tokens, cursor+1, "stored") {
+				helpMessage(tokens, cursor, "Expected ) STORED")
+				return initialCursor, false
+			}
+			cursor += 2
+
+			cd.generated = value
+		case expectToken(tokens, cursor, tokenFromKeyword(constraintKeyword)) || expectToken(tokens, cursor, tokenFromKeyword(checkKeyword)) ||
+			expectToken(tokens, cursor, tokenFromKeyword(referencesKeyword)):
+			c, newCursor, ok := parseConstraint(tokens, cursor, &cd.name)
+			if !ok {
+				return initialCursor, false
+			}
+			cursor = newCursor
+
+			cd.constraints = append(cd.constraints, c)
+		default:
+			return cursor, true
+		}
+	}
 }
@@ -0,0 +1,512 @@
+package jiesql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// planNode 是执行计划里的一个算子, 用 op 执行, 同时统计 EXPLAIN ANALYZE 要显示的信息.
+// 过滤, 投影和改名这些不单独显示的步骤包在 op 里面, children 和 subplans 只用于显示
+type planNode struct {
+	title   string
+	details []string
+	// 估计的结果行数
+	estimate int
+	children []*planNode
+	// 查询开头 WITH 定义的临时表, 显示为 CTE name
+	subplans []*commonTable
+	op       operator
+
+	// EXPLAIN ANALYZE 统计的执行次数, 结果行数, 被过滤掉的行数和耗时, 都是所有次数的总和
+	loops   int
+	rows    int
+	removed int
+	elapsed time.Duration
+}
+
+func (n *planNode) Open() (*table, error) {
+	start := time.Now()
+	defer func() { n.elapsed += time.Since(start) }()
+
+	n.loops++
+	return n.op.Open()
+}
+
+func (n *planNode) Next() ([]MemoryCell, bool, error) {
+	start := time.Now()
+	defer func() { n.elapsed += time.Since(start) }()
+
+	row, ok, err := n.op.Next()
+	if ok {
+		n.rows++
+	}
+
+	return row, ok, err
+}
+
+func (n *planNode) Close() error {
+	return n.op.Close()
+}
+
+func (mb *MemoryBackend) Select(slct *SelectStatement) (*Results, error) {
+	plan, err := mb.planSelect(slct, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	t, err := drain(plan)
+	if err != nil {
+		return nil, err
+	}
+
+	return tableResults(t, mb.enums), nil
+}
+
+// tableResults 把算子的结果转换成返回给调用方的 Results
+func tableResults(t *table, enums enumTypes) *Results {
+	results := &Results{
+		Columns: []column{},
+		Rows:    [][]Cell{},
+		enums:   enums,
+	}
+
+	for i, name := range t.columns {
+		results.Columns = append(results.Columns, column{
+			Type: t.columnTypes[i],
+			Name: name,
+		})
+	}
+
+	for _, row := range t.rows {
+		result := make([]Cell, len(row))
+		for i, cell := range row {
+			result[i] = cell
+		}
+
+		results.Rows = append(results.Rows, result)
+	}
+
+	return results
+}
+
+// newResultTable 创建一张只读的表来保存查询结果
+func newResultTable(columns []column) *table {
+	t := table{}
+	for _, c := range columns {
+		t.columns = append(t.columns, c.Name)
+		t.columnTypes = append(t.columnTypes, c.Type)
+		t.columnModifiers = append(t.columnModifiers, typeModifier{})
+		t.columnDefaults = append(t.columnDefaults, nil)
+		t.columnGenerated = append(t.columnGenerated, nil)
+		t.columnIdentity = append(t.columnIdentity, false)
+	}
+
+	return &t
+}
+
+// planSelect 规划完整的 SELECT: 先依次规划 WITH 里的临时表, 后面的可以引用前面的,
+// 再从左到右合并 UNION 的各个部分, 最后是整个查询的 ORDER BY 和 LIMIT. scope 是外层可见的临时表
+func (mb *MemoryBackend) planSelect(slct *SelectStatement, scope map[string]*commonTable) (*planNode, error) {
+	var ctes []*commonTable
+	if len(slct.with) > 0 {
+		inner := map[string]*commonTable{}
+		for name, ct := range scope {
+			inner[name] = ct
+		}
+
+		for _, cte := range slct.with {
+			for _, ct := range ctes {
+				if ct.name == cte.name.value {
+					return nil, fmt.Errorf("%w: %s", ErrDuplicateTableName, ct.name)
+				}
+			}
+
+			ct, err := mb.planCommonTable(cte, slct.recursive, inner)
+			if err != nil {
+				return nil, err
+			}
+
+			inner[ct.name] = ct
+			ctes = append(ctes, ct)
+		}
+
+		scope = inner
+	}
+
+	// 没有 UNION 时在投影之前排序, 这样可以按没有选出来的列排序. 这时结果的列还不存在,
+	// ORDER BY 里的列位置要先换成 SELECT 列表里对应的表达式
+	var orderBy []*orderItem
+	if len(slct.union) == 0 {
+		var err error
+		orderBy, err = resolveOrderPositions(slct.orderBy, slct.item)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	node, err := mb.planSimple(slct, orderBy, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, op := range slct.union {
+		right, err := mb.planSelect(op.query, scope)
+		if err != nil {
+			return nil, err
+		}
+
+		node = unionNode(node, right, op.all)
+	}
+
+	// 有 UNION 时按合并后结果的列排序, 列位置直接取结果里对应的列
+	if len(slct.union) > 0 && len(slct.orderBy) > 0 {
+		for _, item := range slct.orderBy {
+			if n, ok := orderPosition(item); ok && (n < 1 || n > len(slct.item)) {
+				return nil, fmt.Errorf("%w: ORDER BY position %d is not in select list", ErrInvalidSelectItem, n)
+			}
+		}
+
+		node = mb.sortNode(node, slct.orderBy, true)
+	}
+
+	if slct.limit != nil || slct.offset != nil {
+		node = mb.limitNode(node, slct.limit, slct.offset)
+	}
+
+	if len(ctes) > 0 {
+		node.op = &withOp{child: node.op, ctes: ctes}
+		node.subplans = ctes
+	}
+
+	return node, nil
+}
+
+// planSimple 规划不带 WITH 和 UNION 的 SELECT: 扫描和连接 FROM 里的表, 用 WHERE 过滤,
+// 计算窗口函数, 按 orderBy 排序, 最后投影出结果的列
+func (mb *MemoryBackend) planSimple(slct *SelectStatement, orderBy []*orderItem, scope map[string]*commonTable) (*planNode, error) {
+	node, err := mb.planFrom(slct.from, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	if slct.where != nil {
+		mb.addFilter(node, *slct.where)
+	}
+
+	// 窗口函数看到的是 WHERE 过滤之后的行, ORDER BY 里也可以用窗口函数
+	items := append([]*expression{}, slct.item...)
+	for _, item := range orderBy {
+		exp := item.exp
+		items = append(items, &exp)
+	}
+
+	var calls []*functionCall
+	setReturning := false
+	for _, item := range items {
+		calls = append(calls, windowCalls(*item)...)
+	}
+
+	for _, item := range slct.item {
+		setReturning = setReturning || isSetReturning(*item)
+	}
+
+	if len(calls) > 0 {
+		child := node
+		node = &planNode{
+			title:    "WindowAgg",
+			estimate: child.estimate,
+			children: []*planNode{child},
+			op:       &windowOp{mb: mb, child: child, items: items},
+		}
+
+		for _, call := range calls {
+			node.details = append(node.details, "Window: "+formatExpression(expression{function: call, kind: functionKind}))
+		}
+	}
+
+	if len(orderBy) > 0 {
+		node = mb.sortNode(node, orderBy, false)
+	}
+
+	// 和 PostgreSQL 一样估计每个 unnest 展开成 10 行
+	if setReturning {
+		child := node
+		return &planNode{
+			title:    "ProjectSet",
+			estimate: child.estimate * 10,
+			children: []*planNode{child},
+			op:       &projectOp{mb: mb, child: child, items: slct.item},
+		}, nil
+	}
+
+	// 普通的投影不单独显示, 在最上面的算子里完成
+	node.op = &projectOp{mb: mb, child: node.op, items: slct.item}
+	return node, nil
+}
+
+// planFrom 规划 FROM 里的表. 只有一张没有别名的表时直接扫描它, 否则列名前面加上别名或表名,
+// 多张表从左到右两两做嵌套循环连接, 也就是笛卡尔积
+func (mb *MemoryBackend) planFrom(from []*tableReference, scope map[string]*commonTable) (*planNode, error) {
+	// 没有 FROM 时在只有一个空行的表上求值, 例如 SELECT 'a' || 'b'
+	if len(from) == 0 {
+		return &planNode{
+			title:    "Result",
+			estimate: 1,
+			op:       &resultOp{},
+		}, nil
+	}
+
+	qualify := len(from) > 1 || from[0].alias != nil
+	used := map[string]bool{}
+	var node *planNode
+	for _, ref := range from {
+		scan, err := mb.planRelation(ref, scope)
+		if err != nil {
+			return nil, err
+		}
+
+		qualifier := ref.name.value
+		if ref.alias != nil {
+			qualifier = ref.alias.value
+		}
+
+		if used[qualifier] {
+			return nil, fmt.Errorf("%w: %s", ErrDuplicateTableName, qualifier)
+		}
+		used[qualifier] = true
+
+		if qualify {
+			scan.op = &qualifyOp{child: scan.op, qualifier: qualifier}
+		}
+
+		if node == nil {
+			node = scan
+			continue
+		}
+
+		node = &planNode{
+			title:    "Nested Loop",
+			estimate: node.estimate * scan.estimate,
+			children: []*planNode{node, scan},
+			op:       &nestedLoopOp{left: node, right: scan},
+		}
+	}
+
+	return node, nil
+}
+
+// planRelation 规划 FROM 里的一张表, WITH 定义的临时表优先, 视图展开成子查询
+func (mb *MemoryBackend) planRelation(ref *tableReference, scope map[string]*commonTable) (*planNode, error) {
+	name := ref.name.value
+	title := name
+	if ref.alias != nil {
+		title += " " + ref.alias.value
+	}
+
+	if ct, ok := scope[name]; ok {
+		// 递归项里对自己的引用读取上一轮产生的行
+		if ct.recursive {
+			return &planNode{
+				title:    "WorkTable Scan on " + title,
+				estimate: ct.estimate,
+				op:       &scanOp{source: func() *table { return ct.working }},
+			}, nil
+		}
+
+		return &planNode{
+			title:    "CTE Scan on " + title,
+			estimate: ct.estimate,
+			op:       &cteScanOp{ct: ct},
+		}, nil
+	}
+
+	if t, ok := mb.tables[name]; ok {
+		return &planNode{
+			title:    "Seq Scan on " + title,
+			estimate: len(t.rows),
+			op:       &scanOp{source: func() *table { return t }},
+		}, nil
+	}
+
+	v, ok := mb.views[name]
+	if !ok {
+		return nil, ErrTableDoesNotExist
+	}
+
+	child, err := mb.planView(v)
+	if err != nil {
+		return nil, err
+	}
+
+	return &planNode{
+		title:    "Subquery Scan on " + title,
+		estimate: child.estimate,
+		children: []*planNode{child},
+		op:       child,
+	}, nil
+}
+
+// addFilter 在算子的输出上按 where 过滤, 和 PostgreSQL 一样显示为算子的 Filter
+func (mb *MemoryBackend) addFilter(node *planNode, where expression) {
+	label := "Filter"
+	if node.title == "Nested Loop" {
+		label = "Join Filter"
+	}
+
+	node.details = append(node.details, label+": "+formatExpression(where))
+	node.estimate = estimateRows(node.estimate, where)
+	node.op = &filterOp{mb: mb, child: node.op, where: where, removed: &node.removed}
+}
+
+// isIntegerLiteral 判断表达式是不是 SQL 里直接写的整数常量
+func isIntegerLiteral(exp expression) bool {
+	return exp.kind == literalKind && exp.literal.kind == numericKind && strings.Trim(exp.literal.value, "0123456789") == ""
+}
+
+// orderPosition 判断 ORDER BY 的项是不是整数常量, 和 PostgreSQL 一样这时它表示结果的第几列, 从 1 开始
+func orderPosition(item *orderItem) (int, bool) {
+	if !item.positional {
+		return 0, false
+	}
+
+	n, err := strconv.Atoi(item.exp.literal.value)
+	if err != nil {
+		// 位置太大时一定不在 SELECT 列表里
+		return -1, true
+	}
+
+	return n, true
+}
+
+// resolveOrderPositions 把 orderBy 里的列位置换成 items 里对应的表达式, 其他的排序项不变
+func resolveOrderPositions(orderBy []*orderItem, items []*expression) ([]*orderItem, error) {
+	var resolved []*orderItem
+	for _, item := range orderBy {
+		n, ok := orderPosition(item)
+		if !ok {
+			resolved = append(resolved, item)
+			continue
+		}
+
+		if n < 1 || n > len(items) {
+			return nil, fmt.Errorf("%w: ORDER BY position %d is not in select list", ErrInvalidSelectItem, n)
+		}
+
+		resolved = append(resolved, &orderItem{exp: *items[n-1], desc: item.desc})
+	}
+
+	return resolved, nil
+}
+
+// sortNode 按 orderBy 排序, 相等的行保持原来的顺序. positional 见 sortOp
+func (mb *MemoryBackend) sortNode(child *planNode, orderBy []*orderItem, positional bool) *planNode {
+	var keys []string
+	for _, item := range orderBy {
+		key := formatExpression(item.exp)
+		if item.desc {
+			key += " DESC"
+		}
+
+		keys = append(keys, key)
+	}
+
+	return &planNode{
+		title:    "Sort",
+		details:  []string{"Sort Key: " + strings.Join(keys, ", ")},
+		estimate: child.estimate,
+		children: []*planNode{child},
+		op:       &sortOp{mb: mb, child: child, orderBy: orderBy, positional: positional},
+	}
+}
+
+// limitNode 跳过前 offset 行, 最多返回 limit 行
+func (mb *MemoryBackend) limitNode(child *planNode, limit, offset *expression) *planNode {
+	// 估计行数时只看整数常量, 参数和 nextval 这样的表达式要到执行时才能求值
+	constant := func(exp *expression) int {
+		if exp == nil || exp.kind != literalKind || exp.literal.kind != numericKind {
+			return -1
+		}
+
+		n, err := strconv.Atoi(exp.literal.value)
+		if err != nil {
+			return -1
+		}
+
+		return n
+	}
+
+	estimate := child.estimate
+	if n := constant(offset); n > 0 {
+		estimate -= n
+	}
+
+	if n := constant(limit); n >= 0 && n < estimate {
+		estimate = n
+	}
+
+	if estimate < 0 {
+		estimate = 0
+	}
+
+	return &planNode{
+		title:    "Limit",
+		estimate: estimate,
+		children: []*planNode{child},
+		op:       &limitOp{mb: mb, child: child, limit: limit, offset: offset},
+	}
+}
+
+// estimateRows 估计过滤后剩下的行数, 有输入时至少是 1
+func estimateRows(rows int, where expression) int {
+	estimate := int(float64(rows)*selectivity(where) + 0.5)
+	if rows > 0 && estimate < 1 {
+		return 1
+	}
+
+	return estimate
+}
+
+// selectivity 估计满足条件的行所占的比例. 没有统计信息, 和 PostgreSQL 的默认值一样,
+// 等值比较, LIKE 和 BETWEEN 取 0.005, 其他条件取 1/3
+func selectivity(exp expression) float64 {
+	const eqSel, defaultSel = 0.005, 1.0 / 3
+
+	switch exp.kind {
+	case binaryKind:
+		a, b := exp.binary.a, exp.binary.b
+		switch exp.binary.op.value {
+		case string(andKeyword):
+			return selectivity(a) * selectivity(b)
+		case string(orKeyword):
+			sa, sb := selectivity(a), selectivity(b)
+			return sa + sb - sa*sb
+		case string(eqSymbol):
+			return eqSel
+		case string(neqSymbol):
+			return 1 - eqSel
+		}
+	case unaryKind:
+		if exp.unary.op.value == string(notKeyword) {
+			return 1 - selectivity(exp.unary.operand)
+		}
+	case likeKind, betweenKind:
+		s := eqSel
+		if exp.kind == likeKind && exp.like.not || exp.kind == betweenKind && exp.between.not {
+			s = 1 - s
+		}
+		return s
+	case inKind:
+		s := eqSel * float64(len(exp.in.list))
+		if s > 1 {
+			s = 1
+		}
+		if exp.in.not {
+			s = 1 - s
+		}
+		return s
+	}
+
+	return defaultSel
+}
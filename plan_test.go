@@ -0,0 +1,109 @@
+package jiesql
+
+import (
+	"testing"
+)
+
+func TestOrderByPosition(t *testing.T) {
+	mb := newScoresBackend(t)
+
+	// 整数常量表示 SELECT 列表里的第几列, 不是按常量排序
+	expectRows(t, mb, "SELECT id, points FROM scores ORDER BY 2 DESC, 1", [][]string{
+		{"5", "null"}, {"2", "30"}, {"3", "30"}, {"4", "20"}, {"1", "10"},
+	})
+	expectRows(t, mb, "SELECT points - id FROM scores WHERE points > 0 ORDER BY 1", [][]string{{"9"}, {"16"}, {"27"}, {"28"}})
+	expectRows(t, mb, "SELECT 7, id FROM scores WHERE id < 3 ORDER BY 1, 2 DESC", [][]string{{"7", "2"}, {"7", "1"}})
+	expectRows(t, mb, "SELECT id, row_number() OVER (ORDER BY id DESC) FROM scores WHERE id < 4 ORDER BY 2", [][]string{
+		{"3", "1"}, {"2", "2"}, {"1", "3"},
+	})
+	// 其他常量表达式还是按常量排序
+	expectRows(t, mb, "SELECT id FROM scores WHERE id < 4 ORDER BY 1 + 0 DESC, id DESC", [][]string{{"3"}, {"2"}, {"1"}})
+
+	// 有 UNION 时按合并后结果的列排序
+	expectRows(t, mb, "SELECT id, team FROM scores WHERE id < 3 UNION SELECT 9, 'a' UNION SELECT 0, 'c' ORDER BY 2 DESC, 1", [][]string{
+		{"0", "c"}, {"1", "a"}, {"2", "a"}, {"9", "a"},
+	})
+	expectRows(t, mb, "SELECT points FROM scores UNION ALL SELECT 15 ORDER BY 1 DESC LIMIT 3", [][]string{{"null"}, {"30"}, {"30"}})
+
+	expectError(t, mb, "SELECT id FROM scores ORDER BY 0", ErrInvalidSelectItem)
+	expectError(t, mb, "SELECT id FROM scores ORDER BY 2", ErrInvalidSelectItem)
+	expectError(t, mb, "SELECT id FROM scores ORDER BY 99999999999999999999", ErrInvalidSelectItem)
+	expectError(t, mb, "SELECT id FROM scores UNION SELECT 1 ORDER BY 2", ErrInvalidSelectItem)
+}
+
+func TestOrderBy(t *testing.T) {
+	mb := newScoresBackend(t)
+
+	// 相等的行保持原来的顺序, 和 PostgreSQL 一样 NULL 排在最大的位置
+	expectRows(t, mb, "SELECT id FROM scores ORDER BY points", [][]string{{"1"}, {"4"}, {"2"}, {"3"}, {"5"}})
+	expectRows(t, mb, "SELECT id FROM scores ORDER BY points DESC", [][]string{{"5"}, {"2"}, {"3"}, {"4"}, {"1"}})
+	expectRows(t, mb, "SELECT id FROM scores ORDER BY team DESC, points DESC", [][]string{{"5"}, {"4"}, {"2"}, {"3"}, {"1"}})
+	// 可以按没有选出来的列和表达式排序
+	expectRows(t, mb, "SELECT team FROM scores WHERE points > 0 ORDER BY points - id * 10", [][]string{{"b"}, {"a"}, {"a"}, {"a"}})
+	expectRows(t, mb, "SELECT id FROM scores WHERE id > 10 ORDER BY id", [][]string{})
+	expectRows(t, mb, "SELECT id FROM scores ORDER BY row_number() OVER (ORDER BY id DESC)", [][]string{{"5"}, {"4"}, {"3"}, {"2"}, {"1"}})
+	// 有 UNION 时按合并后结果的列名排序
+	expectRows(t, mb, "SELECT id FROM scores WHERE id > 3 UNION SELECT 0 ORDER BY id DESC", [][]string{{"5"}, {"4"}, {"0"}})
+
+	expectError(t, mb, "SELECT id FROM scores ORDER BY nosuch", ErrColumnDoesNotExist)
+	expectError(t, mb, "SELECT id FROM scores UNION SELECT 1 ORDER BY team", ErrColumnDoesNotExist)
+}
+
+func TestLimit(t *testing.T) {
+	mb := newScoresBackend(t)
+
+	tests := []struct {
+		source   string
+		expected [][]string
+	}{
+		{"SELECT id FROM scores LIMIT 2", [][]string{{"1"}, {"2"}}},
+		{"SELECT id FROM scores LIMIT 0", [][]string{}},
+		{"SELECT id FROM scores LIMIT 10", [][]string{{"1"}, {"2"}, {"3"}, {"4"}, {"5"}}},
+		{"SELECT id FROM scores OFFSET 3", [][]string{{"4"}, {"5"}}},
+		{"SELECT id FROM scores OFFSET 10", [][]string{}},
+		{"SELECT id FROM scores ORDER BY id DESC LIMIT 2 OFFSET 1", [][]string{{"4"}, {"3"}}},
+		{"SELECT id FROM scores LIMIT 1 + 1 OFFSET 2 * 2", [][]string{{"5"}}},
+		// NULL 表示没有限制
+		{"SELECT id FROM scores LIMIT null OFFSET 3", [][]string{{"4"}, {"5"}}},
+		{"SELECT id FROM scores LIMIT 1 OFFSET null", [][]string{{"1"}}},
+		{"SELECT id FROM scores UNION ALL SELECT id FROM scores LIMIT 2 OFFSET 4", [][]string{{"5"}, {"1"}}},
+		{"SELECT 1 LIMIT 1", [][]string{{"1"}}},
+	}
+
+	for _, test := range tests {
+		expectRows(t, mb, test.source, test.expected)
+	}
+
+	// LIMIT 够了以后不再往下执行, 所以后面出错的行不会被算到
+	mustRun(t, mb, "CREATE TABLE z (n INT); INSERT INTO z VALUES (1); INSERT INTO z VALUES (0)")
+	expectRows(t, mb, "SELECT 1 / n FROM z LIMIT 1", [][]string{{"1"}})
+	expectError(t, mb, "SELECT 1 / n FROM z LIMIT 2", ErrDivisionByZero)
+	// 和 PostgreSQL 一样 LIMIT 0 时还是会检查查询, 只是不取行
+	expectRows(t, mb, "SELECT 1 / n FROM z LIMIT 0", [][]string{})
+
+	expectError(t, mb, "SELECT id FROM scores LIMIT -1", ErrInvalidLimit)
+	expectError(t, mb, "SELECT id FROM scores OFFSET -1", ErrInvalidLimit)
+	expectError(t, mb, "SELECT id FROM scores LIMIT 1.5", ErrInvalidLimit)
+	expectError(t, mb, "SELECT id FROM scores LIMIT 'a'::text", ErrInvalidLimit)
+	expectError(t, mb, "SELECT id FROM scores LIMIT id", ErrColumnDoesNotExist)
+}
+
+func TestStreaming(t *testing.T) {
+	mb := NewMemoryBackend()
+
+	// 没有 LIMIT 时无限的递归查询会一直执行到迭代上限, LIMIT 让它提前停下
+	mb.MaxRecursion = 100
+	expectRows(t, mb, "WITH RECURSIVE n (i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n) SELECT i FROM n WHERE i > 3 LIMIT 2", [][]string{{"4"}, {"5"}})
+	expectRows(t, mb, "WITH RECURSIVE n (i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n) SELECT i FROM n LIMIT 2 OFFSET 98", [][]string{{"99"}, {"100"}})
+	expectError(t, mb, "WITH RECURSIVE n (i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n) SELECT i FROM n LIMIT 2 OFFSET 200", ErrRecursionLimit)
+	// 排序要先读完所有的行
+	expectError(t, mb, "WITH RECURSIVE n (i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n) SELECT i FROM n ORDER BY i LIMIT 1", ErrRecursionLimit)
+	// 临时表被引用多次时共用已经算出来的行
+	expectRows(t, mb, "WITH RECURSIVE n (i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n WHERE i < 3) SELECT a.i, b.i FROM n a, n b WHERE a.i < b.i LIMIT 2", [][]string{
+		{"1", "2"}, {"1", "3"},
+	})
+	// 嵌套循环也是一行一行地产生结果
+	expectRows(t, mb, "WITH RECURSIVE n (i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n) SELECT a.i, b.i FROM n a, n b LIMIT 2", [][]string{
+		{"1", "1"}, {"1", "2"},
+	})
+}
@@ -0,0 +1,276 @@
+package jiesql
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Stmt 是解析好的一条语句, 每次执行时把参数绑定到语法树的副本上, 不需要重新解析
+type Stmt struct {
+	mb        *MemoryBackend
+	statement *Statement
+	params    int
+}
+
+// Prepare 解析一条语句, 语句里可以用 $1, $2, ... 或 ? 作为参数, 末尾的分号可以省略
+func (mb *MemoryBackend) Prepare(source string) (*Stmt, error) {
+	tokens, err := lex(source)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(tokens) > 0 && !expectToken(tokens, uint(len(tokens)-1), tokenFromSymbol(semicolonSymbol)) {
+		semicolon := tokenFromSymbol(semicolonSymbol)
+		tokens = append(tokens, &semicolon)
+	}
+
+	ast, err := parseTokens(tokens)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(ast.Statements) != 1 {
+		return nil, ErrMultipleStatements
+	}
+
+	params := 0
+	for _, t := range tokens {
+		if t.kind != parameterKind {
+			continue
+		}
+
+		if n, _ := strconv.Atoi(t.value); n > params {
+			params = n
+		}
+	}
+
+	return &Stmt{mb: mb, statement: ast.Statements[0], params: params}, nil
+}
+
+// NumInput 返回语句需要的参数个数, 也就是最大的参数编号
+func (s *Stmt) NumInput() int {
+	return s.params
+}
+
+// Exec 绑定参数后执行语句, SELECT 的结果被丢弃
+func (s *Stmt) Exec(args ...interface{}) error {
+	stmt, err := s.bind(args)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.mb.execute(stmt)
+	return err
+}
+
+// Query 绑定参数后执行 SELECT 或 EXPLAIN 并返回结果
+func (s *Stmt) Query(args ...interface{}) (*Results, error) {
+	if s.statement.Kind != SelectKind && s.statement.Kind != ExplainKind {
+		return nil, ErrNotQuery
+	}
+
+	stmt, err := s.bind(args)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.mb.execute(stmt)
+}
+
+// execute 执行一条语句, 只有 SELECT 返回结果
+func (mb *MemoryBackend) execute(stmt *Statement) (*Results, error) {
+	switch stmt.Kind {
+	case SelectKind:
+		return mb.Select(stmt.SelectStatement)
+	case CreateTableKind:
+		return nil, mb.CreateTable(stmt.CreateTableStatement)
+	case InsertKind:
+		return nil, mb.Insert(stmt.InsertStatement)
+	case CreateTypeKind:
+		return nil, mb.CreateType(stmt.CreateTypeStatement)
+	case UpdateKind:
+		return nil, mb.Update(stmt.UpdateStatement)
+	case DeleteKind:
+		return nil, mb.Delete(stmt.DeleteStatement)
+	case CreateSequenceKind:
+		return nil, mb.CreateSequence(stmt.CreateSequenceStatement)
+	case AlterTableKind:
+		return nil, mb.AlterTable(stmt.AlterTableStatement)
+	case CreateViewKind:
+		return nil, mb.CreateView(stmt.CreateViewStatement)
+	case DropViewKind:
+		return nil, mb.DropView(stmt.DropViewStatement)
+	case RefreshMaterializedViewKind:
+		return nil, mb.RefreshMaterializedView(stmt.RefreshMaterializedViewStatement)
+	case ExplainKind:
+		return mb.Explain(stmt.ExplainStatement)
+	}
+
+	return nil, fmt.Errorf("unknown statement kind %d", stmt.Kind)
+}
+
+// bind 复制语法树, 把其中的参数换成 args 对应的值, 原来的语法树不变, 可以反复执行
+func (s *Stmt) bind(args []interface{}) (*Statement, error) {
+	if len(args) != s.params {
+		return nil, fmt.Errorf("%w: expected %d arguments, got %d", ErrInvalidParameter, s.params, len(args))
+	}
+
+	if s.params == 0 {
+		return s.statement, nil
+	}
+
+	values := make([]expression, len(args))
+	for i, arg := range args {
+		value, err := bindValue(arg)
+		if err != nil {
+			return nil, fmt.Errorf("%w: $%d", err, i+1)
+		}
+
+		values[i] = value
+	}
+
+	f := func(exp expression) expression {
+		if exp.kind == literalKind && exp.literal.kind == parameterKind {
+			n, _ := strconv.Atoi(exp.literal.value)
+			return values[n-1]
+		}
+
+		return exp
+	}
+
+	stmt := *s.statement
+	switch stmt.Kind {
+	case SelectKind:
+		stmt.SelectStatement = bindSelect(stmt.SelectStatement, f)
+	case ExplainKind:
+		explain := *stmt.ExplainStatement
+		explain.query = bindSelect(explain.query, f)
+		stmt.ExplainStatement = &explain
+	case InsertKind:
+		inst := *stmt.InsertStatement
+		if inst.values != nil {
+			var values []*expression
+			for _, exp := range *inst.values {
+				value := mapExpression(*exp, f)
+				values = append(values, &value)
+			}
+			inst.values = &values
+		}
+		stmt.InsertStatement = &inst
+	case UpdateKind:
+		upd := *stmt.UpdateStatement
+		upd.set = nil
+		for _, set := range stmt.UpdateStatement.set {
+			a := *set
+			a.value = mapExpression(a.value, f)
+			upd.set = append(upd.set, &a)
+		}
+		upd.where = bindOptional(upd.where, f)
+		stmt.UpdateStatement = &upd
+	case DeleteKind:
+		del := *stmt.DeleteStatement
+		del.where = bindOptional(del.where, f)
+		stmt.DeleteStatement = &del
+	default:
+		// 和 PostgreSQL 一样, DDL 里的表达式会被保存下来, 不能引用参数
+		return nil, fmt.Errorf("%w: parameters are only allowed in SELECT, INSERT, UPDATE, DELETE and EXPLAIN", ErrInvalidParameter)
+	}
+
+	return &stmt, nil
+}
+
+func bindOptional(exp *expression, f func(expression) expression) *expression {
+	if exp == nil {
+		return nil
+	}
+
+	bound := mapExpression(*exp, f)
+	return &bound
+}
+
+func bindSelect(slct *SelectStatement, f func(expression) expression) *SelectStatement {
+	bound := *slct
+
+	bound.with = nil
+	for _, cte := range slct.with {
+		c := *cte
+		c.query = bindSelect(cte.query, f)
+		bound.with = append(bound.with, &c)
+	}
+
+	bound.item = nil
+	for _, item := range slct.item {
+		exp := mapExpression(*item, f)
+		bound.item = append(bound.item, &exp)
+	}
+
+	bound.where = bindOptional(slct.where, f)
+
+	bound.union = nil
+	for _, op := range slct.union {
+		o := *op
+		o.query = bindSelect(op.query, f)
+		bound.union = append(bound.union, &o)
+	}
+
+	bound.orderBy = nil
+	for _, item := range slct.orderBy {
+		o := *item
+		o.exp = mapExpression(item.exp, f)
+		bound.orderBy = append(bound.orderBy, &o)
+	}
+
+	bound.limit = bindOptional(slct.limit, f)
+	bound.offset = bindOptional(slct.offset, f)
+
+	return &bound
+}
+
+// bindValue 把 Go 的值转换成字面量表达式. 字符串和 SQL 里的字符串字面量一样,
+// 会按上下文转换成日期, 数字等类型
+func bindValue(arg interface{}) (expression, error) {
+	literal := func(kind tokenKind, value string) expression {
+		return expression{
+			literal: &token{kind: kind, value: value},
+			kind:    literalKind,
+		}
+	}
+
+	cast := func(value string, typ string) expression {
+		return expression{
+			cast: &castExpression{
+				value:    literal(stringKind, value),
+				datatype: dataType{name: token{kind: identifierKind, value: typ}},
+			},
+			kind: castKind,
+		}
+	}
+
+	switch v := arg.(type) {
+	case nil:
+		return literal(nullKind, string(nullKeyword)), nil
+	case bool:
+		return literal(boolKind, strconv.FormatBool(v)), nil
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return literal(numericKind, fmt.Sprintf("%d", v)), nil
+	case float32:
+		return cast(strconv.FormatFloat(float64(v), 'g', -1, 32), "float"), nil
+	case float64:
+		return cast(strconv.FormatFloat(v, 'g', -1, 64), "float"), nil
+	case string:
+		return literal(stringKind, v), nil
+	case []byte:
+		if v == nil {
+			return literal(nullKind, string(nullKeyword)), nil
+		}
+
+		return literal(byteaKind, hex.EncodeToString(v)), nil
+	case time.Time:
+		// TIMESTAMP 不带时区, 和 NOW() 一样取墙上时间
+		return cast(v.Format("2006-01-02 15:04:05.999999"), "timestamp"), nil
+	}
+
+	return expression{}, fmt.Errorf("%w: unsupported type %T", ErrInvalidParameter, arg)
+}
@@ -0,0 +1,175 @@
+package jiesql
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func mustPrepare(t *testing.T, mb *MemoryBackend, source string) *Stmt {
+	t.Helper()
+
+	stmt, err := mb.Prepare(source)
+	if err != nil {
+		t.Fatalf("%s: %s", source, err)
+	}
+
+	return stmt
+}
+
+func TestPrepare(t *testing.T) {
+	mb := NewMemoryBackend()
+	mustRun(t, mb, "CREATE TABLE users (id INT, name TEXT, score DOUBLE PRECISION, active BOOLEAN, avatar BYTEA, created TIMESTAMP)")
+
+	insert := mustPrepare(t, mb, "INSERT INTO users VALUES ($1, $2, $3, $4, $5, $6)")
+	if insert.NumInput() != 6 {
+		t.Errorf("expected 6 inputs, got %d", insert.NumInput())
+	}
+
+	created := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	args := [][]interface{}{
+		{1, "alice", 1.5, true, []byte{0xde, 0xad}, created},
+		// 参数的值不会被当作 SQL 解析
+		{int64(2), "bob'); DROP TABLE users; --", float32(0.25), false, []byte(nil), created},
+		{3, nil, nil, nil, nil, nil},
+	}
+	for _, a := range args {
+		if err := insert.Exec(a...); err != nil {
+			t.Fatalf("exec %v: %s", a, err)
+		}
+	}
+
+	expectRows(t, mb, "SELECT id, name, score, active, avatar, created FROM users", [][]string{
+		{"1", "alice", "1.5", "true", "\\xdead", "2020-01-02 03:04:05"},
+		{"2", "bob'); DROP TABLE users; --", "0.25", "false", "null", "2020-01-02 03:04:05"},
+		{"3", "null", "null", "null", "null", "null"},
+	})
+
+	// 同一条语句可以用不同的参数反复执行
+	query := mustPrepare(t, mb, "SELECT name FROM users WHERE id > ? AND id < ?")
+	for _, test := range []struct {
+		low, high int
+		expected  [][]string
+	}{
+		{0, 3, [][]string{{"alice"}, {"bob'); DROP TABLE users; --"}}},
+		{1, 4, [][]string{{"bob'); DROP TABLE users; --"}, {"null"}}},
+		{3, 4, [][]string{}},
+	} {
+		results, err := query.Query(test.low, test.high)
+		if err != nil {
+			t.Fatalf("query %d %d: %s", test.low, test.high, err)
+		}
+
+		expectResults(t, results, test.expected)
+	}
+
+	// 同一个参数可以出现多次, 字符串按上下文转换类型
+	results, err := mustPrepare(t, mb, "SELECT id FROM users WHERE id = $1 OR id = $1 + 1 ORDER BY id;").Query("1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectResults(t, results, [][]string{{"1"}, {"2"}})
+
+	if err := mustPrepare(t, mb, "UPDATE users SET name = $2 WHERE id = $1").Exec(3, "carol"); err != nil {
+		t.Fatal(err)
+	}
+	if err := mustPrepare(t, mb, "DELETE FROM users WHERE name = ?").Exec("alice"); err != nil {
+		t.Fatal(err)
+	}
+	expectRows(t, mb, "SELECT name FROM users WHERE id <> 2", [][]string{{"carol"}})
+
+	results, err = mustPrepare(t, mb, "WITH u AS (SELECT id FROM users WHERE id > $1) SELECT id FROM u LIMIT $2").Query(0, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectResults(t, results, [][]string{{"2"}})
+}
+
+func TestPrepareOrderByParameter(t *testing.T) {
+	mb := NewMemoryBackend()
+	mustRun(t, mb, `CREATE TABLE s (g INT, v TEXT);
+		INSERT INTO s VALUES (2, 'b');
+		INSERT INTO s VALUES (1, 'c');
+		INSERT INTO s VALUES (3, 'a');`)
+
+	// 参数是常量, 不是列的位置, 所有的行相等, 保持原来的顺序
+	stmt := mustPrepare(t, mb, "SELECT g, v FROM s ORDER BY $1")
+	for _, arg := range []interface{}{2, 0, 99} {
+		results, err := stmt.Query(arg)
+		if err != nil {
+			t.Fatalf("ORDER BY $1 with %v: %s", arg, err)
+		}
+		expectResults(t, results, [][]string{{"2", "b"}, {"1", "c"}, {"3", "a"}})
+	}
+
+	// 直接写在 SQL 里的整数仍然是位置, 和参数一起用时参数不影响顺序
+	results, err := mustPrepare(t, mb, "SELECT g, v FROM s ORDER BY $1, 2").Query(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectResults(t, results, [][]string{{"3", "a"}, {"2", "b"}, {"1", "c"}})
+
+	results, err = mustPrepare(t, mb, "SELECT g FROM s UNION SELECT 4 ORDER BY $1").Query(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectResults(t, results, [][]string{{"2"}, {"1"}, {"3"}, {"4"}})
+}
+
+func TestPrepareErrors(t *testing.T) {
+	mb := NewMemoryBackend()
+	mustRun(t, mb, "CREATE TABLE users (id INT, name TEXT); INSERT INTO users VALUES (1, 'a')")
+
+	prepareErrors := []struct {
+		source string
+		target error
+	}{
+		{"SELECT 1; SELECT 2", ErrMultipleStatements},
+		{"SELECT $1 + ?", ErrInvalidParameter},
+		{"SELECT $0", ErrInvalidParameter},
+	}
+	for _, test := range prepareErrors {
+		if _, err := mb.Prepare(test.source); !errors.Is(err, test.target) {
+			t.Errorf("%s: expected error %v, got %v", test.source, test.target, err)
+		}
+	}
+
+	if _, err := mb.Prepare("SELECT FROM"); err == nil {
+		t.Errorf("expected parse error")
+	}
+
+	stmt := mustPrepare(t, mb, "SELECT id FROM users WHERE id = $2")
+	if stmt.NumInput() != 2 {
+		t.Errorf("expected 2 inputs, got %d", stmt.NumInput())
+	}
+
+	execErrors := []struct {
+		stmt   *Stmt
+		args   []interface{}
+		target error
+	}{
+		{stmt, []interface{}{1}, ErrInvalidParameter},
+		{stmt, []interface{}{1, 2, 3}, ErrInvalidParameter},
+		{stmt, []interface{}{1, struct{}{}}, ErrInvalidParameter},
+		{stmt, []interface{}{1, "x"}, ErrInvalidValue},
+		{mustPrepare(t, mb, "INSERT INTO users VALUES (?, ?)"), []interface{}{"x", "a"}, ErrInvalidDatatype},
+		// DDL 会保存表达式, 不能带参数
+		{mustPrepare(t, mb, "CREATE TABLE t (a INT DEFAULT $1)"), []interface{}{1}, ErrInvalidParameter},
+	}
+	for _, test := range execErrors {
+		if _, err := test.stmt.Query(test.args...); !errors.Is(err, test.target) && !errors.Is(err, ErrNotQuery) {
+			t.Errorf("query %v: expected error %v, got %v", test.args, test.target, err)
+		}
+
+		if err := test.stmt.Exec(test.args...); !errors.Is(err, test.target) {
+			t.Errorf("exec %v: expected error %v, got %v", test.args, test.target, err)
+		}
+	}
+
+	if _, err := mustPrepare(t, mb, "INSERT INTO users VALUES (1, 'a')").Query(); !errors.Is(err, ErrNotQuery) {
+		t.Errorf("expected error %v, got %v", ErrNotQuery, err)
+	}
+
+	// 没有经过 Prepare 的参数没有值
+	expectError(t, mb, "SELECT $1", ErrInvalidParameter)
+}
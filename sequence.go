@@ -0,0 +1,134 @@
+package jiesql
+
+import (
+	"fmt"
+)
+
+// sequence 是 CREATE SEQUENCE, SERIAL 和 IDENTITY 列背后的计数器
+type sequence struct {
+	name      string
+	increment int64
+	minValue  int64
+	maxValue  int64
+	// last 是最后一次 nextval 返回或 setval 设置的值, called 为 false 时下一次 nextval 直接返回 last
+	last   int64
+	called bool
+	// currval 返回本会话中 nextval 最后一次返回的值
+	current    int64
+	hasCurrent bool
+}
+
+// serialTypes 是 SERIAL 类的类型名和它们实际的列类型
+var serialTypes = map[string]ColumnType{
+	"smallserial": SmallIntType,
+	"serial2":     SmallIntType,
+	"serial":      IntType,
+	"serial4":     IntType,
+	"bigserial":   BigIntType,
+	"serial8":     BigIntType,
+}
+
+// newSequence 按选项创建序列, 没写的选项和 PostgreSQL 一样取 typ 能表示的范围:
+// 递增的序列默认从 1 开始, 递减的序列默认从 -1 开始
+func newSequence(name string, options sequenceOptions, typ ColumnType) (*sequence, error) {
+	s := sequence{name: name, increment: 1}
+	if options.increment != nil {
+		s.increment = *options.increment
+	}
+
+	if s.increment == 0 {
+		return nil, fmt.Errorf("%w: INCREMENT must not be zero", ErrInvalidValue)
+	}
+
+	min, max := integerRange(typ)
+	if s.increment > 0 {
+		s.minValue, s.maxValue = 1, max
+	} else {
+		s.minValue, s.maxValue = min, -1
+	}
+
+	if options.minValue != nil {
+		s.minValue = *options.minValue
+	}
+	if options.maxValue != nil {
+		s.maxValue = *options.maxValue
+	}
+
+	if s.minValue < min || s.maxValue > max {
+		return nil, fmt.Errorf("%w: sequence %s bounds are out of range for type %s", ErrOutOfRange, name, typ)
+	}
+
+	if s.minValue >= s.maxValue {
+		return nil, fmt.Errorf("%w: MINVALUE must be less than MAXVALUE", ErrInvalidValue)
+	}
+
+	s.last = s.minValue
+	if s.increment < 0 {
+		s.last = s.maxValue
+	}
+	if options.start != nil {
+		s.last = *options.start
+	}
+
+	if s.last < s.minValue || s.last > s.maxValue {
+		return nil, fmt.Errorf("%w: START value %d is out of the sequence bounds", ErrInvalidValue, s.last)
+	}
+
+	return &s, nil
+}
+
+// next 推进序列, 超出 MINVALUE 或 MAXVALUE 时报错而不是循环
+func (s *sequence) next() (int64, error) {
+	v := s.last
+	if s.called {
+		if (s.increment > 0 && v > s.maxValue-s.increment) || (s.increment < 0 && v < s.minValue-s.increment) {
+			return 0, fmt.Errorf("%w: nextval: reached limit of sequence %s", ErrOutOfRange, s.name)
+		}
+
+		v += s.increment
+	}
+
+	s.last, s.called = v, true
+	s.current, s.hasCurrent = v, true
+	return v, nil
+}
+
+// set 实现 setval, called 为 true 时下一次 nextval 返回 v 之后的值, 否则返回 v 本身
+func (s *sequence) set(v int64, called bool) error {
+	if v < s.minValue || v > s.maxValue {
+		return fmt.Errorf("%w: setval: value %d is out of bounds for sequence %s", ErrOutOfRange, v, s.name)
+	}
+
+	s.last, s.called = v, called
+	return nil
+}
+
+// nextvalExpression 构造 nextval('name'), 用作 SERIAL 和标识列的默认值
+func nextvalExpression(name string) *expression {
+	return &expression{
+		function: &functionCall{
+			name: token{value: "nextval", kind: identifierKind},
+			args: []*expression{
+				{
+					literal: &token{value: name, kind: stringKind},
+					kind:    literalKind,
+				},
+			},
+		},
+		kind: functionKind,
+	}
+}
+
+func (mb *MemoryBackend) CreateSequence(crt *CreateSequenceStatement) error {
+	if _, ok := mb.sequences[crt.name.value]; ok {
+		return ErrSequenceAlreadyExists
+	}
+
+	s, err := newSequence(crt.name.value, crt.options, BigIntType)
+	if err != nil {
+		return err
+	}
+
+	mb.sequences[crt.name.value] = s
+	return nil
+}
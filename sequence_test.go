@@ -0,0 +1,104 @@
+package jiesql
+
+import (
+	"testing"
+)
+
+func TestSequences(t *testing.T) {
+	mb := NewMemoryBackend()
+	mustRun(t, mb, "CREATE SEQUENCE ids")
+
+	expectError(t, mb, "SELECT currval('ids')", ErrCurrvalNotDefined)
+	expectValue(t, mb, "SELECT nextval('ids')", "1")
+	expectValue(t, mb, "SELECT nextval('ids')", "2")
+	expectValue(t, mb, "SELECT currval('ids')", "2")
+	expectValue(t, mb, "SELECT setval('ids', 10)", "10")
+	expectValue(t, mb, "SELECT nextval('ids')", "11")
+	// 第三个参数为 false 时下一次 nextval 返回设置的值本身
+	expectValue(t, mb, "SELECT setval('ids', 20, false)", "20")
+	expectValue(t, mb, "SELECT currval('ids')", "11")
+	expectValue(t, mb, "SELECT nextval('ids')", "20")
+	expectValue(t, mb, "SELECT nextval(null)", "null")
+
+	mustRun(t, mb, "CREATE SEQUENCE down INCREMENT BY -2 START WITH 5 MINVALUE 1 MAXVALUE 5")
+	expectValue(t, mb, "SELECT nextval('down')", "5")
+	expectValue(t, mb, "SELECT nextval('down')", "3")
+	expectValue(t, mb, "SELECT nextval('down')", "1")
+	// 超出范围时报错而不是循环
+	expectError(t, mb, "SELECT nextval('down')", ErrOutOfRange)
+	expectError(t, mb, "SELECT setval('down', 6)", ErrOutOfRange)
+
+	expectError(t, mb, "CREATE SEQUENCE ids", ErrSequenceAlreadyExists)
+	expectError(t, mb, "SELECT nextval('nosuch')", ErrSequenceDoesNotExist)
+	expectError(t, mb, "CREATE SEQUENCE bad INCREMENT BY 0", ErrInvalidValue)
+	expectError(t, mb, "CREATE SEQUENCE bad MINVALUE 5 MAXVALUE 5", ErrInvalidValue)
+	expectError(t, mb, "CREATE SEQUENCE bad START WITH 0", ErrInvalidValue)
+}
+
+func TestSerialAndIdentity(t *testing.T) {
+	mb := NewMemoryBackend()
+	mustRun(t, mb, `CREATE TABLE a (id SERIAL, name TEXT);
+		CREATE TABLE b (id BIGINT GENERATED ALWAYS AS IDENTITY, name TEXT);
+		CREATE TABLE c (id SMALLINT GENERATED BY DEFAULT AS IDENTITY (START WITH 100 INCREMENT BY 10), name TEXT);`)
+
+	mustRun(t, mb, "INSERT INTO a (name) VALUES ('x')")
+	mustRun(t, mb, "INSERT INTO a VALUES (DEFAULT, 'y')")
+	// SERIAL 可以手动赋值, 不会推进序列
+	mustRun(t, mb, "INSERT INTO a VALUES (7, 'z')")
+	mustRun(t, mb, "INSERT INTO a (name) VALUES ('w')")
+	expectRows(t, mb, "SELECT id, name FROM a", [][]string{{"1", "x"}, {"2", "y"}, {"7", "z"}, {"3", "w"}})
+	expectValue(t, mb, "SELECT currval('a_id_seq')", "3")
+	expectColumns(t, mb, "SELECT id FROM a WHERE id = 1", []column{{Name: "id", Type: IntType}})
+
+	mustRun(t, mb, "INSERT INTO b (name) VALUES ('x')")
+	mustRun(t, mb, "INSERT INTO b (name) VALUES ('y')")
+	expectRows(t, mb, "SELECT id, name FROM b", [][]string{{"1", "x"}, {"2", "y"}})
+	expectError(t, mb, "INSERT INTO b VALUES (5, 'z')", ErrGeneratedColumn)
+	expectError(t, mb, "UPDATE b SET id = 5", ErrGeneratedColumn)
+
+	mustRun(t, mb, "INSERT INTO c (name) VALUES ('x')")
+	mustRun(t, mb, "INSERT INTO c VALUES (1, 'y')")
+	mustRun(t, mb, "INSERT INTO c (name) VALUES ('z')")
+	expectRows(t, mb, "SELECT id, name FROM c", [][]string{{"100", "x"}, {"1", "y"}, {"110", "z"}})
+
+	// 序列的范围取列类型的范围
+	mustRun(t, mb, "SELECT setval('c_id_seq', 32767)")
+	expectError(t, mb, "INSERT INTO c (name) VALUES ('full')", ErrOutOfRange)
+
+	// 每张表的序列各自计数
+	mustRun(t, mb, "CREATE TABLE d (id SERIAL)")
+	mustRun(t, mb, "INSERT INTO d VALUES (DEFAULT)")
+	expectRows(t, mb, "SELECT id FROM d", [][]string{{"1"}})
+
+	expectError(t, mb, "CREATE TABLE bad (id SERIAL DEFAULT 1)", ErrInvalidDatatype)
+	expectError(t, mb, "CREATE TABLE bad (id TEXT GENERATED ALWAYS AS IDENTITY)", ErrInvalidDatatype)
+	expectError(t, mb, "CREATE TABLE bad (id SMALLINT GENERATED ALWAYS AS IDENTITY (START WITH 40000))", ErrInvalidValue)
+	expectError(t, mb, "CREATE TABLE bad (id SMALLINT GENERATED ALWAYS AS IDENTITY (MAXVALUE 40000))", ErrOutOfRange)
+}
+
+func TestDropOwnedSequence(t *testing.T) {
+	mb := NewMemoryBackend()
+	mustRun(t, mb, `CREATE SEQUENCE shared;
+		CREATE TABLE i (a INT, b SERIAL, c BIGINT GENERATED ALWAYS AS IDENTITY, d BIGINT DEFAULT nextval('shared'));
+		INSERT INTO i (a) VALUES (1);`)
+
+	// 出错的 ALTER TABLE 不会删除序列
+	expectError(t, mb, "ALTER TABLE i DROP COLUMN b, DROP COLUMN nosuch", ErrColumnDoesNotExist)
+	expectValue(t, mb, "SELECT nextval('i_b_seq')", "2")
+
+	mustRun(t, mb, "ALTER TABLE i DROP COLUMN b, DROP COLUMN c")
+	expectError(t, mb, "SELECT nextval('i_b_seq')", ErrSequenceDoesNotExist)
+	expectError(t, mb, "SELECT nextval('i_c_seq')", ErrSequenceDoesNotExist)
+
+	// 只删除列自己拥有的序列, DEFAULT nextval 引用的序列不受影响
+	mustRun(t, mb, "ALTER TABLE i DROP COLUMN d")
+	expectValue(t, mb, "SELECT nextval('shared')", "2")
+
+	// 同一条语句里新加又删掉的列
+	mustRun(t, mb, "ALTER TABLE i ADD COLUMN e SERIAL, DROP COLUMN e")
+	expectError(t, mb, "SELECT nextval('i_e_seq')", ErrSequenceDoesNotExist)
+
+	// 同名的列可以重新创建, 序列从头开始
+	mustRun(t, mb, "ALTER TABLE i ADD COLUMN b SERIAL")
+	expectRows(t, mb, "SELECT a, b FROM i", [][]string{{"1", "1"}})
+}
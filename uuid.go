@@ -0,0 +1,55 @@
+package jiesql
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+)
+
+// UUID 在 MemoryCell 里存成 16 个字节, 所以按字节比较的顺序和 PostgreSQL 一致
+
+// parseUUID 和 PostgreSQL 一样接受大小写, 可选的花括号, 以及每 4 个十六进制数字之后可选的连字符
+func parseUUID(s string) (MemoryCell, error) {
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, "{") && strings.HasSuffix(s, "}") {
+		s = s[1 : len(s)-1]
+	}
+
+	var digits []byte
+	for i := 0; i < len(s); i++ {
+		if s[i] == '-' && len(digits) > 0 && len(digits)%4 == 0 && i+1 < len(s) && s[i+1] != '-' {
+			continue
+		}
+
+		digits = append(digits, s[i])
+	}
+
+	if len(digits) != 32 {
+		return nil, ErrInvalidValue
+	}
+
+	b, err := hex.DecodeString(string(digits))
+	if err != nil {
+		return nil, ErrInvalidValue
+	}
+
+	return MemoryCell(b), nil
+}
+
+// formatUUID 输出小写的 8-4-4-4-12 形式
+func formatUUID(c MemoryCell) string {
+	s := hex.EncodeToString(c)
+	return s[:8] + "-" + s[8:12] + "-" + s[12:16] + "-" + s[16:20] + "-" + s[20:]
+}
+
+// randomUUID 生成 RFC 4122 第 4 版的随机 UUID
+func randomUUID() (MemoryCell, error) {
+	b := make(MemoryCell, 16)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return b, nil
+}
@@ -0,0 +1,44 @@
+package jiesql
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestUUID(t *testing.T) {
+	mb := NewMemoryBackend()
+	mustRun(t, mb, `CREATE TABLE things (id UUID, name TEXT);
+		INSERT INTO things VALUES ('a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11', 'lower');
+		INSERT INTO things VALUES ('{A0EEBC99-9C0B-4EF8-BB6D-6BB9BD380A12}', 'braces');
+		INSERT INTO things VALUES ('a0eebc999c0b4ef8bb6d6bb9bd380a10', 'plain');
+		INSERT INTO things VALUES ('a0ee-bc99-9c0b-4ef8-bb6d-6bb9-bd38-0a13', 'groups');
+		INSERT INTO things VALUES (null, 'none');`)
+
+	expectRows(t, mb, "SELECT id, name FROM things", [][]string{
+		{"a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11", "lower"},
+		{"a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a12", "braces"},
+		{"a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a10", "plain"},
+		{"a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a13", "groups"},
+		{"null", "none"},
+	})
+
+	expectRows(t, mb, "SELECT name FROM things WHERE id = 'A0EEBC99-9C0B-4EF8-BB6D-6BB9BD380A11'", [][]string{{"lower"}})
+	expectRows(t, mb, "SELECT name FROM things WHERE id < 'a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a12'", [][]string{{"lower"}, {"plain"}})
+	expectValue(t, mb, "SELECT 'a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11'::uuid::text", "a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11")
+
+	// 第 4 版 UUID: 第 13 位是 4, 第 17 位是 8, 9, a 或 b
+	pattern := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	rows := formatRows(mustRun(t, mb, "SELECT gen_random_uuid(), gen_random_uuid()"))
+	if !pattern.MatchString(rows[0][0]) || !pattern.MatchString(rows[0][1]) || rows[0][0] == rows[0][1] {
+		t.Errorf("unexpected random uuids %q", rows[0])
+	}
+
+	mustRun(t, mb, "INSERT INTO things VALUES (gen_random_uuid(), 'random')")
+	expectColumns(t, mb, "SELECT id FROM things WHERE name = 'random'", []column{{Name: "id", Type: UUIDType}})
+
+	expectError(t, mb, "SELECT 'a0eebc99-9c0b'::uuid", ErrInvalidValue)
+	expectError(t, mb, "SELECT 'g0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11'::uuid", ErrInvalidValue)
+	expectError(t, mb, "SELECT 'a0eebc99--9c0b-4ef8-bb6d-6bb9bd380a11'::uuid", ErrInvalidValue)
+	expectError(t, mb, "INSERT INTO things VALUES ('not a uuid', 'x')", ErrInvalidDatatype)
+	expectError(t, mb, "SELECT gen_random_uuid(1)", ErrFunctionDoesNotExist)
+}
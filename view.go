@@ -0,0 +1,238 @@
+package jiesql
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// view 只保存定义, 普通视图每次查询时重新展开, 物化视图在创建和 REFRESH 时展开
+type view struct {
+	name string
+	// CREATE VIEW v (a, b) 给出的列名, 没给出的列沿用查询结果的列名
+	columns []string
+	query   *SelectStatement
+}
+
+// planView 规划视图的查询, 结果的列名换成视图给出的列名
+func (mb *MemoryBackend) planView(v *view) (*planNode, error) {
+	plan, err := mb.planSelect(v.query, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	plan.op = &renameOp{child: plan.op, name: v.name, columns: v.columns}
+	return plan, nil
+}
+
+// expandView 执行视图的查询, 把结果当作一张只读的表
+func (mb *MemoryBackend) expandView(v *view) (*table, error) {
+	plan, err := mb.planView(v)
+	if err != nil {
+		return nil, err
+	}
+
+	return drain(plan)
+}
+
+func (mb *MemoryBackend) CreateView(crt *CreateViewStatement) error {
+	name := crt.name.value
+	if _, ok := mb.tables[name]; ok {
+		return ErrTableAlreadyExists
+	}
+
+	old, exists := mb.views[name]
+	if exists && !crt.replace {
+		return ErrTableAlreadyExists
+	}
+
+	// 顺着查询用到的视图往下找, 回到自己就是循环引用
+	pending := relationNames(crt.query)
+	visited := map[string]bool{}
+	for len(pending) > 0 {
+		from := pending[0]
+		pending = pending[1:]
+		if from == name {
+			return fmt.Errorf("%w: %s", ErrRecursiveView, name)
+		}
+
+		next, ok := mb.views[from]
+		if !ok || visited[from] {
+			continue
+		}
+		visited[from] = true
+
+		pending = append(pending, relationNames(next.query)...)
+	}
+
+	v := view{name: name, query: crt.query}
+	for _, column := range crt.columns {
+		v.columns = append(v.columns, column.value)
+	}
+
+	// 创建时展开一次, 检查查询本身和列名
+	t, err := mb.expandView(&v)
+	if err != nil {
+		return err
+	}
+
+	// 和 PostgreSQL 一样, OR REPLACE 只能在后面加列, 已有的列名和类型不能变
+	if exists {
+		o, err := mb.expandView(old)
+		if err != nil {
+			return err
+		}
+
+		if len(t.columns) < len(o.columns) {
+			return fmt.Errorf("%w: cannot drop columns from view %s", ErrCannotChangeView, name)
+		}
+
+		for i := range o.columns {
+			if t.columns[i] != o.columns[i] || t.columnTypes[i] != o.columnTypes[i] {
+				return fmt.Errorf("%w: cannot change column %s of view %s", ErrCannotChangeView, o.columns[i], name)
+			}
+		}
+	}
+
+	if crt.materialized {
+		mb.materializedViews[name] = &v
+		mb.tables[name] = t
+		return nil
+	}
+
+	mb.views[name] = &v
+	return nil
+}
+
+// RefreshMaterializedView 重新执行物化视图的查询, 用新的结果替换保存的表
+func (mb *MemoryBackend) RefreshMaterializedView(refresh *RefreshMaterializedViewStatement) error {
+	name := refresh.name.value
+	v, ok := mb.materializedViews[name]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrViewDoesNotExist, name)
+	}
+
+	t, err := mb.expandView(v)
+	if err != nil {
+		return err
+	}
+
+	mb.tables[name] = t
+	return nil
+}
+
+// writableTable 找到可以修改的表, 物化视图只能通过 REFRESH 修改
+func (mb *MemoryBackend) writableTable(name string) (*table, error) {
+	t, ok := mb.tables[name]
+	if !ok {
+		return nil, ErrTableDoesNotExist
+	}
+
+	if _, ok := mb.materializedViews[name]; ok {
+		return nil, fmt.Errorf("%w: %s", ErrMaterializedView, name)
+	}
+
+	return t, nil
+}
+
+func (mb *MemoryBackend) DropView(drop *DropViewStatement) error {
+	name := drop.name.value
+	views := mb.views
+	if drop.materialized {
+		views = mb.materializedViews
+	}
+
+	if _, ok := views[name]; !ok {
+		if drop.ifExists {
+			return nil
+		}
+
+		return fmt.Errorf("%w: %s", ErrViewDoesNotExist, name)
+	}
+
+	for _, others := range []map[string]*view{mb.views, mb.materializedViews} {
+		for _, other := range others {
+			for _, from := range relationNames(other.query) {
+				if from == name {
+					return fmt.Errorf("%w: view %s depends on view %s", ErrDependentObjects, other.name, name)
+				}
+			}
+		}
+	}
+
+	delete(views, name)
+	if drop.materialized {
+		delete(mb.tables, name)
+	}
+
+	return nil
+}
+
+// dependentView 返回查询里用到表 name 的一个视图, 包括物化视图, 按名字排序让错误信息稳定.
+// column 不为空时只找用到了这一列的视图, 列名可能带着表名或别名, 所以只要后缀相同就算用到
+func (mb *MemoryBackend) dependentView(name, column string) *view {
+	var views []*view
+	for _, others := range []map[string]*view{mb.views, mb.materializedViews} {
+		for _, v := range others {
+			views = append(views, v)
+		}
+	}
+	sort.Slice(views, func(i, j int) bool {
+		return views[i].name < views[j].name
+	})
+
+	for _, v := range views {
+		if !containsName(relationNames(v.query), name) {
+			continue
+		}
+
+		if column == "" || selectUsesColumn(v.query, column) {
+			return v
+		}
+	}
+
+	return nil
+}
+
+// selectUsesColumn 判断查询里有没有表达式引用了列 column, 包括 WITH 和 UNION 里的查询
+func selectUsesColumn(slct *SelectStatement, column string) bool {
+	var exps []expression
+	for _, item := range slct.item {
+		exps = append(exps, *item)
+	}
+	if slct.where != nil {
+		exps = append(exps, *slct.where)
+	}
+	for _, item := range slct.orderBy {
+		exps = append(exps, item.exp)
+	}
+
+	for _, exp := range exps {
+		found := false
+		mapIdentifiers(exp, func(identifier string) string {
+			if identifier == column || strings.HasSuffix(identifier, "."+column) {
+				found = true
+			}
+
+			return identifier
+		})
+
+		if found {
+			return true
+		}
+	}
+
+	for _, cte := range slct.with {
+		if selectUsesColumn(cte.query, column) {
+			return true
+		}
+	}
+
+	for _, op := range slct.union {
+		if selectUsesColumn(op.query, column) {
+			return true
+		}
+	}
+
+	return false
+}
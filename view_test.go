@@ -0,0 +1,126 @@
+package jiesql
+
+import (
+	"testing"
+)
+
+func TestAlterTableWithDependentView(t *testing.T) {
+	mb := NewMemoryBackend()
+	mustRun(t, mb, `CREATE TABLE t (id INT, name TEXT, note TEXT);
+		CREATE TABLE u (id INT, name TEXT);
+		INSERT INTO t VALUES (1, 'a', 'x');
+		CREATE VIEW v AS SELECT id FROM t WHERE name <> '';
+		CREATE MATERIALIZED VIEW m AS SELECT t.id FROM t;`)
+
+	expectError(t, mb, "ALTER TABLE t DROP COLUMN id", ErrDependentObjects)
+	expectError(t, mb, "ALTER TABLE t DROP COLUMN name", ErrDependentObjects)
+	expectError(t, mb, "ALTER TABLE t RENAME COLUMN id TO x", ErrDependentObjects)
+	expectError(t, mb, "ALTER TABLE t ALTER COLUMN id TYPE TEXT", ErrDependentObjects)
+	expectError(t, mb, "ALTER TABLE t RENAME TO t2", ErrDependentObjects)
+	expectValue(t, mb, "SELECT id FROM v", "1")
+
+	// 视图没有用到的列和别的表不受影响
+	mustRun(t, mb, "ALTER TABLE t RENAME COLUMN note TO memo")
+	mustRun(t, mb, "ALTER TABLE t DROP COLUMN memo, ADD COLUMN extra INT")
+	mustRun(t, mb, "ALTER TABLE u DROP COLUMN id")
+	mustRun(t, mb, "ALTER TABLE u RENAME TO u2")
+
+	// 物化视图 REFRESH 时还要用到这一列
+	mustRun(t, mb, "DROP VIEW v")
+	mustRun(t, mb, "ALTER TABLE t DROP COLUMN name")
+	expectError(t, mb, "ALTER TABLE t DROP COLUMN id", ErrDependentObjects)
+	mustRun(t, mb, "DROP MATERIALIZED VIEW m")
+	mustRun(t, mb, "ALTER TABLE t RENAME COLUMN id TO x, RENAME TO t2")
+	expectValue(t, mb, "SELECT x FROM t2", "1")
+}
+
+func TestViews(t *testing.T) {
+	mb := NewMemoryBackend()
+	mustRun(t, mb, `CREATE TABLE sales (region TEXT, amount INT);
+		INSERT INTO sales VALUES ('north', 10);
+		INSERT INTO sales VALUES ('south', 20);
+		INSERT INTO sales VALUES ('north', null);
+		CREATE VIEW north AS SELECT amount FROM sales WHERE region = 'north';
+		CREATE VIEW doubled (region, twice) AS SELECT region, amount * 2 FROM sales;
+		CREATE VIEW big AS SELECT twice FROM doubled WHERE twice > 25;`)
+
+	expectRows(t, mb, "SELECT amount FROM north", [][]string{{"10"}, {"null"}})
+	expectRows(t, mb, "SELECT region, twice FROM doubled WHERE region = 'south'", [][]string{{"south", "40"}})
+	expectRows(t, mb, "SELECT twice FROM big", [][]string{{"40"}})
+	expectColumns(t, mb, "SELECT twice FROM doubled WHERE region = 'south'", []column{{Name: "twice", Type: IntType}})
+
+	// 视图每次查询时重新展开, 能看到表的最新内容
+	mustRun(t, mb, "INSERT INTO sales VALUES ('north', 30)")
+	expectRows(t, mb, "SELECT amount FROM north", [][]string{{"10"}, {"null"}, {"30"}})
+	expectRows(t, mb, "SELECT twice FROM big", [][]string{{"40"}, {"60"}})
+
+	// OR REPLACE 只能在后面加列
+	mustRun(t, mb, "CREATE OR REPLACE VIEW north AS SELECT amount, region FROM sales WHERE region = 'north' AND amount > 10")
+	expectRows(t, mb, "SELECT amount, region FROM north", [][]string{{"30", "north"}})
+	expectError(t, mb, "CREATE OR REPLACE VIEW north AS SELECT region FROM sales", ErrCannotChangeView)
+	expectError(t, mb, "CREATE OR REPLACE VIEW north AS SELECT amount::text, region FROM sales", ErrCannotChangeView)
+	expectError(t, mb, "CREATE OR REPLACE VIEW doubled AS SELECT region FROM sales", ErrCannotChangeView)
+
+	expectError(t, mb, "CREATE VIEW north AS SELECT 1", ErrTableAlreadyExists)
+	expectError(t, mb, "CREATE VIEW sales AS SELECT 1", ErrTableAlreadyExists)
+	expectError(t, mb, "CREATE TABLE north (a INT)", ErrTableAlreadyExists)
+	expectError(t, mb, "CREATE VIEW bad AS SELECT nosuch FROM sales", ErrColumnDoesNotExist)
+	expectError(t, mb, "CREATE VIEW bad (a, b) AS SELECT region FROM sales", ErrInvalidSelectItem)
+	expectError(t, mb, "CREATE OR REPLACE VIEW doubled AS SELECT region, twice FROM big", ErrRecursiveView)
+	expectError(t, mb, "INSERT INTO north VALUES (1, 'x')", ErrTableDoesNotExist)
+
+	// 被其他视图引用时不能删除
+	expectError(t, mb, "DROP VIEW doubled", ErrDependentObjects)
+	mustRun(t, mb, "DROP VIEW big")
+	mustRun(t, mb, "DROP VIEW doubled")
+	mustRun(t, mb, "DROP VIEW IF EXISTS doubled")
+	expectError(t, mb, "DROP VIEW doubled", ErrViewDoesNotExist)
+	expectError(t, mb, "SELECT twice FROM doubled", ErrTableDoesNotExist)
+}
+
+func TestMaterializedViews(t *testing.T) {
+	mb := NewMemoryBackend()
+	mustRun(t, mb, `CREATE TABLE sales (region TEXT, amount INT);
+		INSERT INTO sales VALUES ('north', 10);
+		INSERT INTO sales VALUES ('south', 20);
+		INSERT INTO sales VALUES ('north', null);
+		CREATE MATERIALIZED VIEW doubled (region, twice) AS SELECT region, amount * 2 FROM sales WHERE region = 'north';`)
+
+	expectRows(t, mb, "SELECT region, twice FROM doubled", [][]string{{"north", "20"}, {"north", "null"}})
+	expectColumns(t, mb, "SELECT twice FROM doubled WHERE twice = 20", []column{{Name: "twice", Type: IntType}})
+
+	// 结果在 REFRESH 之前不会变
+	mustRun(t, mb, "INSERT INTO sales VALUES ('north', 5)")
+	mustRun(t, mb, "DELETE FROM sales WHERE amount = 10")
+	expectRows(t, mb, "SELECT twice FROM doubled", [][]string{{"20"}, {"null"}})
+	mustRun(t, mb, "REFRESH MATERIALIZED VIEW doubled")
+	expectRows(t, mb, "SELECT twice FROM doubled", [][]string{{"null"}, {"10"}})
+
+	// 物化视图只能通过 REFRESH 修改
+	expectError(t, mb, "INSERT INTO doubled VALUES ('west', 1)", ErrMaterializedView)
+	expectError(t, mb, "UPDATE doubled SET twice = 0", ErrMaterializedView)
+	expectError(t, mb, "DELETE FROM doubled", ErrMaterializedView)
+	expectError(t, mb, "CREATE TABLE r (region TEXT REFERENCES doubled (region))", ErrMaterializedView)
+
+	expectError(t, mb, "CREATE MATERIALIZED VIEW doubled AS SELECT 1", ErrTableAlreadyExists)
+	expectError(t, mb, "CREATE MATERIALIZED VIEW sales AS SELECT 1", ErrTableAlreadyExists)
+	expectError(t, mb, "CREATE MATERIALIZED VIEW bad AS SELECT nosuch FROM sales", ErrColumnDoesNotExist)
+	expectError(t, mb, "REFRESH MATERIALIZED VIEW sales", ErrViewDoesNotExist)
+	expectError(t, mb, "REFRESH MATERIALIZED VIEW nosuch", ErrViewDoesNotExist)
+
+	// 普通视图可以建在物化视图上, 这时物化视图不能删除
+	mustRun(t, mb, "CREATE VIEW small AS SELECT twice FROM doubled WHERE twice < 15")
+	expectRows(t, mb, "SELECT twice FROM small", [][]string{{"10"}})
+	expectError(t, mb, "DROP MATERIALIZED VIEW doubled", ErrDependentObjects)
+	expectError(t, mb, "DROP VIEW doubled", ErrViewDoesNotExist)
+	expectError(t, mb, "DROP MATERIALIZED VIEW small", ErrViewDoesNotExist)
+	mustRun(t, mb, "DROP VIEW small")
+	mustRun(t, mb, "DROP MATERIALIZED VIEW doubled")
+	mustRun(t, mb, "DROP MATERIALIZED VIEW IF EXISTS doubled")
+	expectError(t, mb, "SELECT twice FROM doubled", ErrTableDoesNotExist)
+	expectError(t, mb, "REFRESH MATERIALIZED VIEW doubled", ErrViewDoesNotExist)
+
+	// 空表上的物化视图也是空的
+	mustRun(t, mb, "CREATE TABLE tmp (a INT); CREATE MATERIALIZED VIEW m AS SELECT a FROM tmp")
+	expectRows(t, mb, "SELECT a FROM m", [][]string{})
+}
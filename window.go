@@ -0,0 +1,680 @@
+package jiesql
+
+import (
+	"fmt"
+	"sort"
+)
+
+// windowFunctions 是可以带 OVER 的函数, 其中聚合函数暂时只能作为窗口函数使用
+var windowFunctions = map[string]bool{
+	"row_number": true,
+	"rank":       true,
+	"dense_rank": true,
+	"lag":        true,
+	"lead":       true,
+	"count":      true,
+	"sum":        true,
+	"avg":        true,
+	"min":        true,
+	"max":        true,
+}
+
+// childExpressions 返回表达式的直接子表达式
+func childExpressions(exp expression) []expression {
+	var children []expression
+	appendList := func(list []*expression) {
+		for _, e := range list {
+			children = append(children, *e)
+		}
+	}
+
+	switch exp.kind {
+	case binaryKind:
+		children = append(children, exp.binary.a, exp.binary.b)
+	case likeKind:
+		children = append(children, exp.like.value, exp.like.pattern)
+		if exp.like.escape != nil {
+			children = append(children, *exp.like.escape)
+		}
+	case unaryKind:
+		children = append(children, exp.unary.operand)
+	case inKind:
+		children = append(children, exp.in.value)
+		appendList(exp.in.list)
+	case betweenKind:
+		children = append(children, exp.between.value, exp.between.low, exp.between.high)
+	case castKind:
+		children = append(children, exp.cast.value)
+	case functionKind:
+		appendList(exp.function.args)
+	case arrayKind:
+		appendList(exp.array.elements)
+	case subscriptKind:
+		children = append(children, exp.subscript.value, exp.subscript.index)
+	case quantifiedKind:
+		children = append(children, exp.quantified.array)
+	}
+
+	return children
+}
+
+// windowCalls 找出表达式里的窗口函数调用. 不进入窗口函数的参数, 那里的窗口函数求值时会报错
+func windowCalls(exp expression) []*functionCall {
+	if exp.kind == functionKind && exp.function.over != nil {
+		return []*functionCall{exp.function}
+	}
+
+	var calls []*functionCall
+	for _, child := range childExpressions(exp) {
+		calls = append(calls, windowCalls(child)...)
+	}
+
+	return calls
+}
+
+// windowTable 返回只包含 rows 的表, 并在 rows 上计算 items 里所有的窗口函数,
+// 结果作为隐藏列追加到每一行的末尾, 求值时通过 windowColumns 找到
+func (mb *MemoryBackend) windowTable(t *table, rows [][]MemoryCell, items []*expression) (*table, error) {
+	w := *t
+	w.rows = rows
+
+	var calls []*functionCall
+	for _, item := range items {
+		calls = append(calls, windowCalls(*item)...)
+	}
+
+	if len(calls) == 0 {
+		return &w, nil
+	}
+
+	// 复制列信息, 不能修改原来的表
+	w.columns = append([]string{}, t.columns...)
+	w.columnTypes = append([]ColumnType{}, t.columnTypes...)
+	w.columnModifiers = append([]typeModifier{}, t.columnModifiers...)
+	w.windowColumns = map[*functionCall]int{}
+
+	w.rows = make([][]MemoryCell, len(rows))
+	for i, row := range rows {
+		w.rows[i] = append(append([]MemoryCell{}, row...), make([]MemoryCell, len(calls))...)
+	}
+
+	for k, call := range calls {
+		values, typ, err := mb.evaluateWindow(t, rows, call)
+		if err != nil {
+			return nil, err
+		}
+
+		i := len(t.columns) + k
+		w.columns = append(w.columns, "")
+		w.columnTypes = append(w.columnTypes, typ)
+		w.columnModifiers = append(w.columnModifiers, typeModifier{})
+		w.windowColumns[call] = i
+
+		for r := range w.rows {
+			w.rows[r][i] = values[r]
+		}
+	}
+
+	return &w, nil
+}
+
+// windowResultType 检查窗口函数的参数类型, 返回结果类型. 和 PostgreSQL 一样,
+// 整数的 sum 结果是 bigint 或 numeric, 除 float 以外的 avg 结果是 numeric
+func windowResultType(call *functionCall, types []ColumnType) (ColumnType, error) {
+	name := call.name.value
+	if call.star && name != "count" {
+		return 0, ErrFunctionDoesNotExist
+	}
+
+	switch name {
+	case "row_number", "rank", "dense_rank":
+		if len(types) == 0 {
+			return BigIntType, nil
+		}
+	case "lag", "lead":
+		if len(types) < 1 || len(types) > 3 {
+			break
+		}
+
+		if len(types) >= 2 && !isIntegerType(types[1]) {
+			break
+		}
+
+		if len(types) == 3 && !canCoerce(types[2], types[0], assignmentCoercion) {
+			break
+		}
+
+		return types[0], nil
+	case "count":
+		if call.star && len(types) == 0 || !call.star && len(types) == 1 {
+			return BigIntType, nil
+		}
+	case "sum":
+		if len(types) != 1 {
+			break
+		}
+
+		switch types[0] {
+		case SmallIntType, IntType:
+			return BigIntType, nil
+		case BigIntType, NumericType:
+			return NumericType, nil
+		case FloatType:
+			return FloatType, nil
+		}
+	case "avg":
+		if len(types) != 1 {
+			break
+		}
+
+		switch types[0] {
+		case SmallIntType, IntType, BigIntType, NumericType:
+			return NumericType, nil
+		case FloatType:
+			return FloatType, nil
+		}
+	case "min", "max":
+		if len(types) == 1 {
+			return types[0], nil
+		}
+	}
+
+	return 0, ErrFunctionDoesNotExist
+}
+
+// windowPartition 是一个分区里按 ORDER BY 排好序的行号和对应的排序键
+type windowPartition struct {
+	rows []int
+	keys [][]MemoryCell
+}
+
+// compareOrderKeys 按 ORDER BY 比较两个排序键, 和 PostgreSQL 一样 NULL 比所有值都大
+func compareOrderKeys(a, b []MemoryCell, orderBy []*orderItem, types []ColumnType) int {
+	for i, item := range orderBy {
+		c := 0
+		switch {
+		case a[i] == nil && b[i] == nil:
+		case a[i] == nil:
+			c = 1
+		case b[i] == nil:
+			c = -1
+		default:
+			c = compareCells(a[i], b[i], types[i])
+		}
+
+		if item.desc {
+			c = -c
+		}
+
+		if c != 0 {
+			return c
+		}
+	}
+
+	return 0
+}
+
+// evaluateList 在一行上计算一组表达式, row 为 nil 时只推导类型
+func (mb *MemoryBackend) evaluateList(t *table, row []MemoryCell, exps []expression) ([]MemoryCell, []ColumnType, error) {
+	var cells []MemoryCell
+	var types []ColumnType
+	for _, exp := range exps {
+		cell, _, typ, err := mb.evaluateCell(t, row, exp)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		cells = append(cells, cell)
+		types = append(types, typ)
+	}
+
+	return cells, types, nil
+}
+
+// windowPartitions 按 PARTITION BY 把行分组, 每组按 ORDER BY 稳定排序, 同时返回排序键的类型
+func (mb *MemoryBackend) windowPartitions(t *table, rows [][]MemoryCell, over *windowDefinition) ([]*windowPartition, []ColumnType, error) {
+	var partitionBy, orderBy []expression
+	for _, exp := range over.partitionBy {
+		partitionBy = append(partitionBy, *exp)
+	}
+	for _, item := range over.orderBy {
+		orderBy = append(orderBy, item.exp)
+	}
+
+	_, partitionTypes, err := mb.evaluateList(t, nil, partitionBy)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	_, orderTypes, err := mb.evaluateList(t, nil, orderBy)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var partitions []*windowPartition
+	var partitionKeys [][]MemoryCell
+	for r, row := range rows {
+		key, _, err := mb.evaluateList(t, row, partitionBy)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		orderKey, _, err := mb.evaluateList(t, row, orderBy)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		var partition *windowPartition
+		for i, other := range partitionKeys {
+			equal := true
+			for j := range key {
+				if (key[j] == nil) != (other[j] == nil) || key[j] != nil && compareCells(key[j], other[j], partitionTypes[j]) != 0 {
+					equal = false
+					break
+				}
+			}
+
+			if equal {
+				partition = partitions[i]
+				break
+			}
+		}
+
+		if partition == nil {
+			partition = &windowPartition{}
+			partitions = append(partitions, partition)
+			partitionKeys = append(partitionKeys, key)
+		}
+
+		partition.rows = append(partition.rows, r)
+		partition.keys = append(partition.keys, orderKey)
+	}
+
+	for _, partition := range partitions {
+		sort.Stable(partitionSorter{partition, over.orderBy, orderTypes})
+	}
+
+	return partitions, orderTypes, nil
+}
+
+type partitionSorter struct {
+	partition *windowPartition
+	orderBy   []*orderItem
+	types     []ColumnType
+}
+
+func (s partitionSorter) Len() int {
+	return len(s.partition.rows)
+}
+
+func (s partitionSorter) Less(i, j int) bool {
+	return compareOrderKeys(s.partition.keys[i], s.partition.keys[j], s.orderBy, s.types) < 0
+}
+
+func (s partitionSorter) Swap(i, j int) {
+	p := s.partition
+	p.rows[i], p.rows[j] = p.rows[j], p.rows[i]
+	p.keys[i], p.keys[j] = p.keys[j], p.keys[i]
+}
+
+// evaluateWindow 计算一个窗口函数在每一行上的值, 结果按 rows 的顺序排列
+func (mb *MemoryBackend) evaluateWindow(t *table, rows [][]MemoryCell, call *functionCall) ([]MemoryCell, ColumnType, error) {
+	var args []expression
+	for _, arg := range call.args {
+		args = append(args, *arg)
+	}
+
+	_, types, err := mb.evaluateList(t, nil, args)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	typ, err := windowResultType(call, types)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// 参数在每一行上只计算一次
+	argValues := make([][]MemoryCell, len(rows))
+	for r, row := range rows {
+		argValues[r], _, err = mb.evaluateList(t, row, args)
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+
+	partitions, orderTypes, err := mb.windowPartitions(t, rows, call.over)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	values := make([]MemoryCell, len(rows))
+	for _, part := range partitions {
+		peers := func(i, j int) bool {
+			return compareOrderKeys(part.keys[i], part.keys[j], call.over.orderBy, orderTypes) == 0
+		}
+
+		rank, denseRank := 0, 0
+		for p, r := range part.rows {
+			// 和前一行不是同级时排名才变化
+			if p == 0 || !peers(p-1, p) {
+				rank = p + 1
+				denseRank++
+			}
+
+			switch call.name.value {
+			case "row_number":
+				values[r] = integerCell(int64(p+1), BigIntType)
+			case "rank":
+				values[r] = integerCell(int64(rank), BigIntType)
+			case "dense_rank":
+				values[r] = integerCell(int64(denseRank), BigIntType)
+			case "lag", "lead":
+				values[r], err = offsetValue(call.name.value == "lag", part, p, argValues, types, mb.enums)
+			default:
+				var start, end int
+				start, end, err = mb.frameRows(t, rows[r], part, p, call.over, orderTypes, peers)
+				if err != nil {
+					return nil, 0, err
+				}
+
+				var frame []MemoryCell
+				for _, i := range part.rows[start:end] {
+					if call.star {
+						frame = append(frame, MemoryCell{})
+						continue
+					}
+
+					frame = append(frame, argValues[i][0])
+				}
+
+				var argType ColumnType
+				if len(types) > 0 {
+					argType = types[0]
+				}
+
+				values[r], err = aggregateCells(call.name.value, frame, argType, typ)
+			}
+			if err != nil {
+				return nil, 0, err
+			}
+		}
+	}
+
+	return values, typ, nil
+}
+
+// offsetValue 计算 lag(value [, offset [, default]]) 和 lead, 偏移超出分区时取 default
+func offsetValue(lag bool, part *windowPartition, p int, argValues [][]MemoryCell, types []ColumnType, enums enumTypes) (MemoryCell, error) {
+	current := argValues[part.rows[p]]
+	offset := int64(1)
+	if len(current) >= 2 {
+		if current[1] == nil {
+			return nil, nil
+		}
+
+		offset = current[1].AsInt64()
+	}
+
+	if lag {
+		offset = -offset
+	}
+
+	target := int64(p) + offset
+	if target >= 0 && target < int64(len(part.rows)) {
+		return argValues[part.rows[target]][0], nil
+	}
+
+	if len(current) == 3 {
+		return castCell(current[2], types[2], types[0], assignmentCoercion, enums)
+	}
+
+	return nil, nil
+}
+
+// frameRows 返回第 p 行的窗口框架在分区里的范围 [start, end). 没有写框架时和 PostgreSQL 一样是
+// RANGE BETWEEN UNBOUNDED PRECEDING AND CURRENT ROW, 没有 ORDER BY 时所有行都是同级, 也就是整个分区
+func (mb *MemoryBackend) frameRows(t *table, row []MemoryCell, part *windowPartition, p int, over *windowDefinition, orderTypes []ColumnType, peers func(i, j int) bool) (int, int, error) {
+	frame := over.frame
+	if frame == nil {
+		frame = &windowFrame{
+			mode:  rangeFrame,
+			start: frameBound{kind: unboundedPrecedingBound},
+			end:   frameBound{kind: currentRowBound},
+		}
+	}
+
+	start, err := mb.frameBoundIndex(t, row, part, p, frame.mode, frame.start, true, over, orderTypes, peers)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	end, err := mb.frameBoundIndex(t, row, part, p, frame.mode, frame.end, false, over, orderTypes, peers)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if start < 0 {
+		start = 0
+	}
+	end++
+	if end > len(part.rows) {
+		end = len(part.rows)
+	}
+	if start > end {
+		start = end
+	}
+
+	return start, end, nil
+}
+
+// frameBoundIndex 返回框架起点或终点对应的行在分区里的位置, 结果可能超出分区, 由调用方截断
+func (mb *MemoryBackend) frameBoundIndex(t *table, row []MemoryCell, part *windowPartition, p int, mode frameMode, bound frameBound, isStart bool, over *windowDefinition, orderTypes []ColumnType, peers func(i, j int) bool) (int, error) {
+	n := len(part.rows)
+
+	// RANGE 的 CURRENT ROW 包括所有同级的行
+	currentRow := func() int {
+		i := p
+		if mode == rowsFrame {
+			return i
+		}
+
+		if isStart {
+			for i > 0 && peers(i-1, p) {
+				i--
+			}
+			return i
+		}
+
+		for i < n-1 && peers(i+1, p) {
+			i++
+		}
+		return i
+	}
+
+	switch bound.kind {
+	case unboundedPrecedingBound:
+		return 0, nil
+	case unboundedFollowingBound:
+		return n - 1, nil
+	case currentRowBound:
+		return currentRow(), nil
+	}
+
+	offset, _, typ, err := mb.evaluateCell(t, row, *bound.offset)
+	if err != nil {
+		return 0, err
+	}
+
+	if offset == nil {
+		return 0, fmt.Errorf("%w: frame offset must not be null", ErrInvalidFrame)
+	}
+
+	if mode == rowsFrame {
+		if !isIntegerType(typ) {
+			return 0, fmt.Errorf("%w: ROWS offset must be an integer", ErrInvalidFrame)
+		}
+
+		off := offset.AsInt64()
+		if off < 0 {
+			return 0, fmt.Errorf("%w: frame offset must not be negative", ErrInvalidFrame)
+		}
+
+		if off > int64(n) {
+			off = int64(n)
+		}
+
+		if bound.kind == precedingBound {
+			return p - int(off), nil
+		}
+
+		return p + int(off), nil
+	}
+
+	// RANGE 的偏移按唯一的排序列的值计算, 例如 RANGE BETWEEN 10 PRECEDING AND CURRENT ROW
+	if len(over.orderBy) != 1 {
+		return 0, fmt.Errorf("%w: RANGE with offset PRECEDING/FOLLOWING requires exactly one ORDER BY column", ErrInvalidFrame)
+	}
+
+	keyType := orderTypes[0]
+	if !isNumericType(keyType) || !isNumericType(typ) {
+		return 0, fmt.Errorf("%w: RANGE with offset PRECEDING/FOLLOWING is not supported for column type %s and offset type %s", ErrInvalidFrame, keyType, typ)
+	}
+
+	o, ot, zero, _, err := promoteNumeric(offset, typ, integerCell(0, IntType), IntType)
+	if err != nil {
+		return 0, err
+	}
+
+	if compareCells(o, zero, ot) < 0 {
+		return 0, fmt.Errorf("%w: frame offset must not be negative", ErrInvalidFrame)
+	}
+
+	// NULL 的当前行只和其他 NULL 同级
+	current := part.keys[p][0]
+	if current == nil {
+		return currentRow(), nil
+	}
+
+	// PRECEDING 是排序方向上更靠前的值
+	op := plusSymbol
+	if (bound.kind == precedingBound) != over.orderBy[0].desc {
+		op = minusSymbol
+	}
+
+	l, lt, r, rt, err := promoteNumeric(current, keyType, offset, typ)
+	if err != nil {
+		return 0, err
+	}
+
+	target, targetType, err := evaluateArithmetic(op, l, lt, r, rt)
+	if err != nil {
+		return 0, err
+	}
+
+	compare := func(i int) (int, error) {
+		value := part.keys[i][0]
+		if value == nil {
+			if over.orderBy[0].desc {
+				return -1, nil
+			}
+			return 1, nil
+		}
+
+		value, err := castCell(value, keyType, targetType, assignmentCoercion, nil)
+		if err != nil {
+			return 0, err
+		}
+
+		c := compareCells(value, target, targetType)
+		if over.orderBy[0].desc {
+			c = -c
+		}
+		return c, nil
+	}
+
+	if isStart {
+		for i := 0; i < n; i++ {
+			c, err := compare(i)
+			if err != nil {
+				return 0, err
+			}
+
+			if c >= 0 {
+				return i, nil
+			}
+		}
+
+		return n, nil
+	}
+
+	for i := n - 1; i >= 0; i-- {
+		c, err := compare(i)
+		if err != nil {
+			return 0, err
+		}
+
+		if c <= 0 {
+			return i, nil
+		}
+	}
+
+	return -1, nil
+}
+
+// aggregateCells 在窗口框架的值上计算聚合函数, 忽略 NULL. 除 count 外没有值时结果是 NULL
+func aggregateCells(name string, cells []MemoryCell, argType, typ ColumnType) (MemoryCell, error) {
+	var result MemoryCell
+	count := int64(0)
+	for _, cell := range cells {
+		if cell == nil {
+			continue
+		}
+		count++
+
+		switch name {
+		case "sum", "avg":
+			value, err := castCell(cell, argType, typ, assignmentCoercion, nil)
+			if err != nil {
+				return nil, err
+			}
+
+			if result == nil {
+				result = value
+				continue
+			}
+
+			result, _, err = evaluateArithmetic(plusSymbol, result, typ, value, typ)
+			if err != nil {
+				return nil, err
+			}
+		case "min":
+			if result == nil || compareCells(cell, result, typ) < 0 {
+				result = cell
+			}
+		case "max":
+			if result == nil || compareCells(cell, result, typ) > 0 {
+				result = cell
+			}
+		}
+	}
+
+	switch name {
+	case "count":
+		return integerCell(count, BigIntType), nil
+	case "avg":
+		if result == nil {
+			return nil, nil
+		}
+
+		n, err := castCell(integerCell(count, BigIntType), BigIntType, typ, assignmentCoercion, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		result, _, err = evaluateArithmetic(slashSymbol, result, typ, n, typ)
+		return result, err
+	}
+
+	return result, nil
+}
@@ -0,0 +1,111 @@
+package jiesql
+
+import (
+	"testing"
+)
+
+func newScoresBackend(t *testing.T) *MemoryBackend {
+	t.Helper()
+
+	mb := NewMemoryBackend()
+	mustRun(t, mb, `CREATE TABLE scores (id INT, team TEXT, points INT);
+		INSERT INTO scores VALUES (1, 'a', 10);
+		INSERT INTO scores VALUES (2, 'a', 30);
+		INSERT INTO scores VALUES (3, 'a', 30);
+		INSERT INTO scores VALUES (4, 'b', 20);
+		INSERT INTO scores VALUES (5, 'b', null);`)
+	return mb
+}
+
+func TestRankingFunctions(t *testing.T) {
+	mb := newScoresBackend(t)
+
+	expectRows(t, mb, "SELECT id, row_number() OVER (ORDER BY id DESC) FROM scores", [][]string{
+		{"1", "5"}, {"2", "4"}, {"3", "3"}, {"4", "2"}, {"5", "1"},
+	})
+	// 同级的行名次相同, RANK 会跳过名次, DENSE_RANK 不会. 和 PostgreSQL 一样 DESC 时 NULL 排在最前面
+	expectRows(t, mb, "SELECT id, rank() OVER (ORDER BY points DESC), dense_rank() OVER (ORDER BY points DESC) FROM scores", [][]string{
+		{"1", "5", "4"}, {"2", "2", "2"}, {"3", "2", "2"}, {"4", "4", "3"}, {"5", "1", "1"},
+	})
+	expectRows(t, mb, "SELECT id, row_number() OVER (PARTITION BY team ORDER BY points) FROM scores", [][]string{
+		{"1", "1"}, {"2", "2"}, {"3", "3"}, {"4", "1"}, {"5", "2"},
+	})
+	// 没有 ORDER BY 时所有行都是同级
+	expectRows(t, mb, "SELECT rank() OVER (PARTITION BY team) FROM scores", [][]string{{"1"}, {"1"}, {"1"}, {"1"}, {"1"}})
+	expectColumns(t, mb, "SELECT row_number() OVER () FROM scores WHERE id = 1", []column{{Name: "row_number", Type: BigIntType}})
+	// 窗口函数在 WHERE 之后计算
+	expectRows(t, mb, "SELECT id, row_number() OVER (ORDER BY id) FROM scores WHERE team = 'b'", [][]string{{"4", "1"}, {"5", "2"}})
+	expectRows(t, mb, "SELECT row_number() OVER () FROM scores WHERE id > 10", [][]string{})
+	expectRows(t, mb, "SELECT row_number() OVER () + 100 FROM scores WHERE id = 3", [][]string{{"101"}})
+
+	expectError(t, mb, "SELECT row_number(id) OVER () FROM scores", ErrFunctionDoesNotExist)
+	expectError(t, mb, "SELECT row_number() FROM scores", ErrOverRequired)
+	expectError(t, mb, "SELECT id FROM scores WHERE row_number() OVER () = 1", ErrWindowFunction)
+}
+
+func TestOffsetFunctions(t *testing.T) {
+	mb := newScoresBackend(t)
+
+	expectRows(t, mb, "SELECT id, lag(points) OVER (ORDER BY id), lead(points) OVER (ORDER BY id) FROM scores", [][]string{
+		{"1", "null", "30"}, {"2", "10", "30"}, {"3", "30", "20"}, {"4", "30", "null"}, {"5", "20", "null"},
+	})
+	// 超出分区时取默认值, 但分区里的 NULL 不会被替换
+	expectRows(t, mb, "SELECT id, lag(points, 1, 0) OVER (PARTITION BY team ORDER BY id), lead(points, 2, -1) OVER (PARTITION BY team ORDER BY id) FROM scores", [][]string{
+		{"1", "0", "30"}, {"2", "10", "-1"}, {"3", "30", "-1"}, {"4", "0", "-1"}, {"5", "20", "-1"},
+	})
+	expectRows(t, mb, "SELECT lag(id, 0) OVER (), lag(id, -1) OVER (ORDER BY id) FROM scores WHERE team = 'b'", [][]string{{"4", "5"}, {"5", "null"}})
+	expectRows(t, mb, "SELECT lag(id, null::int, 0) OVER () FROM scores WHERE id = 2", [][]string{{"null"}})
+	expectColumns(t, mb, "SELECT lead(team) OVER () FROM scores WHERE id = 1", []column{{Name: "lead", Type: TextType}})
+
+	expectError(t, mb, "SELECT lag(id, 'x') OVER () FROM scores", ErrFunctionDoesNotExist)
+	expectError(t, mb, "SELECT lag(id, 1, 'x'::text) OVER () FROM scores", ErrFunctionDoesNotExist)
+	expectError(t, mb, "SELECT lead() OVER () FROM scores", ErrFunctionDoesNotExist)
+	expectError(t, mb, "SELECT lag(id) FROM scores", ErrOverRequired)
+}
+
+func TestAggregateWindows(t *testing.T) {
+	mb := newScoresBackend(t)
+
+	// 默认框架是 RANGE BETWEEN UNBOUNDED PRECEDING AND CURRENT ROW, 同级的行一起算
+	expectRows(t, mb, "SELECT id, sum(points) OVER (ORDER BY points) FROM scores", [][]string{
+		{"1", "10"}, {"2", "90"}, {"3", "90"}, {"4", "30"}, {"5", "90"},
+	})
+	expectRows(t, mb, "SELECT id, sum(points) OVER (ORDER BY id ROWS BETWEEN UNBOUNDED PRECEDING AND CURRENT ROW) FROM scores", [][]string{
+		{"1", "10"}, {"2", "40"}, {"3", "70"}, {"4", "90"}, {"5", "90"},
+	})
+	expectRows(t, mb, "SELECT id, sum(points) OVER (PARTITION BY team), count(*) OVER (PARTITION BY team), count(points) OVER (PARTITION BY team) FROM scores", [][]string{
+		{"1", "70", "3", "3"}, {"2", "70", "3", "3"}, {"3", "70", "3", "3"}, {"4", "20", "2", "1"}, {"5", "20", "2", "1"},
+	})
+	expectRows(t, mb, "SELECT id, avg(points) OVER (ORDER BY id ROWS BETWEEN 1 PRECEDING AND 1 FOLLOWING) FROM scores", [][]string{
+		{"1", "20.0000000000000000"}, {"2", "23.3333333333333333"}, {"3", "26.6666666666666667"},
+		{"4", "25.0000000000000000"}, {"5", "20.0000000000000000"},
+	})
+	expectRows(t, mb, "SELECT id, min(points) OVER (ORDER BY id ROWS 1 PRECEDING), max(points) OVER (ORDER BY id ROWS BETWEEN CURRENT ROW AND UNBOUNDED FOLLOWING) FROM scores", [][]string{
+		{"1", "10", "30"}, {"2", "10", "30"}, {"3", "30", "30"}, {"4", "20", "20"}, {"5", "20", "null"},
+	})
+	// RANGE 的偏移按排序列的值计算, NULL 只和 NULL 同级
+	expectRows(t, mb, "SELECT id, count(*) OVER (ORDER BY points RANGE BETWEEN 10 PRECEDING AND 10 FOLLOWING) FROM scores", [][]string{
+		{"1", "2"}, {"2", "3"}, {"3", "3"}, {"4", "4"}, {"5", "1"},
+	})
+	expectRows(t, mb, "SELECT id, sum(points) OVER (ORDER BY id ROWS BETWEEN 2 FOLLOWING AND 3 FOLLOWING) FROM scores", [][]string{
+		{"1", "50"}, {"2", "20"}, {"3", "null"}, {"4", "null"}, {"5", "null"},
+	})
+	expectColumns(t, mb, "SELECT sum(id) OVER (), sum(id::bigint) OVER (), avg(id) OVER (), sum(id::float) OVER () FROM scores WHERE id = 1", []column{
+		{Name: "sum", Type: BigIntType},
+		{Name: "sum", Type: NumericType},
+		{Name: "avg", Type: NumericType},
+		{Name: "sum", Type: FloatType},
+	})
+
+	expectError(t, mb, "SELECT sum(team) OVER () FROM scores", ErrFunctionDoesNotExist)
+	expectError(t, mb, "SELECT sum(*) OVER () FROM scores", ErrFunctionDoesNotExist)
+	expectError(t, mb, "SELECT sum(points) FROM scores", ErrOverRequired)
+	expectError(t, mb, "SELECT sum(points) OVER (ORDER BY id ROWS -1 PRECEDING) FROM scores", ErrInvalidFrame)
+	expectError(t, mb, "SELECT sum(points) OVER (ORDER BY id ROWS null::int PRECEDING) FROM scores", ErrInvalidFrame)
+	expectError(t, mb, "SELECT sum(points) OVER (ORDER BY id ROWS 1.5 PRECEDING) FROM scores", ErrInvalidFrame)
+	expectError(t, mb, "SELECT sum(points) OVER (ORDER BY id, team RANGE 1 PRECEDING) FROM scores", ErrInvalidFrame)
+	expectError(t, mb, "SELECT sum(points) OVER (ORDER BY team RANGE 1 PRECEDING) FROM scores", ErrInvalidFrame)
+	// 终点在起点之前的框架在解析时就报错
+	expectError(t, mb, "SELECT sum(points) OVER (ROWS BETWEEN CURRENT ROW AND 1 PRECEDING) FROM scores", nil)
+	expectError(t, mb, "SELECT sum(points) OVER (ROWS UNBOUNDED FOLLOWING) FROM scores", nil)
+}